@@ -37,6 +37,20 @@ func Convert_v1_SubNamespace_To_v2_SubNamespace(in *SubNamespace, out *accuratev
 
 		delete(out.Annotations, constants.AnnConditions)
 	}
+	if v, ok := out.Annotations[constants.AnnDeletionPolicy]; ok {
+		out.Spec.DeletionPolicy = accuratev2.DeletionPolicy(v)
+
+		delete(out.Annotations, constants.AnnDeletionPolicy)
+	}
+	if v, ok := out.Annotations[constants.AnnKeepPropagatedResources]; ok {
+		keep, err := strconv.ParseBool(v)
+		if err != nil {
+			return fmt.Errorf("error converting %q to bool from annotation %s", v, constants.AnnKeepPropagatedResources)
+		}
+		out.Spec.KeepPropagatedResources = keep
+
+		delete(out.Annotations, constants.AnnKeepPropagatedResources)
+	}
 	return nil
 }
 
@@ -51,7 +65,7 @@ func Convert_v2_SubNamespace_To_v1_SubNamespace(in *accuratev2.SubNamespace, out
 		out.Status = SubNamespaceConflict
 	case in.Status.ObservedGeneration == 0:
 		// SubNamespace has never been reconciled.
-	case in.Status.ObservedGeneration == in.Generation && len(in.Status.Conditions) == 0:
+	case in.Status.ObservedGeneration == in.Generation && !meta.IsStatusConditionFalse(in.Status.Conditions, accuratev2.SubNamespaceReady):
 		out.Status = SubNamespaceOK
 	default:
 		// SubNamespace is in some transitional state, not possible to represent in v1 status.
@@ -72,8 +86,21 @@ func Convert_v2_SubNamespace_To_v1_SubNamespace(in *accuratev2.SubNamespace, out
 		}
 		out.Annotations[constants.AnnConditions] = string(buf)
 	}
+	if in.Spec.DeletionPolicy != "" {
+		out.Annotations[constants.AnnDeletionPolicy] = string(in.Spec.DeletionPolicy)
+	}
+	if in.Spec.KeepPropagatedResources {
+		out.Annotations[constants.AnnKeepPropagatedResources] = strconv.FormatBool(in.Spec.KeepPropagatedResources)
+	}
 	if len(out.Annotations) == 0 {
 		out.Annotations = nil
 	}
 	return nil
 }
+
+// Convert_v2_SubNamespaceSpec_To_v1_SubNamespaceSpec complements the generated conversion functions
+// since the spec fields added in v2 do not exist in v1.
+// They are stored in annotations by Convert_v2_SubNamespace_To_v1_SubNamespace.
+func Convert_v2_SubNamespaceSpec_To_v1_SubNamespaceSpec(in *accuratev2.SubNamespaceSpec, out *SubNamespaceSpec, s conversion.Scope) error {
+	return autoConvert_v2_SubNamespaceSpec_To_v1_SubNamespaceSpec(in, out, s)
+}
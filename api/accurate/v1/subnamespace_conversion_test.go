@@ -24,6 +24,13 @@ func TestSubNamespace_ConvertFrom(t *testing.T) {
 			src:       newSubNamespaceWithStatus(1, 1),
 			expStatus: SubNamespaceOK,
 		},
+		"if SubNamespace is reconciled and ready, status should be ok": {
+			src:       newSubNamespaceWithStatus(1, 1, newReadyCondition(metav1.ConditionTrue)),
+			expStatus: SubNamespaceOK,
+		},
+		"if SubNamespace is reconciled but not ready, status should have zero-value": {
+			src: newSubNamespaceWithStatus(1, 1, newReadyCondition(metav1.ConditionFalse)),
+		},
 		"if SubNamespace is reconciled with errors, status should be conflict": {
 			src:       newSubNamespaceWithStatus(1, 1, newStalledCondition()),
 			expStatus: SubNamespaceConflict,
@@ -56,3 +63,10 @@ func newStalledCondition() metav1.Condition {
 		Status: metav1.ConditionTrue,
 	}
 }
+
+func newReadyCondition(status metav1.ConditionStatus) metav1.Condition {
+	return metav1.Condition{
+		Type:   accuratev2.SubNamespaceReady,
+		Status: status,
+	}
+}
@@ -35,11 +35,6 @@ func RegisterConversions(s *runtime.Scheme) error {
 	}); err != nil {
 		return err
 	}
-	if err := s.AddGeneratedConversionFunc((*v2.SubNamespaceSpec)(nil), (*SubNamespaceSpec)(nil), func(a, b interface{}, scope conversion.Scope) error {
-		return Convert_v2_SubNamespaceSpec_To_v1_SubNamespaceSpec(a.(*v2.SubNamespaceSpec), b.(*SubNamespaceSpec), scope)
-	}); err != nil {
-		return err
-	}
 	if err := s.AddConversionFunc((*SubNamespace)(nil), (*v2.SubNamespace)(nil), func(a, b interface{}, scope conversion.Scope) error {
 		return Convert_v1_SubNamespace_To_v2_SubNamespace(a.(*SubNamespace), b.(*v2.SubNamespace), scope)
 	}); err != nil {
@@ -50,6 +45,11 @@ func RegisterConversions(s *runtime.Scheme) error {
 	}); err != nil {
 		return err
 	}
+	if err := s.AddConversionFunc((*v2.SubNamespaceSpec)(nil), (*SubNamespaceSpec)(nil), func(a, b interface{}, scope conversion.Scope) error {
+		return Convert_v2_SubNamespaceSpec_To_v1_SubNamespaceSpec(a.(*v2.SubNamespaceSpec), b.(*SubNamespaceSpec), scope)
+	}); err != nil {
+		return err
+	}
 	return nil
 }
 
@@ -127,10 +127,7 @@ func Convert_v1_SubNamespaceSpec_To_v2_SubNamespaceSpec(in *SubNamespaceSpec, ou
 func autoConvert_v2_SubNamespaceSpec_To_v1_SubNamespaceSpec(in *v2.SubNamespaceSpec, out *SubNamespaceSpec, s conversion.Scope) error {
 	out.Labels = *(*map[string]string)(unsafe.Pointer(&in.Labels))
 	out.Annotations = *(*map[string]string)(unsafe.Pointer(&in.Annotations))
+	// WARNING: in.DeletionPolicy requires manual conversion: does not exist in peer-type
+	// WARNING: in.KeepPropagatedResources requires manual conversion: does not exist in peer-type
 	return nil
 }
-
-// Convert_v2_SubNamespaceSpec_To_v1_SubNamespaceSpec is an autogenerated conversion function.
-func Convert_v2_SubNamespaceSpec_To_v1_SubNamespaceSpec(in *v2.SubNamespaceSpec, out *SubNamespaceSpec, s conversion.Scope) error {
-	return autoConvert_v2_SubNamespaceSpec_To_v1_SubNamespaceSpec(in, out, s)
-}
@@ -0,0 +1,214 @@
+package v2
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// AccurateConfigName is the name of the AccurateConfig read by Accurate.
+const AccurateConfigName = "default"
+
+// AccurateConfigWatch represents a namespace-scoped resource propagated by Accurate.
+type AccurateConfigWatch struct {
+	// Group is the API group of the resource.
+	// +optional
+	Group string `json:"group,omitempty"`
+
+	// Version is the API version of the resource.
+	// It can be omitted only for wildcard watches to use the preferred version of the group.
+	// +optional
+	Version string `json:"version,omitempty"`
+
+	// Kind is the kind of the resource.
+	// "*" matches all namespace-scoped resources in the group.
+	Kind string `json:"kind"`
+
+	// ConflictPolicy is the policy for objects in child namespaces that have the same
+	// name as a propagated object but were not propagated by Accurate.
+	// +kubebuilder:validation:Enum=skip;overwrite;fail
+	// +optional
+	ConflictPolicy string `json:"conflictPolicy,omitempty"`
+
+	// Modes are the propagation modes allowed for the resource.
+	// If empty, all modes are allowed.
+	// +kubebuilder:validation:items:Enum=create;update;merge
+	// +optional
+	Modes []string `json:"modes,omitempty"`
+
+	// LabelKeyExcludes are the labels not to be copied to propagated objects of the resource.
+	// +optional
+	LabelKeyExcludes []string `json:"labelKeyExcludes,omitempty"`
+
+	// AnnotationKeyExcludes are the annotations not to be copied to propagated objects of the resource.
+	// +optional
+	AnnotationKeyExcludes []string `json:"annotationKeyExcludes,omitempty"`
+
+	// StripFields are the dot-separated paths of fields removed from propagated objects.
+	// +optional
+	StripFields []string `json:"stripFields,omitempty"`
+
+	// Optional defers watching the resource until it is installed
+	// instead of rejecting the configurations.
+	// +optional
+	Optional bool `json:"optional,omitempty"`
+}
+
+// AccurateConfigNamingPolicy represents a naming policy for Namespaces created from SubNamespaces.
+type AccurateConfigNamingPolicy struct {
+	// Root is a regular expression to match the root namespace name.
+	Root string `json:"root"`
+
+	// Match is a regular expression that SubNamespace names in the tree should match.
+	// +optional
+	Match string `json:"match,omitempty"`
+
+	// Deny are regular expressions that SubNamespace names in the tree must not match.
+	// +optional
+	Deny []string `json:"deny,omitempty"`
+
+	// MaxLength is the maximum length of SubNamespace names in the tree.
+	// +kubebuilder:validation:Minimum=0
+	// +optional
+	MaxLength int `json:"maxLength,omitempty"`
+
+	// MaxDepth is the maximum depth of the tree, where children of the root are at depth 1.
+	// +kubebuilder:validation:Minimum=0
+	// +optional
+	MaxDepth int `json:"maxDepth,omitempty"`
+}
+
+// AccurateConfigAutoGraftRule makes namespaces created without SubNamespace sub-namespaces by their names.
+type AccurateConfigAutoGraftRule struct {
+	// Match is a regular expression to match the names of new namespaces.
+	Match string `json:"match"`
+
+	// Parent is the name of the parent namespace.
+	// It can use the capture groups of Match.
+	Parent string `json:"parent"`
+}
+
+// AccurateConfigSubNamespaceLimits limits the number of sub-namespaces.
+type AccurateConfigSubNamespaceLimits struct {
+	// MaxDescendants is the maximum number of sub-namespaces in a tree under a root namespace.
+	// +kubebuilder:validation:Minimum=0
+	// +optional
+	MaxDescendants int `json:"maxDescendants,omitempty"`
+
+	// MaxChildren is the maximum number of direct children of a namespace.
+	// +kubebuilder:validation:Minimum=0
+	// +optional
+	MaxChildren int `json:"maxChildren,omitempty"`
+}
+
+// AccurateConfigSpec defines the configurations of Accurate.
+// The fields are the same as those of the configuration file.
+type AccurateConfigSpec struct {
+	// LabelKeys are the labels to be propagated to sub-namespaces.
+	// +optional
+	LabelKeys []string `json:"labelKeys,omitempty"`
+
+	// AnnotationKeys are the annotations to be propagated to sub-namespaces.
+	// +optional
+	AnnotationKeys []string `json:"annotationKeys,omitempty"`
+
+	// SubNamespaceLabelKeys are the labels to be propagated to sub-namespaces from SubNamespace resources.
+	// +optional
+	SubNamespaceLabelKeys []string `json:"subNamespaceLabelKeys,omitempty"`
+
+	// SubNamespaceAnnotationKeys are the annotations to be propagated to sub-namespaces from SubNamespace resources.
+	// +optional
+	SubNamespaceAnnotationKeys []string `json:"subNamespaceAnnotationKeys,omitempty"`
+
+	// Watches are the namespace-scoped resources that can be propagated.
+	// +optional
+	Watches []AccurateConfigWatch `json:"watches,omitempty"`
+
+	// PropagateLabelKeyExcludes are the labels not to be copied to propagated resources.
+	// +optional
+	PropagateLabelKeyExcludes []string `json:"propagateLabelKeyExcludes,omitempty"`
+
+	// PropagateAnnotationKeyExcludes are the annotations not to be copied to propagated resources.
+	// +optional
+	PropagateAnnotationKeyExcludes []string `json:"propagateAnnotationKeyExcludes,omitempty"`
+
+	// NamingPolicies are the naming policies for SubNamespaces.
+	// +optional
+	NamingPolicies []AccurateConfigNamingPolicy `json:"namingPolicies,omitempty"`
+
+	// SubNamespaceLimits limits the number of sub-namespaces.
+	// +optional
+	SubNamespaceLimits *AccurateConfigSubNamespaceLimits `json:"subNamespaceLimits,omitempty"`
+
+	// SubNamespaceKeyPolicy is how to handle keys in spec.labels and spec.annotations of SubNamespaces
+	// that are never propagated. "warn" admits them with warnings, and "deny" denies them.
+	// +kubebuilder:validation:Enum=warn;deny
+	// +optional
+	SubNamespaceKeyPolicy string `json:"subNamespaceKeyPolicy,omitempty"`
+
+	// AutoGraftRules are the rules to make new namespaces sub-namespaces by their names.
+	// The first matching rule is used.
+	// +optional
+	AutoGraftRules []AccurateConfigAutoGraftRule `json:"autoGraftRules,omitempty"`
+}
+
+// AccurateConfigStatus defines the observed state of AccurateConfig
+type AccurateConfigStatus struct {
+	// The generation observed by the object controller.
+	// +optional
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+
+	// Watches are the resources being watched by Accurate.
+	// +optional
+	Watches []metav1.GroupVersionKind `json:"watches,omitempty"`
+
+	// Conditions represent the latest available observations of an object's state
+	// +listType=map
+	// +listMapKey=type
+	// +optional
+	Conditions []metav1.Condition `json:"conditions,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+//+kubebuilder:resource:scope=Cluster
+//+kubebuilder:subresource:status
+//+kubebuilder:printcolumn:name="Ready",type="string",JSONPath=".status.conditions[?(@.type==\"Ready\")].status"
+//+kubebuilder:printcolumn:name="Age",type="date",JSONPath=".metadata.creationTimestamp"
+//+kubebuilder:validation:XValidation:rule="self.metadata.name == 'default'",message="the name of AccurateConfig must be default"
+
+// AccurateConfig is the Schema for the accurateconfigs API.
+// Accurate reads the configurations from the AccurateConfig named "default".
+type AccurateConfig struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	// Spec is the spec of AccurateConfig.
+	// +optional
+	Spec AccurateConfigSpec `json:"spec,omitempty"`
+
+	// Status is the status of AccurateConfig.
+	// +optional
+	Status AccurateConfigStatus `json:"status,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+
+// AccurateConfigList contains a list of AccurateConfig
+type AccurateConfigList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []AccurateConfig `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&AccurateConfig{}, &AccurateConfigList{})
+}
+
+const (
+	// AccurateConfigReady is the condition type that reports whether the configurations are in effect.
+	AccurateConfigReady string = "Ready"
+
+	// AccurateConfigInvalid is the reason of the Ready condition when the configurations are invalid.
+	AccurateConfigInvalid string = "Invalid"
+
+	// AccurateConfigApplied is the reason of the Ready condition when the configurations are in effect.
+	AccurateConfigApplied string = "Applied"
+)
@@ -0,0 +1,93 @@
+package v2
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+// NamespaceTemplateResource is an object propagated to the instance namespaces.
+type NamespaceTemplateResource struct {
+	// Mode is the propagation mode of the object, in the same way as `accurate.cybozu.com/propagate` annotation.
+	// +kubebuilder:validation:Enum=create;update;merge
+	// +kubebuilder:default=update
+	// +optional
+	Mode string `json:"mode,omitempty"`
+
+	// Manifest is the namespace-scoped object to be propagated.
+	// Its kind must be one of the watched resources, and metadata.namespace is ignored.
+	// +kubebuilder:validation:EmbeddedResource
+	// +kubebuilder:pruning:PreserveUnknownFields
+	Manifest runtime.RawExtension `json:"manifest"`
+}
+
+// NamespaceTemplateSpec defines the labels, annotations and objects of the instance namespaces.
+type NamespaceTemplateSpec struct {
+	// Labels are propagated to the instance namespaces if they match labelKeys of the configurations.
+	// +optional
+	Labels map[string]string `json:"labels,omitempty"`
+
+	// Annotations are propagated to the instance namespaces if they match annotationKeys of the configurations.
+	// +optional
+	Annotations map[string]string `json:"annotations,omitempty"`
+
+	// Resources are the objects propagated to the instance namespaces.
+	// +optional
+	Resources []NamespaceTemplateResource `json:"resources,omitempty"`
+}
+
+// NamespaceTemplateConflict is an object in an instance namespace that conflicts with a resource of NamespaceTemplate.
+type NamespaceTemplateConflict struct {
+	// APIVersion is the API version of the object.
+	APIVersion string `json:"apiVersion"`
+
+	// Kind is the kind of the object.
+	Kind string `json:"kind"`
+
+	// Namespace is the instance namespace of the object.
+	Namespace string `json:"namespace"`
+
+	// Name is the name of the object.
+	Name string `json:"name"`
+}
+
+// NamespaceTemplateStatus defines the observed state of NamespaceTemplate.
+type NamespaceTemplateStatus struct {
+	// Conflicts are the objects in the instance namespaces that were not propagated by Accurate
+	// and conflict with the resources.
+	// +optional
+	Conflicts []NamespaceTemplateConflict `json:"conflicts,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+//+kubebuilder:resource:scope=Cluster
+//+kubebuilder:subresource:status
+//+kubebuilder:printcolumn:name="Age",type="date",JSONPath=".metadata.creationTimestamp"
+
+// NamespaceTemplate is a template for namespaces without a template namespace.
+// Namespaces refer to it by `accurate.cybozu.com/template` label in the same way as template namespaces.
+// A template namespace of the same name takes precedence over it.
+type NamespaceTemplate struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	// Spec is the spec of NamespaceTemplate.
+	// +optional
+	Spec NamespaceTemplateSpec `json:"spec,omitempty"`
+
+	// Status is the status of NamespaceTemplate.
+	// +optional
+	Status NamespaceTemplateStatus `json:"status,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+
+// NamespaceTemplateList contains a list of NamespaceTemplate
+type NamespaceTemplateList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []NamespaceTemplate `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&NamespaceTemplate{}, &NamespaceTemplateList{})
+}
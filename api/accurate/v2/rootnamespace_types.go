@@ -0,0 +1,129 @@
+package v2
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// RootNamespaceNamingPolicy is the naming policy for sub-namespaces in a tree.
+// It is applied in addition to the naming policies of the configurations.
+type RootNamespaceNamingPolicy struct {
+	// Match is a regular expression that the names of sub-namespaces in the tree must match.
+	// +optional
+	Match string `json:"match,omitempty"`
+
+	// Deny are regular expressions that the names of sub-namespaces in the tree must not match.
+	// +optional
+	Deny []string `json:"deny,omitempty"`
+
+	// MaxLength is the maximum length of the names of sub-namespaces in the tree.
+	// +kubebuilder:validation:Minimum=0
+	// +optional
+	MaxLength int `json:"maxLength,omitempty"`
+
+	// MaxDepth is the maximum depth of the tree, where children of the root are at depth 1.
+	// +kubebuilder:validation:Minimum=0
+	// +optional
+	MaxDepth int `json:"maxDepth,omitempty"`
+}
+
+// RootNamespaceSpec defines the policy for the tree under a root namespace.
+type RootNamespaceSpec struct {
+	// NamingPolicy is the naming policy for sub-namespaces in the tree.
+	// +optional
+	NamingPolicy *RootNamespaceNamingPolicy `json:"namingPolicy,omitempty"`
+
+	// SubNamespaceLimits limits the number of sub-namespaces in the tree.
+	// They take precedence over the configurations and the annotations of the root namespace.
+	// +optional
+	SubNamespaceLimits *AccurateConfigSubNamespaceLimits `json:"subNamespaceLimits,omitempty"`
+
+	// LabelKeys are the labels to be propagated to sub-namespaces in the tree
+	// in addition to labelKeys of the configurations.
+	// +optional
+	LabelKeys []string `json:"labelKeys,omitempty"`
+
+	// AnnotationKeys are the annotations to be propagated to sub-namespaces in the tree
+	// in addition to annotationKeys of the configurations.
+	// +optional
+	AnnotationKeys []string `json:"annotationKeys,omitempty"`
+
+	// AllowCascadingDeletion overrides whether namespaces in the tree that have children can be deleted.
+	// If unset, the command-line flag of the webhook is used.
+	// +optional
+	AllowCascadingDeletion *bool `json:"allowCascadingDeletion,omitempty"`
+}
+
+// RootNamespaceStatus summarizes the tree under a root namespace.
+type RootNamespaceStatus struct {
+	// The generation observed by the object controller.
+	// +optional
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+
+	// Conditions represent the latest available observations of an object's state
+	// +listType=map
+	// +listMapKey=type
+	// +optional
+	Conditions []metav1.Condition `json:"conditions,omitempty"`
+
+	// Namespaces is the number of sub-namespaces in the tree.
+	// +optional
+	Namespaces int32 `json:"namespaces,omitempty"`
+
+	// Depth is the depth of the tree, where children of the root are at depth 1.
+	// +optional
+	Depth int32 `json:"depth,omitempty"`
+
+	// Conflicts is the number of SubNamespaces in the tree that conflict with existing namespaces.
+	// +optional
+	Conflicts int32 `json:"conflicts,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+//+kubebuilder:resource:scope=Cluster
+//+kubebuilder:subresource:status
+//+kubebuilder:printcolumn:name="Ready",type="string",JSONPath=".status.conditions[?(@.type==\"Ready\")].status"
+//+kubebuilder:printcolumn:name="Namespaces",type="integer",JSONPath=".status.namespaces"
+//+kubebuilder:printcolumn:name="Depth",type="integer",JSONPath=".status.depth"
+//+kubebuilder:printcolumn:name="Age",type="date",JSONPath=".metadata.creationTimestamp"
+
+// RootNamespace declares the namespace of the same name as a root namespace,
+// and holds the policy for the tree under it.
+type RootNamespace struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	// Spec is the spec of RootNamespace.
+	// +optional
+	Spec RootNamespaceSpec `json:"spec,omitempty"`
+
+	// Status is the status of RootNamespace.
+	// +optional
+	Status RootNamespaceStatus `json:"status,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+
+// RootNamespaceList contains a list of RootNamespace
+type RootNamespaceList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []RootNamespace `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&RootNamespace{}, &RootNamespaceList{})
+}
+
+const (
+	// RootNamespaceReady is the condition type that reports whether the namespace is a root namespace.
+	RootNamespaceReady string = "Ready"
+
+	// RootNamespaceActive is the reason of the Ready condition when the namespace is a root namespace.
+	RootNamespaceActive string = "Active"
+
+	// RootNamespaceNotFound is the reason of the Ready condition when the namespace does not exist.
+	RootNamespaceNotFound string = "NamespaceNotFound"
+
+	// RootNamespaceNotRoot is the reason of the Ready condition when the namespace is a sub-namespace.
+	RootNamespaceNotRoot string = "NotRoot"
+)
@@ -1,7 +1,9 @@
 package v2
 
 import (
+	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
 )
 
 // EDIT THIS FILE!  THIS IS SCAFFOLDING FOR YOU TO OWN!
@@ -18,6 +20,22 @@ type SubNamespaceStatus struct {
 	// +listMapKey=type
 	// +optional
 	Conditions []metav1.Condition `json:"conditions,omitempty"`
+
+	// NamespaceUID is the UID of the namespace created for the SubNamespace.
+	// +optional
+	NamespaceUID types.UID `json:"namespaceUID,omitempty"`
+
+	// NamespacePhase is the phase of the namespace created for the SubNamespace.
+	// +optional
+	NamespacePhase corev1.NamespacePhase `json:"namespacePhase,omitempty"`
+
+	// Path is the names of the namespaces from the root namespace to the created namespace.
+	// +optional
+	Path []string `json:"path,omitempty"`
+
+	// PropagatedResources is the number of resources propagated into the created namespace.
+	// +optional
+	PropagatedResources int32 `json:"propagatedResources,omitempty"`
 }
 
 // SubNamespaceSpec defines the desired state of SubNamespace
@@ -29,11 +47,38 @@ type SubNamespaceSpec struct {
 	// Annotations are the annotations to be propagated to the sub-namespace.
 	// +optional
 	Annotations map[string]string `json:"annotations,omitempty"`
+
+	// DeletionPolicy specifies what happens to the sub-namespace when the SubNamespace is deleted.
+	// "Delete" deletes the sub-namespace. "Orphan" detaches the sub-namespace from its parent and keeps it.
+	// Defaults to "Delete".
+	// +kubebuilder:validation:Enum=Delete;Orphan
+	// +optional
+	DeletionPolicy DeletionPolicy `json:"deletionPolicy,omitempty"`
+
+	// KeepPropagatedResources keeps the resources propagated to the sub-namespace as ordinary resources
+	// when the sub-namespace is orphaned. Otherwise, they are deleted.
+	// This is effective only when DeletionPolicy is "Orphan".
+	// +optional
+	KeepPropagatedResources bool `json:"keepPropagatedResources,omitempty"`
 }
 
+// DeletionPolicy specifies what happens to a sub-namespace when its SubNamespace is deleted.
+type DeletionPolicy string
+
+const (
+	// DeletionPolicyDelete deletes the sub-namespace.
+	DeletionPolicyDelete DeletionPolicy = "Delete"
+
+	// DeletionPolicyOrphan removes the sub-namespace from the tree and keeps it.
+	DeletionPolicyOrphan DeletionPolicy = "Orphan"
+)
+
 //+kubebuilder:object:root=true
 //+kubebuilder:storageversion
 //+kubebuilder:subresource:status
+//+kubebuilder:printcolumn:name="Ready",type="string",JSONPath=".status.conditions[?(@.type==\"Ready\")].status"
+//+kubebuilder:printcolumn:name="Path",type="string",JSONPath=".status.path",priority=1
+//+kubebuilder:printcolumn:name="Age",type="date",JSONPath=".metadata.creationTimestamp"
 //+genclient
 
 // SubNamespace is the Schema for the subnamespaces API
@@ -64,5 +109,24 @@ func init() {
 }
 
 const (
+	// SubNamespaceReady is the condition type that reports whether the namespace is created and active.
+	SubNamespaceReady string = "Ready"
+
+	// SubNamespaceConflict is the reason when a namespace of the same name is not a child of the parent.
 	SubNamespaceConflict string = "Conflict"
+
+	// SubNamespaceAdoptionDenied is the reason when adopting an existing namespace is denied.
+	SubNamespaceAdoptionDenied string = "AdoptionDenied"
+
+	// SubNamespaceActive is the reason of the Ready condition when the namespace is active.
+	SubNamespaceActive string = "NamespaceActive"
+
+	// SubNamespaceTerminating is the reason when the namespace is being deleted and will be recreated.
+	SubNamespaceTerminating string = "NamespaceTerminating"
+
+	// SubNamespaceCreating is the reason when the namespace has just been created.
+	SubNamespaceCreating string = "NamespaceCreating"
+
+	// SubNamespaceAdopting is the reason when an existing namespace has just been adopted.
+	SubNamespaceAdopting string = "NamespaceAdopting"
 )
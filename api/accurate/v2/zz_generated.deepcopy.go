@@ -6,9 +6,536 @@ package v2
 
 import (
 	"k8s.io/apimachinery/pkg/apis/meta/v1"
-	runtime "k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime"
 )
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *AccurateConfig) DeepCopyInto(out *AccurateConfig) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+	in.Status.DeepCopyInto(&out.Status)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new AccurateConfig.
+func (in *AccurateConfig) DeepCopy() *AccurateConfig {
+	if in == nil {
+		return nil
+	}
+	out := new(AccurateConfig)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *AccurateConfig) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *AccurateConfigAutoGraftRule) DeepCopyInto(out *AccurateConfigAutoGraftRule) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new AccurateConfigAutoGraftRule.
+func (in *AccurateConfigAutoGraftRule) DeepCopy() *AccurateConfigAutoGraftRule {
+	if in == nil {
+		return nil
+	}
+	out := new(AccurateConfigAutoGraftRule)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *AccurateConfigList) DeepCopyInto(out *AccurateConfigList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]AccurateConfig, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new AccurateConfigList.
+func (in *AccurateConfigList) DeepCopy() *AccurateConfigList {
+	if in == nil {
+		return nil
+	}
+	out := new(AccurateConfigList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *AccurateConfigList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *AccurateConfigNamingPolicy) DeepCopyInto(out *AccurateConfigNamingPolicy) {
+	*out = *in
+	if in.Deny != nil {
+		in, out := &in.Deny, &out.Deny
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new AccurateConfigNamingPolicy.
+func (in *AccurateConfigNamingPolicy) DeepCopy() *AccurateConfigNamingPolicy {
+	if in == nil {
+		return nil
+	}
+	out := new(AccurateConfigNamingPolicy)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *AccurateConfigSpec) DeepCopyInto(out *AccurateConfigSpec) {
+	*out = *in
+	if in.LabelKeys != nil {
+		in, out := &in.LabelKeys, &out.LabelKeys
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.AnnotationKeys != nil {
+		in, out := &in.AnnotationKeys, &out.AnnotationKeys
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.SubNamespaceLabelKeys != nil {
+		in, out := &in.SubNamespaceLabelKeys, &out.SubNamespaceLabelKeys
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.SubNamespaceAnnotationKeys != nil {
+		in, out := &in.SubNamespaceAnnotationKeys, &out.SubNamespaceAnnotationKeys
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.Watches != nil {
+		in, out := &in.Watches, &out.Watches
+		*out = make([]AccurateConfigWatch, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.PropagateLabelKeyExcludes != nil {
+		in, out := &in.PropagateLabelKeyExcludes, &out.PropagateLabelKeyExcludes
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.PropagateAnnotationKeyExcludes != nil {
+		in, out := &in.PropagateAnnotationKeyExcludes, &out.PropagateAnnotationKeyExcludes
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.NamingPolicies != nil {
+		in, out := &in.NamingPolicies, &out.NamingPolicies
+		*out = make([]AccurateConfigNamingPolicy, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.SubNamespaceLimits != nil {
+		in, out := &in.SubNamespaceLimits, &out.SubNamespaceLimits
+		*out = new(AccurateConfigSubNamespaceLimits)
+		**out = **in
+	}
+	if in.AutoGraftRules != nil {
+		in, out := &in.AutoGraftRules, &out.AutoGraftRules
+		*out = make([]AccurateConfigAutoGraftRule, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new AccurateConfigSpec.
+func (in *AccurateConfigSpec) DeepCopy() *AccurateConfigSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(AccurateConfigSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *AccurateConfigStatus) DeepCopyInto(out *AccurateConfigStatus) {
+	*out = *in
+	if in.Watches != nil {
+		in, out := &in.Watches, &out.Watches
+		*out = make([]v1.GroupVersionKind, len(*in))
+		copy(*out, *in)
+	}
+	if in.Conditions != nil {
+		in, out := &in.Conditions, &out.Conditions
+		*out = make([]v1.Condition, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new AccurateConfigStatus.
+func (in *AccurateConfigStatus) DeepCopy() *AccurateConfigStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(AccurateConfigStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *AccurateConfigSubNamespaceLimits) DeepCopyInto(out *AccurateConfigSubNamespaceLimits) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new AccurateConfigSubNamespaceLimits.
+func (in *AccurateConfigSubNamespaceLimits) DeepCopy() *AccurateConfigSubNamespaceLimits {
+	if in == nil {
+		return nil
+	}
+	out := new(AccurateConfigSubNamespaceLimits)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *AccurateConfigWatch) DeepCopyInto(out *AccurateConfigWatch) {
+	*out = *in
+	if in.Modes != nil {
+		in, out := &in.Modes, &out.Modes
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.LabelKeyExcludes != nil {
+		in, out := &in.LabelKeyExcludes, &out.LabelKeyExcludes
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.AnnotationKeyExcludes != nil {
+		in, out := &in.AnnotationKeyExcludes, &out.AnnotationKeyExcludes
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.StripFields != nil {
+		in, out := &in.StripFields, &out.StripFields
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new AccurateConfigWatch.
+func (in *AccurateConfigWatch) DeepCopy() *AccurateConfigWatch {
+	if in == nil {
+		return nil
+	}
+	out := new(AccurateConfigWatch)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *NamespaceTemplate) DeepCopyInto(out *NamespaceTemplate) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+	in.Status.DeepCopyInto(&out.Status)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new NamespaceTemplate.
+func (in *NamespaceTemplate) DeepCopy() *NamespaceTemplate {
+	if in == nil {
+		return nil
+	}
+	out := new(NamespaceTemplate)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *NamespaceTemplate) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *NamespaceTemplateConflict) DeepCopyInto(out *NamespaceTemplateConflict) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new NamespaceTemplateConflict.
+func (in *NamespaceTemplateConflict) DeepCopy() *NamespaceTemplateConflict {
+	if in == nil {
+		return nil
+	}
+	out := new(NamespaceTemplateConflict)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *NamespaceTemplateList) DeepCopyInto(out *NamespaceTemplateList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]NamespaceTemplate, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new NamespaceTemplateList.
+func (in *NamespaceTemplateList) DeepCopy() *NamespaceTemplateList {
+	if in == nil {
+		return nil
+	}
+	out := new(NamespaceTemplateList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *NamespaceTemplateList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *NamespaceTemplateResource) DeepCopyInto(out *NamespaceTemplateResource) {
+	*out = *in
+	in.Manifest.DeepCopyInto(&out.Manifest)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new NamespaceTemplateResource.
+func (in *NamespaceTemplateResource) DeepCopy() *NamespaceTemplateResource {
+	if in == nil {
+		return nil
+	}
+	out := new(NamespaceTemplateResource)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *NamespaceTemplateSpec) DeepCopyInto(out *NamespaceTemplateSpec) {
+	*out = *in
+	if in.Labels != nil {
+		in, out := &in.Labels, &out.Labels
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.Annotations != nil {
+		in, out := &in.Annotations, &out.Annotations
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.Resources != nil {
+		in, out := &in.Resources, &out.Resources
+		*out = make([]NamespaceTemplateResource, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new NamespaceTemplateSpec.
+func (in *NamespaceTemplateSpec) DeepCopy() *NamespaceTemplateSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(NamespaceTemplateSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *NamespaceTemplateStatus) DeepCopyInto(out *NamespaceTemplateStatus) {
+	*out = *in
+	if in.Conflicts != nil {
+		in, out := &in.Conflicts, &out.Conflicts
+		*out = make([]NamespaceTemplateConflict, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new NamespaceTemplateStatus.
+func (in *NamespaceTemplateStatus) DeepCopy() *NamespaceTemplateStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(NamespaceTemplateStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RootNamespace) DeepCopyInto(out *RootNamespace) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+	in.Status.DeepCopyInto(&out.Status)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RootNamespace.
+func (in *RootNamespace) DeepCopy() *RootNamespace {
+	if in == nil {
+		return nil
+	}
+	out := new(RootNamespace)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *RootNamespace) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RootNamespaceList) DeepCopyInto(out *RootNamespaceList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]RootNamespace, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RootNamespaceList.
+func (in *RootNamespaceList) DeepCopy() *RootNamespaceList {
+	if in == nil {
+		return nil
+	}
+	out := new(RootNamespaceList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *RootNamespaceList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RootNamespaceNamingPolicy) DeepCopyInto(out *RootNamespaceNamingPolicy) {
+	*out = *in
+	if in.Deny != nil {
+		in, out := &in.Deny, &out.Deny
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RootNamespaceNamingPolicy.
+func (in *RootNamespaceNamingPolicy) DeepCopy() *RootNamespaceNamingPolicy {
+	if in == nil {
+		return nil
+	}
+	out := new(RootNamespaceNamingPolicy)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RootNamespaceSpec) DeepCopyInto(out *RootNamespaceSpec) {
+	*out = *in
+	if in.NamingPolicy != nil {
+		in, out := &in.NamingPolicy, &out.NamingPolicy
+		*out = new(RootNamespaceNamingPolicy)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.SubNamespaceLimits != nil {
+		in, out := &in.SubNamespaceLimits, &out.SubNamespaceLimits
+		*out = new(AccurateConfigSubNamespaceLimits)
+		**out = **in
+	}
+	if in.LabelKeys != nil {
+		in, out := &in.LabelKeys, &out.LabelKeys
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.AnnotationKeys != nil {
+		in, out := &in.AnnotationKeys, &out.AnnotationKeys
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.AllowCascadingDeletion != nil {
+		in, out := &in.AllowCascadingDeletion, &out.AllowCascadingDeletion
+		*out = new(bool)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RootNamespaceSpec.
+func (in *RootNamespaceSpec) DeepCopy() *RootNamespaceSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(RootNamespaceSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RootNamespaceStatus) DeepCopyInto(out *RootNamespaceStatus) {
+	*out = *in
+	if in.Conditions != nil {
+		in, out := &in.Conditions, &out.Conditions
+		*out = make([]v1.Condition, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RootNamespaceStatus.
+func (in *RootNamespaceStatus) DeepCopy() *RootNamespaceStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(RootNamespaceStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *SubNamespace) DeepCopyInto(out *SubNamespace) {
 	*out = *in
@@ -107,6 +634,11 @@ func (in *SubNamespaceStatus) DeepCopyInto(out *SubNamespaceStatus) {
 			(*in)[i].DeepCopyInto(&(*out)[i])
 		}
 	}
+	if in.Path != nil {
+		in, out := &in.Path, &out.Path
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SubNamespaceStatus.
@@ -0,0 +1,73 @@
+package v2alpha1
+
+import (
+	"fmt"
+	"strconv"
+
+	accuratev2 "github.com/cybozu-go/accurate/api/accurate/v2"
+	"github.com/cybozu-go/accurate/pkg/constants"
+	"k8s.io/apimachinery/pkg/conversion"
+)
+
+// Convert_v2alpha1_SubNamespace_To_v2_SubNamespace complements the generated conversion functions
+// since the spec fields added in v2 are stored in annotations.
+func Convert_v2alpha1_SubNamespace_To_v2_SubNamespace(in *SubNamespace, out *accuratev2.SubNamespace, s conversion.Scope) error {
+	if err := autoConvert_v2alpha1_SubNamespace_To_v2_SubNamespace(in, out, s); err != nil {
+		return err
+	}
+
+	// Restore info from annotations to ensure conversions are lossy-less.
+	// Delete annotation after processing it to avoid polluting converted resource.
+	if v, ok := out.Annotations[constants.AnnDeletionPolicy]; ok {
+		out.Spec.DeletionPolicy = accuratev2.DeletionPolicy(v)
+
+		delete(out.Annotations, constants.AnnDeletionPolicy)
+	}
+	if v, ok := out.Annotations[constants.AnnKeepPropagatedResources]; ok {
+		keep, err := strconv.ParseBool(v)
+		if err != nil {
+			return fmt.Errorf("error converting %q to bool from annotation %s", v, constants.AnnKeepPropagatedResources)
+		}
+		out.Spec.KeepPropagatedResources = keep
+
+		delete(out.Annotations, constants.AnnKeepPropagatedResources)
+	}
+	return nil
+}
+
+// Convert_v2_SubNamespace_To_v2alpha1_SubNamespace complements the generated conversion functions
+// since the spec fields added in v2 are stored in annotations.
+func Convert_v2_SubNamespace_To_v2alpha1_SubNamespace(in *accuratev2.SubNamespace, out *SubNamespace, s conversion.Scope) error {
+	if err := autoConvert_v2_SubNamespace_To_v2alpha1_SubNamespace(in, out, s); err != nil {
+		return err
+	}
+
+	// Store info in annotations to ensure conversions are lossy-less.
+	if in.Spec.DeletionPolicy == "" && !in.Spec.KeepPropagatedResources {
+		return nil
+	}
+	if out.Annotations == nil {
+		out.Annotations = make(map[string]string)
+	}
+	if in.Spec.DeletionPolicy != "" {
+		out.Annotations[constants.AnnDeletionPolicy] = string(in.Spec.DeletionPolicy)
+	}
+	if in.Spec.KeepPropagatedResources {
+		out.Annotations[constants.AnnKeepPropagatedResources] = strconv.FormatBool(in.Spec.KeepPropagatedResources)
+	}
+	return nil
+}
+
+// Convert_v2_SubNamespaceSpec_To_v2alpha1_SubNamespaceSpec complements the generated conversion functions
+// since the spec fields added in v2 do not exist in v2alpha1.
+// They are stored in annotations by Convert_v2_SubNamespace_To_v2alpha1_SubNamespace.
+func Convert_v2_SubNamespaceSpec_To_v2alpha1_SubNamespaceSpec(in *accuratev2.SubNamespaceSpec, out *SubNamespaceSpec, s conversion.Scope) error {
+	return autoConvert_v2_SubNamespaceSpec_To_v2alpha1_SubNamespaceSpec(in, out, s)
+}
+
+// Convert_v2_SubNamespaceStatus_To_v2alpha1_SubNamespaceStatus complements the generated conversion functions
+// since the status fields added in v2 do not exist in v2alpha1.
+// They are dropped as the status is written only by the controller through v2.
+func Convert_v2_SubNamespaceStatus_To_v2alpha1_SubNamespaceStatus(in *accuratev2.SubNamespaceStatus, out *SubNamespaceStatus, s conversion.Scope) error {
+	return autoConvert_v2_SubNamespaceStatus_To_v2alpha1_SubNamespaceStatus(in, out, s)
+}
@@ -21,9 +21,21 @@ func TestFuzzyConversion(t *testing.T) {
 func SubNamespaceStatusFuzzFunc(_ runtimeserializer.CodecFactory) []any {
 	return []any{
 		SubNamespaceStatusFuzzer,
+		hubSubNamespaceStatusFuzzer,
 	}
 }
 
 func SubNamespaceStatusFuzzer(in *SubNamespace, c randfill.Continue) {
 	c.FillNoCustom(in)
 }
+
+func hubSubNamespaceStatusFuzzer(in *accuratev2.SubNamespaceStatus, c randfill.Continue) {
+	c.FillNoCustom(in)
+
+	// The status fields added in v2 do not exist in v2alpha1, and they are dropped on conversion.
+	// This is fine because the controller is the sole actor updating status, and it does so through v2.
+	in.NamespaceUID = ""
+	in.NamespacePhase = ""
+	in.Path = nil
+	in.PropagatedResources = 0
+}
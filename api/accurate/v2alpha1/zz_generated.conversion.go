@@ -21,16 +21,6 @@ func init() {
 // RegisterConversions adds conversion functions to the given scheme.
 // Public to allow building arbitrary schemes.
 func RegisterConversions(s *runtime.Scheme) error {
-	if err := s.AddGeneratedConversionFunc((*SubNamespace)(nil), (*v2.SubNamespace)(nil), func(a, b interface{}, scope conversion.Scope) error {
-		return Convert_v2alpha1_SubNamespace_To_v2_SubNamespace(a.(*SubNamespace), b.(*v2.SubNamespace), scope)
-	}); err != nil {
-		return err
-	}
-	if err := s.AddGeneratedConversionFunc((*v2.SubNamespace)(nil), (*SubNamespace)(nil), func(a, b interface{}, scope conversion.Scope) error {
-		return Convert_v2_SubNamespace_To_v2alpha1_SubNamespace(a.(*v2.SubNamespace), b.(*SubNamespace), scope)
-	}); err != nil {
-		return err
-	}
 	if err := s.AddGeneratedConversionFunc((*SubNamespaceList)(nil), (*v2.SubNamespaceList)(nil), func(a, b interface{}, scope conversion.Scope) error {
 		return Convert_v2alpha1_SubNamespaceList_To_v2_SubNamespaceList(a.(*SubNamespaceList), b.(*v2.SubNamespaceList), scope)
 	}); err != nil {
@@ -46,17 +36,27 @@ func RegisterConversions(s *runtime.Scheme) error {
 	}); err != nil {
 		return err
 	}
-	if err := s.AddGeneratedConversionFunc((*v2.SubNamespaceSpec)(nil), (*SubNamespaceSpec)(nil), func(a, b interface{}, scope conversion.Scope) error {
-		return Convert_v2_SubNamespaceSpec_To_v2alpha1_SubNamespaceSpec(a.(*v2.SubNamespaceSpec), b.(*SubNamespaceSpec), scope)
+	if err := s.AddGeneratedConversionFunc((*SubNamespaceStatus)(nil), (*v2.SubNamespaceStatus)(nil), func(a, b interface{}, scope conversion.Scope) error {
+		return Convert_v2alpha1_SubNamespaceStatus_To_v2_SubNamespaceStatus(a.(*SubNamespaceStatus), b.(*v2.SubNamespaceStatus), scope)
 	}); err != nil {
 		return err
 	}
-	if err := s.AddGeneratedConversionFunc((*SubNamespaceStatus)(nil), (*v2.SubNamespaceStatus)(nil), func(a, b interface{}, scope conversion.Scope) error {
-		return Convert_v2alpha1_SubNamespaceStatus_To_v2_SubNamespaceStatus(a.(*SubNamespaceStatus), b.(*v2.SubNamespaceStatus), scope)
+	if err := s.AddConversionFunc((*SubNamespace)(nil), (*v2.SubNamespace)(nil), func(a, b interface{}, scope conversion.Scope) error {
+		return Convert_v2alpha1_SubNamespace_To_v2_SubNamespace(a.(*SubNamespace), b.(*v2.SubNamespace), scope)
+	}); err != nil {
+		return err
+	}
+	if err := s.AddConversionFunc((*v2.SubNamespace)(nil), (*SubNamespace)(nil), func(a, b interface{}, scope conversion.Scope) error {
+		return Convert_v2_SubNamespace_To_v2alpha1_SubNamespace(a.(*v2.SubNamespace), b.(*SubNamespace), scope)
+	}); err != nil {
+		return err
+	}
+	if err := s.AddConversionFunc((*v2.SubNamespaceSpec)(nil), (*SubNamespaceSpec)(nil), func(a, b interface{}, scope conversion.Scope) error {
+		return Convert_v2_SubNamespaceSpec_To_v2alpha1_SubNamespaceSpec(a.(*v2.SubNamespaceSpec), b.(*SubNamespaceSpec), scope)
 	}); err != nil {
 		return err
 	}
-	if err := s.AddGeneratedConversionFunc((*v2.SubNamespaceStatus)(nil), (*SubNamespaceStatus)(nil), func(a, b interface{}, scope conversion.Scope) error {
+	if err := s.AddConversionFunc((*v2.SubNamespaceStatus)(nil), (*SubNamespaceStatus)(nil), func(a, b interface{}, scope conversion.Scope) error {
 		return Convert_v2_SubNamespaceStatus_To_v2alpha1_SubNamespaceStatus(a.(*v2.SubNamespaceStatus), b.(*SubNamespaceStatus), scope)
 	}); err != nil {
 		return err
@@ -75,11 +75,6 @@ func autoConvert_v2alpha1_SubNamespace_To_v2_SubNamespace(in *SubNamespace, out
 	return nil
 }
 
-// Convert_v2alpha1_SubNamespace_To_v2_SubNamespace is an autogenerated conversion function.
-func Convert_v2alpha1_SubNamespace_To_v2_SubNamespace(in *SubNamespace, out *v2.SubNamespace, s conversion.Scope) error {
-	return autoConvert_v2alpha1_SubNamespace_To_v2_SubNamespace(in, out, s)
-}
-
 func autoConvert_v2_SubNamespace_To_v2alpha1_SubNamespace(in *v2.SubNamespace, out *SubNamespace, s conversion.Scope) error {
 	out.ObjectMeta = in.ObjectMeta
 	if err := Convert_v2_SubNamespaceSpec_To_v2alpha1_SubNamespaceSpec(&in.Spec, &out.Spec, s); err != nil {
@@ -91,14 +86,19 @@ func autoConvert_v2_SubNamespace_To_v2alpha1_SubNamespace(in *v2.SubNamespace, o
 	return nil
 }
 
-// Convert_v2_SubNamespace_To_v2alpha1_SubNamespace is an autogenerated conversion function.
-func Convert_v2_SubNamespace_To_v2alpha1_SubNamespace(in *v2.SubNamespace, out *SubNamespace, s conversion.Scope) error {
-	return autoConvert_v2_SubNamespace_To_v2alpha1_SubNamespace(in, out, s)
-}
-
 func autoConvert_v2alpha1_SubNamespaceList_To_v2_SubNamespaceList(in *SubNamespaceList, out *v2.SubNamespaceList, s conversion.Scope) error {
 	out.ListMeta = in.ListMeta
-	out.Items = *(*[]v2.SubNamespace)(unsafe.Pointer(&in.Items))
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]v2.SubNamespace, len(*in))
+		for i := range *in {
+			if err := Convert_v2alpha1_SubNamespace_To_v2_SubNamespace(&(*in)[i], &(*out)[i], s); err != nil {
+				return err
+			}
+		}
+	} else {
+		out.Items = nil
+	}
 	return nil
 }
 
@@ -109,7 +109,17 @@ func Convert_v2alpha1_SubNamespaceList_To_v2_SubNamespaceList(in *SubNamespaceLi
 
 func autoConvert_v2_SubNamespaceList_To_v2alpha1_SubNamespaceList(in *v2.SubNamespaceList, out *SubNamespaceList, s conversion.Scope) error {
 	out.ListMeta = in.ListMeta
-	out.Items = *(*[]SubNamespace)(unsafe.Pointer(&in.Items))
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]SubNamespace, len(*in))
+		for i := range *in {
+			if err := Convert_v2_SubNamespace_To_v2alpha1_SubNamespace(&(*in)[i], &(*out)[i], s); err != nil {
+				return err
+			}
+		}
+	} else {
+		out.Items = nil
+	}
 	return nil
 }
 
@@ -132,14 +142,11 @@ func Convert_v2alpha1_SubNamespaceSpec_To_v2_SubNamespaceSpec(in *SubNamespaceSp
 func autoConvert_v2_SubNamespaceSpec_To_v2alpha1_SubNamespaceSpec(in *v2.SubNamespaceSpec, out *SubNamespaceSpec, s conversion.Scope) error {
 	out.Labels = *(*map[string]string)(unsafe.Pointer(&in.Labels))
 	out.Annotations = *(*map[string]string)(unsafe.Pointer(&in.Annotations))
+	// WARNING: in.DeletionPolicy requires manual conversion: does not exist in peer-type
+	// WARNING: in.KeepPropagatedResources requires manual conversion: does not exist in peer-type
 	return nil
 }
 
-// Convert_v2_SubNamespaceSpec_To_v2alpha1_SubNamespaceSpec is an autogenerated conversion function.
-func Convert_v2_SubNamespaceSpec_To_v2alpha1_SubNamespaceSpec(in *v2.SubNamespaceSpec, out *SubNamespaceSpec, s conversion.Scope) error {
-	return autoConvert_v2_SubNamespaceSpec_To_v2alpha1_SubNamespaceSpec(in, out, s)
-}
-
 func autoConvert_v2alpha1_SubNamespaceStatus_To_v2_SubNamespaceStatus(in *SubNamespaceStatus, out *v2.SubNamespaceStatus, s conversion.Scope) error {
 	out.ObservedGeneration = in.ObservedGeneration
 	out.Conditions = *(*[]v1.Condition)(unsafe.Pointer(&in.Conditions))
@@ -154,10 +161,9 @@ func Convert_v2alpha1_SubNamespaceStatus_To_v2_SubNamespaceStatus(in *SubNamespa
 func autoConvert_v2_SubNamespaceStatus_To_v2alpha1_SubNamespaceStatus(in *v2.SubNamespaceStatus, out *SubNamespaceStatus, s conversion.Scope) error {
 	out.ObservedGeneration = in.ObservedGeneration
 	out.Conditions = *(*[]v1.Condition)(unsafe.Pointer(&in.Conditions))
+	// WARNING: in.NamespaceUID requires manual conversion: does not exist in peer-type
+	// WARNING: in.NamespacePhase requires manual conversion: does not exist in peer-type
+	// WARNING: in.Path requires manual conversion: does not exist in peer-type
+	// WARNING: in.PropagatedResources requires manual conversion: does not exist in peer-type
 	return nil
 }
-
-// Convert_v2_SubNamespaceStatus_To_v2alpha1_SubNamespaceStatus is an autogenerated conversion function.
-func Convert_v2_SubNamespaceStatus_To_v2alpha1_SubNamespaceStatus(in *v2.SubNamespaceStatus, out *SubNamespaceStatus, s conversion.Scope) error {
-	return autoConvert_v2_SubNamespaceStatus_To_v2alpha1_SubNamespaceStatus(in, out, s)
-}
@@ -0,0 +1,161 @@
+package sub
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/cybozu-go/accurate/controllers"
+	"github.com/cybozu-go/accurate/hooks"
+	"github.com/cybozu-go/accurate/pkg/config"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/discovery"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+)
+
+// configApplier brings new configurations into effect without restarting the manager.
+//
+// The webhooks read their settings from hooks.SubNamespaceKeys, hooks.SubNamespacePolicies,
+// hooks.AutoGraftRules, and hooks.WatchedKinds, which are safe to update while the webhooks
+// are serving requests.  They are updated one by one, so a request admitted during a reload
+// may be checked against a mix of the old and new configurations.
+type configApplier struct {
+	mgr          ctrl.Manager
+	nsReconciler *controllers.NamespaceReconciler
+	snKeys       *hooks.SubNamespaceKeys
+	snPolicies   *hooks.SubNamespacePolicies
+	graftRules   *hooks.AutoGraftRules
+	watchedKinds *hooks.WatchedKinds
+	watches      *controllers.Watches
+	dc           discovery.DiscoveryInterface
+
+	mu sync.Mutex
+	// cfg is the configurations in effect.
+	cfg *config.Config
+	// resolved are the resources watched for cfg.
+	resolved []schema.GroupVersionKind
+}
+
+// configReloader reloads the configuration file when it is changed.
+//
+// A new configuration is validated in the same way as at startup.
+// If it is invalid, the last valid configuration is kept.
+type configReloader struct {
+	*configApplier
+	path     string
+	interval time.Duration
+
+	// data is the content of the last loaded configuration file.
+	data []byte
+}
+
+var _ manager.LeaderElectionRunnable = &configReloader{}
+
+// Start implements manager.Runnable interface.
+func (r *configReloader) Start(ctx context.Context) error {
+	logger := ctrl.Log.WithName("config-reloader")
+	ctx = ctrl.LoggerInto(ctx, logger)
+
+	ticker := time.NewTicker(r.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+
+		data, err := os.ReadFile(r.path)
+		if err != nil {
+			logger.Error(err, "failed to read the configuration file", "path", r.path)
+			continue
+		}
+		if bytes.Equal(data, r.data) {
+			continue
+		}
+		// remember the data even if it is invalid not to report the same error repeatedly.
+		r.data = data
+
+		if err := r.reload(ctx, data); err != nil {
+			logger.Error(err, "rejected the new configuration; keeping the last valid one")
+			continue
+		}
+		logger.Info("reloaded the configuration")
+	}
+}
+
+// NeedLeaderElection implements manager.LeaderElectionRunnable interface.
+// Non-leaders also reload the configuration to be ready to become the leader.
+func (r *configReloader) NeedLeaderElection() bool {
+	return false
+}
+
+func (r *configReloader) reload(ctx context.Context, data []byte) error {
+	cfg := &config.Config{}
+	if err := cfg.Load(data); err != nil {
+		return fmt.Errorf("unable to load the configuration file: %w", err)
+	}
+	return r.apply(ctx, cfg)
+}
+
+// apply validates `cfg` in the same way as at startup and replaces the running configurations with it.
+// If it fails, the running configurations are kept.
+func (a *configApplier) apply(ctx context.Context, cfg *config.Config) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.applyLocked(ctx, cfg)
+}
+
+// refresh resolves the watches of the running configurations again, and
+// applies them if the resources to watch are changed.
+func (a *configApplier) refresh(ctx context.Context) (bool, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if a.cfg == nil || !hasLateBoundWatches(a.cfg) {
+		return false, nil
+	}
+	resolved, err := resolveWatches(ctx, a.dc, a.cfg)
+	if err != nil {
+		return false, err
+	}
+	if slices.Equal(watchedGVKs(resolved), a.resolved) {
+		return false, nil
+	}
+	return true, a.applyLocked(ctx, a.cfg)
+}
+
+func (a *configApplier) applyLocked(ctx context.Context, cfg *config.Config) error {
+	resolved, err := prepareConfig(ctx, a.mgr, a.dc, cfg)
+	if err != nil {
+		return err
+	}
+
+	watched, cloner := watchedResources(resolved)
+
+	// Controllers for new resources should be ready before the namespace reconciler
+	// lists them, and controllers for removed resources should be stopped after it.
+	if err := a.watches.Add(ctx, watched, cloner); err != nil {
+		return err
+	}
+	a.nsReconciler.UpdateConfig(resolved, cloner, watched)
+	a.snKeys.Update(resolved)
+	a.snPolicies.Update(resolved)
+	a.graftRules.Update(resolved)
+	a.watchedKinds.Update(resolved)
+	a.cfg = cfg
+	a.resolved = watchedGVKs(resolved)
+
+	// The new configuration is already in effect here, so failures are only reported.
+	if err := a.watches.Prune(ctx, watched); err != nil {
+		logger := log.FromContext(ctx)
+		logger.Error(err, "failed to stop watching removed resources")
+	}
+	return nil
+}
@@ -0,0 +1,110 @@
+package sub
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/cybozu-go/accurate/pkg/config"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/discovery"
+	"k8s.io/client-go/restmapper"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+)
+
+// prepareConfig validates `cfg` and returns a copy of it whose watches are resolved through discovery.
+func prepareConfig(ctx context.Context, mgr ctrl.Manager, dc discovery.DiscoveryInterface, cfg *config.Config) (*config.Config, error) {
+	if err := cfg.Validate(mgr.GetRESTMapper()); err != nil {
+		return nil, fmt.Errorf("invalid configurations: %w", err)
+	}
+	resolved, err := resolveWatches(ctx, dc, cfg)
+	if err != nil {
+		return nil, err
+	}
+	if err := resolved.ValidateRBAC(ctx, mgr.GetClient(), mgr.GetRESTMapper()); err != nil {
+		return nil, fmt.Errorf("when validating RBAC to support configuration: %w", err)
+	}
+	return resolved, nil
+}
+
+// resolveWatches returns a copy of `cfg` with the watches resolved by config.ResolveWatches.
+func resolveWatches(ctx context.Context, dc discovery.DiscoveryInterface, cfg *config.Config) (*config.Config, error) {
+	resolved := *cfg
+	if !hasLateBoundWatches(cfg) {
+		return &resolved, nil
+	}
+
+	groups, err := restmapper.GetAPIGroupResources(dc)
+	if err != nil {
+		if !discovery.IsGroupDiscoveryFailedError(err) {
+			return nil, fmt.Errorf("failed to discover API resources: %w", err)
+		}
+		// Unavailable aggregated APIs should not prevent resolving the other groups.
+		logger := log.FromContext(ctx)
+		logger.Error(err, "failed to discover some API groups")
+	}
+	watches, err := cfg.ResolveWatches(groups)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve watches: %w", err)
+	}
+	resolved.Watches = watches
+	return &resolved, nil
+}
+
+// hasLateBoundWatches returns true if the watches of `cfg` depend on the resources installed in the cluster.
+func hasLateBoundWatches(cfg *config.Config) bool {
+	return slices.ContainsFunc(cfg.Watches, func(w config.Watch) bool {
+		return w.IsWildcard() || w.Optional
+	})
+}
+
+func watchedGVKs(cfg *config.Config) []schema.GroupVersionKind {
+	gvks := make([]schema.GroupVersionKind, len(cfg.Watches))
+	for i, w := range cfg.Watches {
+		gvks[i] = schema.GroupVersionKind(w.GroupVersionKind)
+	}
+	return gvks
+}
+
+// watchResolver periodically resolves wildcard and optional watches again so that
+// resources installed or removed after startup are watched or unwatched.
+type watchResolver struct {
+	*configApplier
+	interval time.Duration
+}
+
+var _ manager.LeaderElectionRunnable = &watchResolver{}
+
+// Start implements manager.Runnable interface.
+func (r *watchResolver) Start(ctx context.Context) error {
+	logger := ctrl.Log.WithName("watch-resolver")
+	ctx = ctrl.LoggerInto(ctx, logger)
+
+	ticker := time.NewTicker(r.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+
+		changed, err := r.refresh(ctx)
+		if err != nil {
+			logger.Error(err, "failed to update the watched resources")
+			continue
+		}
+		if changed {
+			logger.Info("updated the watched resources")
+		}
+	}
+}
+
+// NeedLeaderElection implements manager.LeaderElectionRunnable interface.
+// Non-leaders also resolve the watches to be ready to become the leader.
+func (r *watchResolver) NeedLeaderElection() bool {
+	return false
+}
@@ -7,6 +7,7 @@ import (
 	"net"
 	"os"
 	"strconv"
+	"time"
 
 	"github.com/cybozu-go/accurate"
 	"github.com/cybozu-go/accurate/pkg/config"
@@ -17,10 +18,14 @@ import (
 
 const (
 	defaultConfigPath = "/etc/accurate/config.yaml"
+
+	configSourceFile     = "file"
+	configSourceResource = "resource"
 )
 
 var options struct {
 	configFile       string
+	configSource     string
 	metricsAddr      string
 	probeAddr        string
 	leaderElectionID string
@@ -29,7 +34,12 @@ var options struct {
 	qps              int
 	zapOpts          zap.Options
 
+	configReloadInterval time.Duration
+	watchResolveInterval time.Duration
+
 	webhookAllowCascadingDeletion bool
+	webhookRequireRootNamespace   bool
+	webhookProtectCopies          bool
 }
 
 var rootCmd = &cobra.Command{
@@ -40,6 +50,9 @@ var rootCmd = &cobra.Command{
 
 	RunE: func(cmd *cobra.Command, args []string) error {
 		cmd.SilenceUsage = true
+		if options.configSource != configSourceFile && options.configSource != configSourceResource {
+			return fmt.Errorf("invalid config source: %s", options.configSource)
+		}
 		h, p, err := net.SplitHostPort(options.webhookAddr)
 		if err != nil {
 			return fmt.Errorf("invalid webhook address: %s, %w", options.webhookAddr, err)
@@ -68,6 +81,9 @@ func Execute() {
 func init() {
 	fs := rootCmd.Flags()
 	fs.StringVar(&options.configFile, "config-file", defaultConfigPath, "Configuration file path")
+	fs.StringVar(&options.configSource, "config-source", configSourceFile, `Where to read configurations from: "file" or "resource" (the AccurateConfig named default)`)
+	fs.DurationVar(&options.configReloadInterval, "config-reload-interval", 10*time.Second, "Interval to check the configuration file for changes. 0 disables reloading.")
+	fs.DurationVar(&options.watchResolveInterval, "watch-resolve-interval", time.Minute, "Interval to resolve wildcard and optional watches again to follow installed resources. 0 disables it.")
 	fs.StringVar(&options.metricsAddr, "metrics-addr", ":8080", "The address the metric endpoint binds to")
 	fs.StringVar(&options.probeAddr, "health-probe-addr", ":8081", "Listen address for health probes")
 	fs.StringVar(&options.leaderElectionID, "leader-election-id", "accurate", "ID for leader election by controller-runtime")
@@ -76,6 +92,8 @@ func init() {
 	fs.IntVar(&options.qps, "apiserver-qps-throttle", 0, "Maximum client-side QPS to the API server. Values greater than 0 enable throttling.")
 
 	fs.BoolVar(&options.webhookAllowCascadingDeletion, "webhook-allow-cascading-deletion", false, "Set to true to allow cascading deletion of namespaces (namespaces with children)")
+	fs.BoolVar(&options.webhookRequireRootNamespace, "webhook-require-root-namespace", false, "Set to true to allow only namespaces declared by RootNamespace to be root namespaces")
+	fs.BoolVar(&options.webhookProtectCopies, "webhook-protect-copies", false, "Set to true to serve the webhook that protects copies propagated in update mode")
 
 	config.DefaultMutableFeatureGate.AddFlag(fs)
 
@@ -1,6 +1,7 @@
 package sub
 
 import (
+	"context"
 	"fmt"
 	"os"
 
@@ -15,9 +16,11 @@ import (
 	"github.com/cybozu-go/accurate/hooks"
 	"github.com/cybozu-go/accurate/pkg/config"
 	"github.com/cybozu-go/accurate/pkg/indexing"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/discovery"
 	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
 	"k8s.io/klog/v2"
 	ctrl "sigs.k8s.io/controller-runtime"
@@ -49,13 +52,17 @@ func subMain(ns, addr string, port int) error {
 		return fmt.Errorf("unable to add Accurate v2 objects: %w", err)
 	}
 
-	cfgData, err := os.ReadFile(options.configFile)
-	if err != nil {
-		return fmt.Errorf("failed to read %s: %w", options.configFile, err)
-	}
+	var cfgData []byte
 	cfg := &config.Config{}
-	if err := cfg.Load(cfgData); err != nil {
-		return fmt.Errorf("unable to load the configuration file: %w", err)
+	if options.configSource == configSourceFile {
+		data, err := os.ReadFile(options.configFile)
+		if err != nil {
+			return fmt.Errorf("failed to read %s: %w", options.configFile, err)
+		}
+		if err := cfg.Load(data); err != nil {
+			return fmt.Errorf("unable to load the configuration file: %w", err)
+		}
+		cfgData = data
 	}
 
 	restCfg, err := ctrl.GetConfig()
@@ -91,37 +98,39 @@ func subMain(ns, addr string, port int) error {
 		return fmt.Errorf("unable to start manager: %w", err)
 	}
 
-	ctx := ctrl.SetupSignalHandler()
+	dc, err := discovery.NewDiscoveryClientForConfig(restCfg)
+	if err != nil {
+		return fmt.Errorf("unable to create discovery client: %w", err)
+	}
+
+	ctx := ctrl.LoggerInto(ctrl.SetupSignalHandler(), logger)
 
-	if err := cfg.Validate(mgr.GetRESTMapper()); err != nil {
-		return fmt.Errorf("invalid configurations: %w", err)
+	if options.configSource == configSourceResource {
+		cfg, err = loadConfigResource(ctx, mgr)
 	}
-	if err := cfg.ValidateRBAC(ctx, mgr.GetClient(), mgr.GetRESTMapper()); err != nil {
-		return fmt.Errorf("when validating RBAC to support configuration: %w", err)
+	var resolved *config.Config
+	if err == nil {
+		resolved, err = prepareConfig(ctx, mgr, dc, cfg)
 	}
-
-	watched := make([]*unstructured.Unstructured, len(cfg.Watches))
-	for i := range cfg.Watches {
-		gvk := &cfg.Watches[i]
-		watched[i] = &unstructured.Unstructured{}
-		watched[i].SetGroupVersionKind(schema.GroupVersionKind{
-			Group:   gvk.Group,
-			Version: gvk.Version,
-			Kind:    gvk.Kind,
-		})
+	if err != nil {
+		if options.configSource != configSourceResource {
+			return err
+		}
+		// The webhook for AccurateConfig is served by this process.
+		// Failing here would prevent users from fixing the resource.
+		logger.Error(err, "failed to load AccurateConfig; starting with empty configurations")
+		cfg = &config.Config{}
+		resolved = cfg
 	}
 
-	cloner := controllers.ResourceCloner{
-		LabelKeyExcludes:      cfg.PropagateLabelKeyExcludes,
-		AnnotationKeyExcludes: cfg.PropagateAnnotationKeyExcludes,
-	}
+	watched, cloner := watchedResources(resolved)
 	dec := admission.NewDecoder(scheme)
 
 	// Namespace reconciler & webhook
 	if err := indexing.SetupIndexForNamespace(ctx, mgr); err != nil {
 		return fmt.Errorf("failed to setup indexer for namespaces: %w", err)
 	}
-	if err := (&controllers.NamespaceReconciler{
+	nsReconciler := &controllers.NamespaceReconciler{
 		Client:                     mgr.GetClient(),
 		ResourceCloner:             cloner,
 		LabelKeys:                  cfg.LabelKeys,
@@ -129,33 +138,99 @@ func subMain(ns, addr string, port int) error {
 		SubNamespaceLabelKeys:      cfg.SubNamespaceLabelKeys,
 		SubNamespaceAnnotationKeys: cfg.SubNamespaceAnnotationKeys,
 		Watched:                    watched,
-	}).SetupWithManager(mgr); err != nil {
+	}
+	if err := nsReconciler.SetupWithManager(mgr); err != nil {
 		return fmt.Errorf("unable to create Namespace controller: %w", err)
 	}
-	hooks.SetupNamespaceWebhook(mgr, dec, options.webhookAllowCascadingDeletion)
+	graftRules := hooks.NewAutoGraftRules(cfg)
+	snPolicies := hooks.NewSubNamespacePolicies(cfg)
+	hooks.SetupNamespaceWebhook(mgr, dec, graftRules, snPolicies, options.webhookAllowCascadingDeletion, options.webhookRequireRootNamespace)
+	watchedKinds := hooks.NewWatchedKinds(resolved)
+	hooks.SetupNamespaceTemplateWebhook(mgr, dec, watchedKinds)
+
+	// Watches is also used by the SubNamespace reconciler to count propagated resources.
+	watches, err := controllers.NewWatches(mgr)
+	if err != nil {
+		return fmt.Errorf("unable to create resource controllers: %w", err)
+	}
 
 	// SubNamespace reconciler & webhook
 	if err := indexing.SetupIndexForSubNamespace(ctx, mgr); err != nil {
 		return fmt.Errorf("failed to setup indexer for subnamespaces: %w", err)
 	}
 	if err = (&controllers.SubNamespaceReconciler{
-		Client: mgr.GetClient(),
+		Client:  mgr.GetClient(),
+		Watches: watches,
 	}).SetupWithManager(mgr); err != nil {
 		return fmt.Errorf("unable to create SubNamespace controller: %w", err)
 	}
-	if err = hooks.SetupSubNamespaceWebhook(mgr, dec, cfg.NamingPolicyRegexps, options.webhookAllowCascadingDeletion); err != nil {
+	snKeys := hooks.NewSubNamespaceKeys(cfg)
+	if err = hooks.SetupSubNamespaceWebhook(mgr, dec, snPolicies, snKeys, options.webhookAllowCascadingDeletion); err != nil {
 		return fmt.Errorf("unable to create SubNamespace webhook: %w", err)
 	}
 
-	// Resource propagation controller
-	for _, res := range watched {
-		if err := indexing.SetupIndexForResource(ctx, mgr, res); err != nil {
-			return fmt.Errorf("failed to setup indexer for %s: %w", res.GroupVersionKind().String(), err)
+	// RootNamespace reconciler & webhook
+	if err = (&controllers.RootNamespaceReconciler{
+		Client: mgr.GetClient(),
+	}).SetupWithManager(mgr); err != nil {
+		return fmt.Errorf("unable to create RootNamespace controller: %w", err)
+	}
+	hooks.SetupRootNamespaceWebhook(mgr, dec)
+
+	// Resource propagation controllers
+	if err := watches.Add(ctx, watched, cloner); err != nil {
+		return err
+	}
+	if err := controllers.SetupMetrics(mgr, watches); err != nil {
+		return fmt.Errorf("unable to set up metrics: %w", err)
+	}
+	if options.webhookProtectCopies {
+		if err := hooks.SetupPropagatedCopyWebhook(ctx, mgr, dec); err != nil {
+			return fmt.Errorf("unable to create webhook for propagated copies: %w", err)
+		}
+	}
+
+	// AccurateConfig webhook & configuration sources
+	hooks.SetupAccurateConfigWebhook(mgr, dec)
+	applier := &configApplier{
+		mgr:          mgr,
+		nsReconciler: nsReconciler,
+		snKeys:       snKeys,
+		snPolicies:   snPolicies,
+		graftRules:   graftRules,
+		watchedKinds: watchedKinds,
+		watches:      watches,
+		dc:           dc,
+		cfg:          cfg,
+		resolved:     watchedGVKs(resolved),
+	}
+	switch {
+	case options.configSource == configSourceResource:
+		if err := (&controllers.AccurateConfigReconciler{
+			Client:          mgr.GetClient(),
+			Apply:           applier.apply,
+			Watches:         watches,
+			RefreshInterval: options.watchResolveInterval,
+		}).SetupWithManager(mgr); err != nil {
+			return fmt.Errorf("unable to create AccurateConfig controller: %w", err)
 		}
-		if err := controllers.NewPropagateController(res, cloner).SetupWithManager(mgr); err != nil {
-			return fmt.Errorf("unable to create %s controller: %w", res.GroupVersionKind().String(), err)
+	case options.configReloadInterval > 0:
+		if err := mgr.Add(&configReloader{
+			configApplier: applier,
+			path:          options.configFile,
+			interval:      options.configReloadInterval,
+			data:          cfgData,
+		}); err != nil {
+			return fmt.Errorf("unable to set up config reloader: %w", err)
+		}
+	}
+	if options.watchResolveInterval > 0 {
+		if err := mgr.Add(&watchResolver{
+			configApplier: applier,
+			interval:      options.watchResolveInterval,
+		}); err != nil {
+			return fmt.Errorf("unable to set up watch resolver: %w", err)
 		}
-		logger.Info("watching", "gvk", res.GroupVersionKind().String())
 	}
 
 	if err := mgr.AddHealthzCheck("healthz", healthz.Ping); err != nil {
@@ -171,3 +246,46 @@ func subMain(ns, addr string, port int) error {
 	}
 	return nil
 }
+
+// watchedResources returns the resources to be watched and the ResourceCloner for `cfg`.
+func watchedResources(cfg *config.Config) ([]*unstructured.Unstructured, controllers.ResourceCloner) {
+	watched := make([]*unstructured.Unstructured, len(cfg.Watches))
+	watches := make(map[schema.GroupVersionKind]config.Watch)
+	for i := range cfg.Watches {
+		gvk := schema.GroupVersionKind{
+			Group:   cfg.Watches[i].Group,
+			Version: cfg.Watches[i].Version,
+			Kind:    cfg.Watches[i].Kind,
+		}
+		watched[i] = &unstructured.Unstructured{}
+		watched[i].SetGroupVersionKind(gvk)
+		watches[gvk] = cfg.Watches[i]
+	}
+
+	cloner := controllers.ResourceCloner{
+		LabelKeyExcludes:       cfg.PropagateLabelKeyExcludes,
+		AnnotationKeyExcludes:  cfg.PropagateAnnotationKeyExcludes,
+		TemplateLabelKeys:      cfg.LabelKeys,
+		TemplateAnnotationKeys: cfg.AnnotationKeys,
+		Watches:                watches,
+	}
+	return watched, cloner
+}
+
+// loadConfigResource loads the configurations from the AccurateConfig named "default".
+// If it does not exist, empty configurations are returned.
+func loadConfigResource(ctx context.Context, mgr ctrl.Manager) (*config.Config, error) {
+	cfg := &config.Config{}
+	ac := &accuratev2.AccurateConfig{}
+	// The cache is not started yet.
+	if err := mgr.GetAPIReader().Get(ctx, client.ObjectKey{Name: accuratev2.AccurateConfigName}, ac); err != nil {
+		if apierrors.IsNotFound(err) {
+			return cfg, nil
+		}
+		return nil, fmt.Errorf("failed to get AccurateConfig %s: %w", accuratev2.AccurateConfigName, err)
+	}
+	if err := cfg.LoadResource(ac); err != nil {
+		return nil, fmt.Errorf("unable to load AccurateConfig %s: %w", accuratev2.AccurateConfigName, err)
+	}
+	return cfg, nil
+}
@@ -0,0 +1,269 @@
+package sub
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/cybozu-go/accurate/pkg/config"
+	"github.com/spf13/cobra"
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+	utilyaml "k8s.io/apimachinery/pkg/util/yaml"
+	"k8s.io/client-go/restmapper"
+)
+
+const (
+	outputJSON = "json"
+	outputText = "text"
+)
+
+var errInvalidConfig = errors.New("invalid configurations")
+
+type validateConfigOpts struct {
+	configFile string
+	discovery  []string
+	crds       []string
+	output     string
+}
+
+// validationReport is the result of validate-config.
+type validationReport struct {
+	Valid  bool     `json:"valid"`
+	Errors []string `json:"errors"`
+}
+
+func newValidateConfigCmd() *cobra.Command {
+	opts := &validateConfigOpts{}
+	cmd := &cobra.Command{
+		Use:   "validate-config",
+		Short: "Validate a configuration file without accessing Kubernetes",
+		Long: `Validate a configuration file without accessing Kubernetes.
+
+The resources in watches are looked up in API discovery documents and
+CustomResourceDefinition manifests on disk instead of the API server.
+A discovery document is an APIResourceList such as the output of
+"kubectl get --raw /apis/apps/v1", and a directory is searched recursively,
+so the discovery cache of kubectl (~/.kube/cache/discovery/<server>) can be used as is.
+
+The result is written to stdout, and the command exits with a non-zero status
+if the configurations are invalid.  RBAC is not checked.`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cmd.SilenceUsage = true
+			if opts.output != outputJSON && opts.output != outputText {
+				return fmt.Errorf("invalid output format: %s", opts.output)
+			}
+			return opts.Run(cmd.OutOrStdout())
+		},
+	}
+
+	fs := cmd.Flags()
+	fs.StringVar(&opts.configFile, "config-file", defaultConfigPath, "Configuration file path")
+	fs.StringSliceVar(&opts.discovery, "discovery", nil, "Files or directories of API discovery documents")
+	fs.StringSliceVar(&opts.crds, "crd", nil, "Files or directories of CustomResourceDefinition manifests")
+	fs.StringVarP(&opts.output, "output", "o", outputJSON, `Output format: "json" or "text"`)
+	return cmd
+}
+
+// Run validates the configurations and writes the report to `w`.
+// Errors in the inputs are reported in the same way as errors in the configurations.
+func (o *validateConfigOpts) Run(w io.Writer) error {
+	report := validationReport{Errors: []string{}}
+	for _, err := range o.validate() {
+		report.Errors = append(report.Errors, err.Error())
+	}
+	report.Valid = len(report.Errors) == 0
+	if err := o.write(w, report); err != nil {
+		return err
+	}
+	if !report.Valid {
+		return errInvalidConfig
+	}
+	return nil
+}
+
+func (o *validateConfigOpts) validate() []error {
+	data, err := os.ReadFile(o.configFile)
+	if err != nil {
+		return []error{fmt.Errorf("failed to read %s: %w", o.configFile, err)}
+	}
+	groups, err := o.apiGroupResources()
+	if err != nil {
+		return []error{err}
+	}
+
+	cfg := &config.Config{}
+	if err := cfg.Load(data); err != nil {
+		return []error{fmt.Errorf("unable to load the configuration file: %w", err)}
+	}
+	errs := flatten(cfg.Validate(restmapper.NewDiscoveryRESTMapper(groups)))
+	_, err = cfg.ResolveWatches(groups)
+	return append(errs, flatten(err)...)
+}
+
+func flatten(err error) []error {
+	if err == nil {
+		return nil
+	}
+	var agg utilerrors.Aggregate
+	if errors.As(err, &agg) {
+		return agg.Errors()
+	}
+	return []error{err}
+}
+
+func (o *validateConfigOpts) write(w io.Writer, report validationReport) error {
+	if o.output == outputJSON {
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(report)
+	}
+
+	if report.Valid {
+		_, err := fmt.Fprintf(w, "%s is valid\n", o.configFile)
+		return err
+	}
+	for _, msg := range report.Errors {
+		if _, err := fmt.Fprintf(w, "%s: %s\n", o.configFile, msg); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// apiGroupResources builds the API resources from the discovery documents and the CRD manifests.
+func (o *validateConfigOpts) apiGroupResources() ([]*restmapper.APIGroupResources, error) {
+	groups := make(map[string]*restmapper.APIGroupResources)
+	var names []string
+	add := func(gv schema.GroupVersion, resources []metav1.APIResource) {
+		g, ok := groups[gv.Group]
+		if !ok {
+			g = &restmapper.APIGroupResources{
+				Group:              metav1.APIGroup{Name: gv.Group},
+				VersionedResources: make(map[string][]metav1.APIResource),
+			}
+			groups[gv.Group] = g
+			names = append(names, gv.Group)
+		}
+		if _, ok := g.VersionedResources[gv.Version]; !ok {
+			v := metav1.GroupVersionForDiscovery{GroupVersion: gv.String(), Version: gv.Version}
+			g.Group.Versions = append(g.Group.Versions, v)
+			if g.Group.PreferredVersion.Version == "" {
+				g.Group.PreferredVersion = v
+			}
+		}
+		g.VersionedResources[gv.Version] = append(g.VersionedResources[gv.Version], resources...)
+	}
+
+	err := decodeFiles(o.discovery, func(path string, raw json.RawMessage) error {
+		l := &metav1.APIResourceList{}
+		if err := json.Unmarshal(raw, l); err != nil {
+			return fmt.Errorf("failed to decode %s: %w", path, err)
+		}
+		if l.Kind != "APIResourceList" {
+			return nil
+		}
+		gv, err := schema.ParseGroupVersion(l.GroupVersion)
+		if err != nil {
+			return fmt.Errorf("invalid groupVersion in %s: %w", path, err)
+		}
+		add(gv, l.APIResources)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	err = decodeFiles(o.crds, func(path string, raw json.RawMessage) error {
+		crd := &apiextensionsv1.CustomResourceDefinition{}
+		if err := json.Unmarshal(raw, crd); err != nil {
+			return fmt.Errorf("failed to decode %s: %w", path, err)
+		}
+		if crd.Kind != "CustomResourceDefinition" {
+			return nil
+		}
+		for _, v := range crd.Spec.Versions {
+			if !v.Served {
+				continue
+			}
+			add(schema.GroupVersion{Group: crd.Spec.Group, Version: v.Name}, []metav1.APIResource{{
+				Name:         crd.Spec.Names.Plural,
+				SingularName: crd.Spec.Names.Singular,
+				Namespaced:   crd.Spec.Scope == apiextensionsv1.NamespaceScoped,
+				Kind:         crd.Spec.Names.Kind,
+				Verbs:        metav1.Verbs{"delete", "deletecollection", "get", "list", "patch", "create", "update", "watch"},
+			}})
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	resources := make([]*restmapper.APIGroupResources, len(names))
+	for i, name := range names {
+		resources[i] = groups[name]
+	}
+	return resources, nil
+}
+
+// decodeFiles calls `fn` for each JSON or YAML document in `paths`.
+// Directories are searched recursively for files with .json, .yaml, or .yml extensions.
+func decodeFiles(paths []string, fn func(path string, raw json.RawMessage) error) error {
+	for _, root := range paths {
+		err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			if d.IsDir() {
+				return nil
+			}
+			if path != root {
+				switch strings.ToLower(filepath.Ext(path)) {
+				case ".json", ".yaml", ".yml":
+				default:
+					return nil
+				}
+			}
+
+			f, err := os.Open(path)
+			if err != nil {
+				return err
+			}
+			defer f.Close()
+
+			dec := utilyaml.NewYAMLOrJSONDecoder(f, 4096)
+			for {
+				var raw json.RawMessage
+				if err := dec.Decode(&raw); err != nil {
+					if errors.Is(err, io.EOF) {
+						return nil
+					}
+					return fmt.Errorf("failed to decode %s: %w", path, err)
+				}
+				if len(raw) == 0 || string(raw) == "null" {
+					continue
+				}
+				if err := fn(path, raw); err != nil {
+					return err
+				}
+			}
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func init() {
+	rootCmd.AddCommand(newValidateConfigCmd())
+}
@@ -0,0 +1,118 @@
+package sub
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestValidateConfig(t *testing.T) {
+	badDiscovery := filepath.Join(t.TempDir(), "bad.json")
+	if err := os.WriteFile(badDiscovery, []byte(`{"kind":`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name       string
+		configFile string
+		discovery  string
+		wantErrors []string
+	}{
+		{
+			name:       "valid",
+			configFile: "testdata/valid.yaml",
+			discovery:  "testdata/discovery",
+		},
+		{
+			name:       "invalid",
+			configFile: "testdata/invalid.yaml",
+			discovery:  "testdata/discovery",
+			wantErrors: []string{
+				"rbac.authorization.k8s.io/v1, Kind=ClusterRole is not namespace-scoped",
+				"invalid gvk example.com/v1, Kind=Gadget",
+				"invalid subNamespaceLimits.maxChildren: -1",
+			},
+		},
+		{
+			name:       "malformed",
+			configFile: "testdata/malformed.yaml",
+			discovery:  "testdata/discovery",
+			wantErrors: []string{"unable to load the configuration file"},
+		},
+		{
+			name:       "missing config file",
+			configFile: "testdata/missing.yaml",
+			discovery:  "testdata/discovery",
+			wantErrors: []string{"failed to read testdata/missing.yaml"},
+		},
+		{
+			name:       "malformed discovery document",
+			configFile: "testdata/valid.yaml",
+			discovery:  badDiscovery,
+			wantErrors: []string{"failed to decode " + badDiscovery},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			out := &bytes.Buffer{}
+			cmd := newValidateConfigCmd()
+			cmd.SetArgs([]string{"--config-file", tt.configFile, "--discovery", tt.discovery, "--crd", "testdata/crds"})
+			cmd.SetOut(out)
+			cmd.SetErr(io.Discard)
+			err := cmd.Execute()
+
+			report := validationReport{}
+			if err := json.Unmarshal(out.Bytes(), &report); err != nil {
+				t.Fatalf("failed to decode the report %q: %v", out.String(), err)
+			}
+			if len(tt.wantErrors) == 0 {
+				if err != nil {
+					t.Errorf("Execute() error = %v, want nil", err)
+				}
+				if !report.Valid || len(report.Errors) != 0 {
+					t.Errorf("report = %+v, want valid", report)
+				}
+				return
+			}
+
+			// a non-nil error makes the command exit with a non-zero status.
+			if !errors.Is(err, errInvalidConfig) {
+				t.Errorf("Execute() error = %v, want %v", err, errInvalidConfig)
+			}
+			if report.Valid {
+				t.Errorf("report = %+v, want invalid", report)
+			}
+			for _, want := range tt.wantErrors {
+				found := false
+				for _, msg := range report.Errors {
+					if strings.Contains(msg, want) {
+						found = true
+						break
+					}
+				}
+				if !found {
+					t.Errorf("report.Errors = %q, want an error containing %q", report.Errors, want)
+				}
+			}
+		})
+	}
+}
+
+func TestValidateConfigText(t *testing.T) {
+	out := &bytes.Buffer{}
+	cmd := newValidateConfigCmd()
+	cmd.SetArgs([]string{"--config-file", "testdata/invalid.yaml", "--discovery", "testdata/discovery", "--crd", "testdata/crds", "-o", "text"})
+	cmd.SetOut(out)
+	cmd.SetErr(io.Discard)
+	if err := cmd.Execute(); !errors.Is(err, errInvalidConfig) {
+		t.Errorf("Execute() error = %v, want %v", err, errInvalidConfig)
+	}
+	if want := "testdata/invalid.yaml: invalid subNamespaceLimits.maxChildren: -1\n"; !strings.Contains(out.String(), want) {
+		t.Errorf("output = %q, want %q", out.String(), want)
+	}
+}
@@ -7,22 +7,28 @@ import (
 	"strings"
 	"text/tabwriter"
 
+	accuratev2 "github.com/cybozu-go/accurate/api/accurate/v2"
 	"github.com/cybozu-go/accurate/pkg/config"
 	"github.com/cybozu-go/accurate/pkg/constants"
 	"github.com/spf13/cobra"
 	appsv1 "k8s.io/api/apps/v1"
 	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/runtime/schema"
 	"k8s.io/cli-runtime/pkg/genericclioptions"
 	"k8s.io/cli-runtime/pkg/genericiooptions"
+	"k8s.io/client-go/discovery"
+	"k8s.io/client-go/restmapper"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 )
 
 type nsDescribeOpts struct {
 	streams    genericiooptions.IOStreams
 	client     client.Client
+	discovery  discovery.DiscoveryInterface
 	name       string
 	accurateNS string
 }
@@ -53,6 +59,11 @@ func (o *nsDescribeOpts) Fill(streams genericiooptions.IOStreams, config *generi
 		return err
 	}
 	o.client = cl
+	dc, err := config.ToDiscoveryClient()
+	if err != nil {
+		return err
+	}
+	o.discovery = dc
 	o.name = args[0]
 	return nil
 }
@@ -79,6 +90,21 @@ func (o *nsDescribeOpts) getChildren(ctx context.Context, k string) ([]string, e
 }
 
 func (o *nsDescribeOpts) getConfig(ctx context.Context) (*config.Config, error) {
+	ac := &accuratev2.AccurateConfig{}
+	err := o.client.Get(ctx, client.ObjectKey{Name: accuratev2.AccurateConfigName}, ac)
+	switch {
+	case err == nil:
+		cfg := &config.Config{}
+		if err := cfg.LoadResource(ac); err != nil {
+			return nil, fmt.Errorf("failed to load AccurateConfig %s: %w", accuratev2.AccurateConfigName, err)
+		}
+		return cfg, nil
+	case apierrors.IsNotFound(err), meta.IsNoMatchError(err):
+		// fall back to the configuration file of the controller
+	default:
+		return nil, fmt.Errorf("failed to get AccurateConfig %s: %w", accuratev2.AccurateConfigName, err)
+	}
+
 	deployment := &appsv1.Deployment{}
 	if err := o.client.Get(ctx, client.ObjectKey{Namespace: o.accurateNS, Name: "accurate-controller-manager"}, deployment); err != nil {
 		return nil, fmt.Errorf("failed to get deployment %s/%s: %w", o.accurateNS, "accurate-controller-manager", err)
@@ -108,6 +134,24 @@ func (o *nsDescribeOpts) getConfig(ctx context.Context) (*config.Config, error)
 	return cfg, nil
 }
 
+// resolveWatches returns the watches of `cfg` for the resources in the cluster.
+// Like accurate-controller, wildcard watches are expanded and watches for missing resources are skipped.
+func (o *nsDescribeOpts) resolveWatches(cfg *config.Config) ([]config.Watch, error) {
+	groups, err := restmapper.GetAPIGroupResources(o.discovery)
+	if err != nil {
+		if !discovery.IsGroupDiscoveryFailedError(err) {
+			return nil, fmt.Errorf("failed to discover API resources: %w", err)
+		}
+		fmt.Fprintf(o.streams.ErrOut, "failed to discover some API groups: %v\n", err)
+	}
+	watches, err := cfg.ResolveWatches(groups)
+	if err != nil {
+		// describe the resources of the other watches
+		fmt.Fprintf(o.streams.ErrOut, "failed to resolve watches: %v\n", err)
+	}
+	return watches, nil
+}
+
 func (o *nsDescribeOpts) Run(ctx context.Context) error {
 	cfg, err := o.getConfig(ctx)
 	if err != nil {
@@ -148,7 +192,11 @@ func (o *nsDescribeOpts) Run(ctx context.Context) error {
 		o.printf("Template: %s\n", tmpl)
 	}
 
-	if len(cfg.Watches) == 0 {
+	watches, err := o.resolveWatches(cfg)
+	if err != nil {
+		return err
+	}
+	if len(watches) == 0 {
 		return nil
 	}
 
@@ -156,8 +204,8 @@ func (o *nsDescribeOpts) Run(ctx context.Context) error {
 	w := tabwriter.NewWriter(o.streams.Out, 2, 8, 1, ' ', 0)
 	fmt.Fprintln(w, "Kind\tName\tFrom\tMode")
 	fmt.Fprintln(w, "--------\t--------\t--------\t--------")
-	for _, gvk := range cfg.Watches {
-		o.printResource(ctx, w, gvk)
+	for _, watch := range watches {
+		o.printResource(ctx, w, watch.GroupVersionKind)
 	}
 	return w.Flush()
 }
@@ -73,6 +73,7 @@ func (o *subGraftOpts) Run(ctx context.Context) error {
 	}
 	delete(ns.Labels, constants.LabelType)
 	delete(ns.Labels, constants.LabelTemplate)
+	delete(ns.Annotations, constants.AnnTemplates)
 	ns.Labels[constants.LabelParent] = o.parent
 	if err := o.client.Update(ctx, ns); err != nil {
 		return fmt.Errorf("failed to update namespace %s: %w", o.name, err)
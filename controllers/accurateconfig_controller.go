@@ -0,0 +1,152 @@
+package controllers
+
+import (
+	"context"
+	"time"
+
+	accuratev2 "github.com/cybozu-go/accurate/api/accurate/v2"
+	"github.com/cybozu-go/accurate/pkg/config"
+	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/utils/ptr"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/source"
+)
+
+// AccurateConfigReconciler brings the AccurateConfig named "default" into effect
+type AccurateConfigReconciler struct {
+	client.Client
+
+	// Apply validates `cfg` and replaces the running configurations with it.
+	Apply func(ctx context.Context, cfg *config.Config) error
+
+	// Watches runs the controllers for the watched resources reported in the status.
+	Watches *Watches
+
+	// RefreshInterval is the interval to update the watched resources in the status,
+	// which change when resources for wildcard or optional watches are installed.
+	// 0 disables it.
+	RefreshInterval time.Duration
+
+	// appliedGeneration is the generation applied by this process.
+	appliedGeneration int64
+	// applyErr is the error of the last attempt to apply the configurations.
+	applyErr error
+	// elected is closed when this process becomes the leader.
+	elected <-chan struct{}
+}
+
+//+kubebuilder:rbac:groups=accurate.cybozu.com,resources=accurateconfigs,verbs=get;list;watch
+//+kubebuilder:rbac:groups=accurate.cybozu.com,resources=accurateconfigs/status,verbs=get;update;patch
+
+// Reconcile implements reconcile.Reconciler interface.
+func (r *AccurateConfigReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	logger := log.FromContext(ctx)
+
+	ac := &accuratev2.AccurateConfig{}
+	if err := r.Get(ctx, req.NamespacedName, ac); err != nil {
+		return ctrl.Result{}, client.IgnoreNotFound(err)
+	}
+
+	if r.appliedGeneration != ac.Generation {
+		r.applyErr = r.apply(ctx, ac)
+		if r.applyErr != nil {
+			// The last valid configurations are kept.
+			logger.Error(r.applyErr, "rejected the configurations; keeping the last valid ones")
+		} else {
+			logger.Info("applied the configurations")
+		}
+	}
+
+	// Retry invalid configurations too, as they may become valid when RBAC or resources are installed.
+	result := ctrl.Result{RequeueAfter: r.RefreshInterval}
+
+	// Only the leader reports the status so that replicas do not overwrite each other.
+	select {
+	case <-r.elected:
+	default:
+		return result, nil
+	}
+
+	orig := ac.DeepCopy()
+	if r.applyErr != nil {
+		meta.SetStatusCondition(&ac.Status.Conditions, metav1.Condition{
+			Type:               accuratev2.AccurateConfigReady,
+			Status:             metav1.ConditionFalse,
+			ObservedGeneration: ac.Generation,
+			Reason:             accuratev2.AccurateConfigInvalid,
+			Message:            r.applyErr.Error(),
+		})
+	} else {
+		meta.SetStatusCondition(&ac.Status.Conditions, metav1.Condition{
+			Type:               accuratev2.AccurateConfigReady,
+			Status:             metav1.ConditionTrue,
+			ObservedGeneration: ac.Generation,
+			Reason:             accuratev2.AccurateConfigApplied,
+		})
+	}
+	ac.Status.ObservedGeneration = ac.Generation
+	ac.Status.Watches = nil
+	for _, res := range r.Watches.Resources() {
+		ac.Status.Watches = append(ac.Status.Watches, metav1.GroupVersionKind(res.GroupVersionKind()))
+	}
+	if equality.Semantic.DeepEqual(orig.Status, ac.Status) {
+		return result, nil
+	}
+	if err := r.Status().Patch(ctx, ac, client.MergeFrom(orig)); err != nil {
+		return ctrl.Result{}, err
+	}
+	return result, nil
+}
+
+func (r *AccurateConfigReconciler) apply(ctx context.Context, ac *accuratev2.AccurateConfig) error {
+	cfg := &config.Config{}
+	if err := cfg.LoadResource(ac); err != nil {
+		return err
+	}
+	if err := r.Apply(ctx, cfg); err != nil {
+		return err
+	}
+	r.appliedGeneration = ac.Generation
+	return nil
+}
+
+// SetupWithManager sets up the controller with the Manager.
+// Non-leaders also apply the configurations to be ready to become the leader,
+// but only the leader updates the status.
+func (r *AccurateConfigReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	isDefault := predicate.NewPredicateFuncs(func(o client.Object) bool {
+		return o.GetName() == accuratev2.AccurateConfigName
+	})
+
+	r.elected = mgr.Elected()
+	// reconcile again to report the status when this process becomes the leader.
+	elected := make(chan event.GenericEvent)
+	err := mgr.Add(manager.RunnableFunc(func(ctx context.Context) error {
+		ac := &accuratev2.AccurateConfig{}
+		ac.Name = accuratev2.AccurateConfigName
+		select {
+		case elected <- event.GenericEvent{Object: ac}:
+		case <-ctx.Done():
+		}
+		return nil
+	}))
+	if err != nil {
+		return err
+	}
+
+	return ctrl.NewControllerManagedBy(mgr).
+		For(&accuratev2.AccurateConfig{}, builder.WithPredicates(isDefault, predicate.GenerationChangedPredicate{})).
+		WatchesRawSource(source.Channel(elected, &handler.EnqueueRequestForObject{})).
+		WithOptions(controller.Options{NeedLeaderElection: ptr.To(false)}).
+		Complete(r)
+}
@@ -0,0 +1,88 @@
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/cybozu-go/accurate/pkg/config"
+	"github.com/cybozu-go/accurate/pkg/constants"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/events"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// errConflict is returned when a conflict is found under ConflictPolicyFail.
+var errConflict = errors.New("conflicting object found")
+
+// isConflict returns true if `existing` in a child namespace was not propagated by Accurate.
+func isConflict(existing *unstructured.Unstructured) bool {
+	return existing.GetAnnotations()[constants.AnnFrom] == ""
+}
+
+// conflictRecorded returns true if the conflict of `src` in namespace `ns` is recorded in the annotation of `src`.
+func conflictRecorded(src *unstructured.Unstructured, ns string) bool {
+	return slices.Contains(strings.Split(src.GetAnnotations()[constants.AnnConflicts], ","), ns)
+}
+
+// resolveConflict reports the conflict between `src` and `existing` as events unless it has been
+// `recorded`, and returns true if `existing` should be overwritten by a copy of `src`.
+// The event about `src` is recorded for `regarding`, which differs from `src` for virtual sources.
+func (rc *ResourceCloner) resolveConflict(recorder events.EventRecorder, src, existing *unstructured.Unstructured, regarding runtime.Object, recorded bool) (bool, error) {
+	if !recorded {
+		recorder.Eventf(regarding, existing, corev1.EventTypeWarning, reasonConflict, actionPropagate,
+			"%s/%s already exists and was not propagated by Accurate", existing.GetNamespace(), existing.GetName())
+		recorder.Eventf(existing, regarding, corev1.EventTypeWarning, reasonConflict, actionPropagate,
+			"conflicts with %s/%s propagated by Accurate", src.GetNamespace(), src.GetName())
+	}
+
+	switch rc.Watches[src.GroupVersionKind()].ConflictPolicy {
+	case config.ConflictPolicyOverwrite:
+		return true, nil
+	case config.ConflictPolicySkip:
+		return false, nil
+	case config.ConflictPolicyFail:
+		recordFailure(src, src.GetAnnotations()[constants.AnnPropagate], failureConflict)
+		return false, fmt.Errorf("%w: %s/%s", errConflict, existing.GetNamespace(), existing.GetName())
+	}
+	return followsSource(src.GetAnnotations()[constants.AnnPropagate]), nil
+}
+
+// setConflicts records the namespaces where `src` conflicts in its annotation.
+// If `add` is true, `namespaces` are added to the existing ones.
+func setConflicts(ctx context.Context, c client.Client, src *unstructured.Unstructured, namespaces []string, add bool) error {
+	current := src.GetAnnotations()[constants.AnnConflicts]
+	if add && current != "" {
+		namespaces = append(namespaces, strings.Split(current, ",")...)
+	}
+	slices.Sort(namespaces)
+	namespaces = slices.Compact(namespaces)
+
+	value := strings.Join(namespaces, ",")
+	if value == current {
+		return nil
+	}
+
+	var v any
+	if value != "" {
+		v = value
+	}
+	patch, err := json.Marshal(map[string]any{
+		"metadata": map[string]any{
+			"annotations": map[string]any{constants.AnnConflicts: v},
+		},
+	})
+	if err != nil {
+		return err
+	}
+	if err := c.Patch(ctx, src, client.RawPatch(types.MergePatchType, patch), fieldOwner); err != nil {
+		return fmt.Errorf("failed to record conflicts of %s/%s: %w", src.GetNamespace(), src.GetName(), err)
+	}
+	return nil
+}
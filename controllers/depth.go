@@ -0,0 +1,43 @@
+package controllers
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/cybozu-go/accurate/pkg/constants"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+// remainingDepth returns the number of levels `res` can still be propagated.
+// The second return value is false if the depth is not limited.
+func remainingDepth(res *unstructured.Unstructured) (int, bool, error) {
+	v, ok := res.GetAnnotations()[constants.AnnPropagateDepth]
+	if !ok {
+		return 0, false, nil
+	}
+	depth, err := strconv.Atoi(v)
+	if err != nil || depth < 0 {
+		return 0, true, fmt.Errorf("invalid %s annotation: %q", constants.AnnPropagateDepth, v)
+	}
+	return depth, true, nil
+}
+
+// canPropagate returns true if `res` can be propagated to child namespaces.
+// Resources with an invalid depth are not propagated.
+func canPropagate(res *unstructured.Unstructured) bool {
+	depth, limited, err := remainingDepth(res)
+	if err != nil {
+		return false
+	}
+	return !limited || depth > 0
+}
+
+// setRemainingDepth sets the remaining depth of a copy of `res` in `ann`.
+func setRemainingDepth(ann map[string]string, res *unstructured.Unstructured) {
+	depth, limited, err := remainingDepth(res)
+	if !limited || err != nil {
+		delete(ann, constants.AnnPropagateDepth)
+		return
+	}
+	ann[constants.AnnPropagateDepth] = strconv.Itoa(max(depth-1, 0))
+}
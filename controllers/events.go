@@ -0,0 +1,37 @@
+package controllers
+
+//+kubebuilder:rbac:groups=events.k8s.io,resources=events,verbs=create;patch
+
+// eventSource is the name of the component reported in Events.
+//
+// Events are recorded only when an object is actually changed, so that
+// repeated reconciliations of an up-to-date object do not emit Events.
+// Identical Events are also aggregated into a series by the recorder.
+const eventSource = "accurate-controller"
+
+// Reasons of Events
+const (
+	reasonCreated         = "Created"
+	reasonUpdated         = "Updated"
+	reasonDeleted         = "Deleted"
+	reasonRenderFailed    = "RenderFailed"
+	reasonInvalidDepth    = "InvalidDepth"
+	reasonInvalidSelector = "InvalidSelector"
+	reasonConflict        = "Conflict"
+	reasonModeNotAllowed  = "ModeNotAllowed"
+	reasonOrphaned        = "Orphaned"
+	reasonAdopted         = "Adopted"
+	reasonDeclared        = "Declared"
+)
+
+// Actions of Events
+const (
+	actionPropagate          = "Propagate"
+	actionDelete             = "Delete"
+	actionCreateNamespace    = "CreateNamespace"
+	actionDeleteNamespace    = "DeleteNamespace"
+	actionOrphanNamespace    = "OrphanNamespace"
+	actionAdoptNamespace     = "AdoptNamespace"
+	actionDeclareRoot        = "DeclareRoot"
+	actionCreateSubNamespace = "CreateSubNamespace"
+)
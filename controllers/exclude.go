@@ -0,0 +1,47 @@
+package controllers
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/cybozu-go/accurate/pkg/constants"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// excludeKey returns the key of `res` in the propagate-exclude annotation of namespaces,
+// e.g. `networkpolicies.networking.k8s.io/default-deny` or `secrets/foo`.
+func excludeKey(c client.Client, res *unstructured.Unstructured) (string, error) {
+	gvk := res.GroupVersionKind()
+	mapping, err := c.RESTMapper().RESTMapping(gvk.GroupKind(), gvk.Version)
+	if err != nil {
+		return "", fmt.Errorf("failed to get REST mapping of %s: %w", gvk.String(), err)
+	}
+
+	key := mapping.Resource.Resource
+	if gvk.Group != "" {
+		key += "." + gvk.Group
+	}
+	return key + "/" + res.GetName(), nil
+}
+
+// isExcluded returns true if `ns` opts out of inheriting `res`.
+// Descendants of `ns` also go without `res` because `ns` has no copy to propagate.
+func isExcluded(c client.Client, res *unstructured.Unstructured, ns *corev1.Namespace) (bool, error) {
+	ann, ok := ns.Annotations[constants.AnnPropagateExclude]
+	if !ok {
+		return false, nil
+	}
+
+	key, err := excludeKey(c, res)
+	if err != nil {
+		return false, err
+	}
+	for _, item := range strings.Split(ann, ",") {
+		if strings.TrimSpace(item) == key {
+			return true, nil
+		}
+	}
+	return false, nil
+}
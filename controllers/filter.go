@@ -0,0 +1,55 @@
+package controllers
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/cybozu-go/accurate/pkg/constants"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
+)
+
+// namespaceSelector returns the selector of namespaces that receive copies of `res`.
+func namespaceSelector(res *unstructured.Unstructured) (labels.Selector, error) {
+	v, ok := res.GetAnnotations()[constants.AnnPropagateNamespaceSelector]
+	if !ok {
+		return labels.Everything(), nil
+	}
+	sel, err := labels.Parse(v)
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s annotation: %w", constants.AnnPropagateNamespaceSelector, err)
+	}
+	return sel, nil
+}
+
+// validatePropagation returns an error if the annotations of `res` that limit the propagation are invalid.
+// Copies of such resources are neither created nor deleted until the annotations are fixed,
+// so that a typo in the annotations does not delete the existing copies.
+func validatePropagation(res *unstructured.Unstructured) error {
+	if _, _, err := remainingDepth(res); err != nil {
+		return err
+	}
+	_, err := namespaceSelector(res)
+	return err
+}
+
+// propagatesTo returns true if `res` in the parent namespace should be propagated to `ns`.
+// Resources with an invalid depth or selector are not propagated.
+func propagatesTo(res *unstructured.Unstructured, ns *corev1.Namespace) bool {
+	if !canPropagate(res) {
+		return false
+	}
+
+	for _, name := range strings.Split(res.GetAnnotations()[constants.AnnPropagateNamespaceExcludes], ",") {
+		if strings.TrimSpace(name) == ns.Name {
+			return false
+		}
+	}
+
+	sel, err := namespaceSelector(res)
+	if err != nil {
+		return false
+	}
+	return sel.Matches(labels.Set(ns.Labels))
+}
@@ -0,0 +1,145 @@
+package controllers
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/cybozu-go/accurate/pkg/constants"
+	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/structured-merge-diff/v6/fieldpath"
+	"sigs.k8s.io/structured-merge-diff/v6/typed"
+)
+
+// followsSource returns true if copies of a resource in `mode` follow the source,
+// i.e., they are updated along with the source and deleted when the source is deleted.
+func followsSource(mode string) bool {
+	return mode == constants.PropagateUpdate || mode == constants.PropagateMerge
+}
+
+// followModes are the propagation modes where copies follow the source.
+var followModes = []string{constants.PropagateUpdate, constants.PropagateMerge}
+
+// createCopy creates `clone` propagated in `mode`.
+// In merge mode, the copy is created with server-side apply so that
+// Accurate owns only the fields present in the source.
+func createCopy(ctx context.Context, c client.Writer, clone *unstructured.Unstructured, mode string) error {
+	var err error
+	if mode != constants.PropagateMerge {
+		err = c.Create(ctx, clone)
+	} else {
+		err = c.Apply(ctx, client.ApplyConfigurationFromUnstructured(clone), fieldOwner)
+	}
+	if err != nil {
+		recordFailure(clone, mode, failureClass(err))
+		return err
+	}
+	recordOperation(clone, mode, operationCreate)
+	return nil
+}
+
+// applyCopy updates `existing` with `clone` propagated in `mode`.
+// It returns true if `existing` was out of date.
+//
+// In update mode, Accurate takes over every field of the source.
+// In merge mode, fields owned by other managers are removed from `clone`
+// before applying, so forcing the ownership only restores Accurate's own
+// labels and annotations.
+func applyCopy(ctx context.Context, c client.Writer, clone, existing *unstructured.Unstructured, mode string) (bool, error) {
+	if mode == constants.PropagateMerge {
+		pruned, err := pruneForeignFields(clone, existing)
+		if err != nil {
+			return false, err
+		}
+		clone = pruned
+	}
+
+	if equality.Semantic.DeepDerivative(clone, existing) {
+		return false, nil
+	}
+
+	ac := client.ApplyConfigurationFromUnstructured(clone)
+	if err := c.Apply(ctx, ac, fieldOwner, client.ForceOwnership); err != nil {
+		recordFailure(clone, mode, failureClass(err))
+		return false, fmt.Errorf("failed to apply %s/%s: %w", clone.GetNamespace(), clone.GetName(), err)
+	}
+	recordOperation(clone, mode, operationApply)
+	return true, nil
+}
+
+// deleteCopy deletes a copy `obj` propagated by Accurate.
+func deleteCopy(ctx context.Context, c client.Writer, obj *unstructured.Unstructured) error {
+	mode := obj.GetAnnotations()[constants.AnnPropagate]
+	if err := c.Delete(ctx, obj); err != nil {
+		recordFailure(obj, mode, failureClass(err))
+		return err
+	}
+	recordOperation(obj, mode, operationDelete)
+	return nil
+}
+
+// pruneForeignFields removes fields from `clone` that are managed by field managers
+// other than Accurate in `existing`, so that applying `clone` never takes over them.
+//
+// Since the schema of `clone` is unknown, lists are treated as atomic; if another
+// manager owns any part of a list, the whole list is left to the manager.
+func pruneForeignFields(clone, existing *unstructured.Unstructured) (*unstructured.Unstructured, error) {
+	foreign := &fieldpath.Set{}
+	for _, mf := range existing.GetManagedFields() {
+		if mf.Manager == string(fieldOwner) || mf.Subresource != "" || mf.FieldsV1 == nil {
+			continue
+		}
+		s := &fieldpath.Set{}
+		if err := s.FromJSON(bytes.NewReader(mf.FieldsV1.Raw)); err != nil {
+			return nil, fmt.Errorf("failed to parse managed fields of %s: %w", mf.Manager, err)
+		}
+		foreign = foreign.Union(s)
+	}
+
+	removed := &fieldpath.Set{}
+	foreign.Iterate(func(p fieldpath.Path) {
+		for i, pe := range p {
+			if pe.FieldName == nil {
+				p = p[:i]
+				break
+			}
+		}
+		if len(p) == 0 || !isPrunable(p) {
+			return
+		}
+		removed.Insert(p.Copy())
+	})
+	if removed.Empty() {
+		return clone, nil
+	}
+
+	tv, err := typed.DeducedParseableType.FromUnstructured(clone.Object)
+	if err != nil {
+		return nil, fmt.Errorf("failed to convert %s/%s: %w", clone.GetNamespace(), clone.GetName(), err)
+	}
+	obj, ok := tv.RemoveItems(removed).AsValue().Unstructured().(map[string]any)
+	if !ok {
+		return nil, fmt.Errorf("unexpected result of pruning %s/%s", clone.GetNamespace(), clone.GetName())
+	}
+	return &unstructured.Unstructured{Object: obj}, nil
+}
+
+// isPrunable returns false for fields that Accurate must keep managing.
+func isPrunable(p fieldpath.Path) bool {
+	if *p[0].FieldName != "metadata" {
+		return true
+	}
+	if len(p) != 3 {
+		return false
+	}
+	switch *p[1].FieldName {
+	case "labels", "annotations":
+	default:
+		return false
+	}
+	key := *p[2].FieldName
+	return !strings.HasPrefix(key, constants.MetaPrefix) && key != constants.LabelCreatedBy
+}
@@ -0,0 +1,244 @@
+package controllers
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	accuratev2 "github.com/cybozu-go/accurate/api/accurate/v2"
+	utilerrors "github.com/cybozu-go/accurate/internal/util/errors"
+	"github.com/cybozu-go/accurate/pkg/constants"
+	"github.com/prometheus/client_golang/prometheus"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+)
+
+const metricsNamespace = "accurate"
+
+// Operations on copies
+const (
+	operationCreate = "create"
+	operationApply  = "apply"
+	operationDelete = "delete"
+)
+
+// Classes of propagation failures
+const (
+	failureNamespaceTerminating = "namespace_terminating"
+	failureConflict             = "conflict"
+	failureRender               = "render"
+	failureModeNotAllowed       = "mode_not_allowed"
+	failureNotFound             = "not_found"
+	failureAlreadyExists        = "already_exists"
+	failureAPIConflict          = "api_conflict"
+	failureForbidden            = "forbidden"
+	failureInvalid              = "invalid"
+	failureTimeout              = "timeout"
+	failureOther                = "other"
+)
+
+var resourceLabels = []string{"group", "version", "kind", "mode"}
+
+var (
+	propagationOperations = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Subsystem: "propagation",
+		Name:      "operations_total",
+		Help:      "The number of copies created, applied, or deleted by Accurate.",
+	}, append(resourceLabels, "operation"))
+
+	propagationFailures = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Subsystem: "propagation",
+		Name:      "failures_total",
+		Help:      "The number of failures to propagate resources.",
+	}, append(resourceLabels, "class"))
+
+	propagationLevelDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: metricsNamespace,
+		Subsystem: "propagation",
+		Name:      "level_duration_seconds",
+		Help:      "The time from a change of a resource until the last copy in its child namespaces is applied.",
+		Buckets:   prometheus.ExponentialBuckets(0.25, 2, 12),
+	}, resourceLabels)
+)
+
+func init() {
+	metrics.Registry.MustRegister(propagationOperations, propagationFailures, propagationLevelDuration)
+}
+
+func resourceLabelValues(obj *unstructured.Unstructured, mode string) []string {
+	gvk := obj.GroupVersionKind()
+	return []string{gvk.Group, gvk.Version, gvk.Kind, mode}
+}
+
+// recordOperation counts `op` done on a copy `obj` propagated in `mode`.
+func recordOperation(obj *unstructured.Unstructured, mode, op string) {
+	propagationOperations.WithLabelValues(append(resourceLabelValues(obj, mode), op)...).Inc()
+}
+
+// recordFailure counts a failure of `class` to propagate `obj` in `mode`.
+func recordFailure(obj *unstructured.Unstructured, mode, class string) {
+	propagationFailures.WithLabelValues(append(resourceLabelValues(obj, mode), class)...).Inc()
+}
+
+// failureClass classifies `err` for the failures metric.
+func failureClass(err error) string {
+	switch {
+	case utilerrors.IsNamespaceTerminating(err):
+		return failureNamespaceTerminating
+	case errors.Is(err, errConflict):
+		return failureConflict
+	case apierrors.IsNotFound(err):
+		return failureNotFound
+	case apierrors.IsAlreadyExists(err):
+		return failureAlreadyExists
+	case apierrors.IsConflict(err):
+		return failureAPIConflict
+	case apierrors.IsForbidden(err):
+		return failureForbidden
+	case apierrors.IsInvalid(err):
+		return failureInvalid
+	case apierrors.IsTimeout(err), apierrors.IsServerTimeout(err):
+		return failureTimeout
+	}
+	return failureOther
+}
+
+// observePropagation records the time since the last change of the source `src`
+// if the change is after `since`, the time when the copies were last brought up to date.
+// This excludes copies re-created or restored for reasons other than changes of the source.
+//
+// The latency is observed for each level of the tree: if `src` is itself a copy,
+// its change is the last time Accurate applied it, not the change of the original source.
+// Changes made by Accurate to an original source and changes of subresources are not counted.
+func observePropagation(src *unstructured.Unstructured, since time.Time) {
+	copied := src.GetAnnotations()[constants.AnnFrom] != ""
+	changed := src.GetCreationTimestamp().Time
+	for _, mf := range src.GetManagedFields() {
+		if (!copied && mf.Manager == string(fieldOwner)) || mf.Subresource != "" || mf.Time == nil {
+			continue
+		}
+		if mf.Time.After(changed) {
+			changed = mf.Time.Time
+		}
+	}
+	if changed.IsZero() || !changed.After(since) {
+		return
+	}
+	mode := src.GetAnnotations()[constants.AnnPropagate]
+	propagationLevelDuration.WithLabelValues(resourceLabelValues(src, mode)...).Observe(time.Since(changed).Seconds())
+}
+
+// lastApplied returns the time when Accurate last changed the copy `obj`.
+func lastApplied(obj *unstructured.Unstructured) time.Time {
+	var t time.Time
+	for _, mf := range obj.GetManagedFields() {
+		if mf.Manager != string(fieldOwner) || mf.Subresource != "" || mf.Time == nil {
+			continue
+		}
+		if mf.Time.After(t) {
+			t = mf.Time.Time
+		}
+	}
+	return t
+}
+
+// latest returns the later of `a` and `b`.
+func latest(a, b time.Time) time.Time {
+	if b.After(a) {
+		return b
+	}
+	return a
+}
+
+// stateCollector computes gauges from the cache when the metrics are scraped.
+// Only the leader reports them because the caches of watched resources
+// are filled only while the controllers are running.
+type stateCollector struct {
+	reader  client.Reader
+	watches *Watches
+	elected <-chan struct{}
+
+	propagatedObjects     *prometheus.Desc
+	subNamespaceConflicts *prometheus.Desc
+}
+
+// SetupMetrics registers metrics about the state of propagated resources and
+// SubNamespaces to the controller-runtime metrics registry.
+func SetupMetrics(mgr ctrl.Manager, watches *Watches) error {
+	return metrics.Registry.Register(&stateCollector{
+		reader:  mgr.GetClient(),
+		watches: watches,
+		elected: mgr.Elected(),
+		propagatedObjects: prometheus.NewDesc(
+			prometheus.BuildFQName(metricsNamespace, "", "propagated_objects"),
+			"The number of copies propagated by Accurate.",
+			resourceLabels, nil,
+		),
+		subNamespaceConflicts: prometheus.NewDesc(
+			prometheus.BuildFQName(metricsNamespace, "", "subnamespace_conflicts"),
+			"The number of SubNamespaces conflicting with existing namespaces.",
+			nil, nil,
+		),
+	})
+}
+
+// Describe implements prometheus.Collector interface.
+func (c *stateCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.propagatedObjects
+	ch <- c.subNamespaceConflicts
+}
+
+// Collect implements prometheus.Collector interface.
+func (c *stateCollector) Collect(ch chan<- prometheus.Metric) {
+	select {
+	case <-c.elected:
+	default:
+		return
+	}
+
+	logger := ctrl.Log.WithName("metrics")
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	for _, res := range c.watches.Resources() {
+		l := &unstructured.UnstructuredList{}
+		l.SetGroupVersionKind(res.GroupVersionKind())
+		if err := c.reader.List(ctx, l, client.MatchingFields{constants.PropagateKey: constants.PropagateAny}); err != nil {
+			logger.Error(err, "failed to list resources", "gvk", res.GroupVersionKind().String())
+			continue
+		}
+
+		counts := make(map[string]int)
+		for _, mode := range []string{constants.PropagateCreate, constants.PropagateUpdate, constants.PropagateMerge} {
+			counts[mode] = 0
+		}
+		for i := range l.Items {
+			ann := l.Items[i].GetAnnotations()
+			if ann[constants.AnnFrom] == "" {
+				continue
+			}
+			counts[ann[constants.AnnPropagate]]++
+		}
+		for mode, n := range counts {
+			ch <- prometheus.MustNewConstMetric(c.propagatedObjects, prometheus.GaugeValue, float64(n), resourceLabelValues(res, mode)...)
+		}
+	}
+
+	snList := &accuratev2.SubNamespaceList{}
+	if err := c.reader.List(ctx, snList); err != nil {
+		logger.Error(err, "failed to list subnamespaces")
+		return
+	}
+	var conflicts int
+	for _, sn := range snList.Items {
+		if isConflicting(&sn) {
+			conflicts++
+		}
+	}
+	ch <- prometheus.MustNewConstMetric(c.subNamespaceConflicts, prometheus.GaugeValue, float64(conflicts))
+}
@@ -2,18 +2,26 @@ package controllers
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"maps"
 	"path"
+	"strings"
+	"sync"
 
 	accuratev2 "github.com/cybozu-go/accurate/api/accurate/v2"
 	utilerrors "github.com/cybozu-go/accurate/internal/util/errors"
+	"github.com/cybozu-go/accurate/internal/util/templates"
+	"github.com/cybozu-go/accurate/internal/util/tree"
+	"github.com/cybozu-go/accurate/pkg/config"
 	"github.com/cybozu-go/accurate/pkg/constants"
+	"github.com/cybozu-go/accurate/pkg/feature"
 	corev1 "k8s.io/api/core/v1"
-	"k8s.io/apimachinery/pkg/api/equality"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/types"
 	corev1ac "k8s.io/client-go/applyconfigurations/core/v1"
+	"k8s.io/client-go/tools/events"
 	"k8s.io/client-go/util/workqueue"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
@@ -32,6 +40,11 @@ type NamespaceReconciler struct {
 	SubNamespaceLabelKeys      []string
 	SubNamespaceAnnotationKeys []string
 	Watched                    []*unstructured.Unstructured
+
+	recorder events.EventRecorder
+
+	// mu protects the configurations from being replaced during reconciliation.
+	mu sync.RWMutex
 }
 
 var _ reconcile.Reconciler = &NamespaceReconciler{}
@@ -39,6 +52,8 @@ var _ reconcile.Reconciler = &NamespaceReconciler{}
 // Reconcile implements reconcile.Reconciler interface.
 func (r *NamespaceReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
 	logger := log.FromContext(ctx)
+	r.mu.RLock()
+	defer r.mu.RUnlock()
 
 	ns := &corev1.Namespace{}
 	if err := r.Get(ctx, req.NamespacedName, ns); err != nil {
@@ -57,13 +72,26 @@ func (r *NamespaceReconciler) Reconcile(ctx context.Context, req ctrl.Request) (
 	return ctrl.Result{}, nil
 }
 
+// UpdateConfig replaces the configurations of the running reconciler.
+// It waits for the ongoing reconciliation to finish.
+func (r *NamespaceReconciler) UpdateConfig(cfg *config.Config, cloner ResourceCloner, watched []*unstructured.Unstructured) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.ResourceCloner = cloner
+	r.LabelKeys = cfg.LabelKeys
+	r.AnnotationKeys = cfg.AnnotationKeys
+	r.SubNamespaceLabelKeys = cfg.SubNamespaceLabelKeys
+	r.SubNamespaceAnnotationKeys = cfg.SubNamespaceAnnotationKeys
+	r.Watched = watched
+}
+
 func (r *NamespaceReconciler) reconcile(ctx context.Context, ns *corev1.Namespace) error {
 	if parent, ok := ns.Labels[constants.LabelParent]; ok {
 		return r.reconcileSubNamespace(ctx, ns, parent)
 	}
 
-	if tmpl, ok := ns.Labels[constants.LabelTemplate]; ok {
-		if err := r.reconcileInstanceNamespace(ctx, ns, tmpl); err != nil {
+	if tmpls := templates.Names(ns); len(tmpls) > 0 {
+		if err := r.reconcileInstanceNamespace(ctx, ns, tmpls); err != nil {
 			return err
 		}
 		// a template instance may also be a root or a template namespace, so don't return here.
@@ -90,18 +118,37 @@ func (r *NamespaceReconciler) propagateMeta(ctx context.Context, ns, parent *cor
 	labels := make(map[string]string)
 	annotations := make(map[string]string)
 
+	_, isSub := ns.Labels[constants.LabelParent]
+	var treeLabelKeys, treeAnnotationKeys []string
+	if isSub {
+		rn, err := r.getRootNamespacePolicy(ctx, parent.Name)
+		if err != nil {
+			return err
+		}
+		if rn != nil {
+			treeLabelKeys = rn.Spec.LabelKeys
+			treeAnnotationKeys = rn.Spec.AnnotationKeys
+		}
+	}
+	matchLabelKey := func(key string) bool {
+		return r.matchLabelKey(key) || matchKey(key, treeLabelKeys)
+	}
+	matchAnnotationKey := func(key string) bool {
+		return r.matchAnnotationKey(key) || matchKey(key, treeAnnotationKeys)
+	}
+
 	for k, v := range parent.Labels {
-		if ok := r.matchLabelKey(k); ok {
+		if ok := matchLabelKey(k); ok {
 			labels[k] = v
 		}
 	}
 	for k, v := range parent.Annotations {
-		if ok := r.matchAnnotationKey(k); ok {
+		if ok := matchAnnotationKey(k); ok {
 			annotations[k] = v
 		}
 	}
 
-	if _, ok := ns.Labels[constants.LabelParent]; ok {
+	if isSub {
 		subNS := &accuratev2.SubNamespace{}
 		err := r.Get(ctx, types.NamespacedName{Name: ns.Name, Namespace: parent.Name}, subNS)
 		if err != nil {
@@ -125,6 +172,18 @@ func (r *NamespaceReconciler) propagateMeta(ctx context.Context, ns, parent *cor
 		labels[constants.LabelParent] = parent.Name
 	}
 
+	if config.DefaultFeatureGate.Enabled(feature.PropagateMetaDeletion) {
+		isPropagated := func(field, key string) bool {
+			if field == "labels" {
+				return matchLabelKey(key) || (isSub && r.matchSubNamespaceLabelKey(key))
+			}
+			return matchAnnotationKey(key) || (isSub && r.matchSubNamespaceAnnotationKey(key))
+		}
+		if err := r.deleteStaleMeta(ctx, ns, isPropagated, labels, annotations); err != nil {
+			return err
+		}
+	}
+
 	ac := corev1ac.Namespace(ns.Name).
 		WithLabels(labels).
 		WithAnnotations(annotations)
@@ -134,6 +193,27 @@ func (r *NamespaceReconciler) propagateMeta(ctx context.Context, ns, parent *cor
 	return nil
 }
 
+// getRootNamespacePolicy returns the RootNamespace of the tree that the namespace `name` belongs to,
+// or nil if the tree has no RootNamespace.
+func (r *NamespaceReconciler) getRootNamespacePolicy(ctx context.Context, name string) (*accuratev2.RootNamespace, error) {
+	root, err := tree.FindRoot(ctx, r.Client, name)
+	if errors.Is(err, tree.ErrCircularReference) {
+		// a tree with a cycle has no root, so no RootNamespace
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	rn := &accuratev2.RootNamespace{}
+	if err := r.Get(ctx, client.ObjectKey{Name: root}, rn); err != nil {
+		if apierrors.IsNotFound(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get RootNamespace %s: %w", root, err)
+	}
+	return rn, nil
+}
+
 func (r *NamespaceReconciler) matchLabelKey(key string) bool {
 	return matchKey(key, r.LabelKeys)
 }
@@ -150,7 +230,16 @@ func (r *NamespaceReconciler) matchSubNamespaceAnnotationKey(key string) bool {
 	return matchKey(key, r.SubNamespaceAnnotationKeys)
 }
 
-func (r *NamespaceReconciler) propagateResource(ctx context.Context, res *unstructured.Unstructured, parent, ns string) error {
+// parentSource is a namespace that objects are propagated from.
+// If `nt` is not nil, the objects of NamespaceTemplate `nt` are propagated instead.
+type parentSource struct {
+	name string
+	nt   *accuratev2.NamespaceTemplate
+}
+
+// propagateResource propagates objects of the kind of `res` from `parents` to `ns`.
+// If objects of the same name exist in two or more parents, the last one takes precedence.
+func (r *NamespaceReconciler) propagateResource(ctx context.Context, res *unstructured.Unstructured, ns *corev1.Namespace, parents []parentSource) error {
 	logger := log.FromContext(ctx)
 
 	gvk := res.GroupVersionKind()
@@ -159,74 +248,185 @@ func (r *NamespaceReconciler) propagateResource(ctx context.Context, res *unstru
 	l := &unstructured.UnstructuredList{}
 	l.SetGroupVersionKind(gvk)
 
-	cl := l.DeepCopy()
-	if err := r.List(ctx, cl, client.MatchingFields{constants.PropagateKey: constants.PropagateCreate}, client.InNamespace(parent)); err != nil {
-		return fmt.Errorf("failed to list %s in %s with propagate=create: %w", gvkStr, parent, err)
-	}
-	for i := range cl.Items {
-		pres := &cl.Items[i]
-		if err := r.propagateCreate(ctx, pres, ns); err != nil {
-			return fmt.Errorf("failed to propagate resource %s/%s of %s with propagate=create: %w", ns, pres.GetName(), gvkStr, err)
+	// collect the sources from all the parents.
+	var names []string
+	sources := make(map[string]*unstructured.Unstructured)
+	providers := make(map[string][]string)
+	parentNames := make(map[string]bool)
+	for _, parent := range parents {
+		parentNames[parent.name] = true
+		for _, mode := range append([]string{constants.PropagateCreate}, followModes...) {
+			pl := l.DeepCopy()
+			if parent.nt != nil {
+				items, err := virtualSources(parent.nt, res.GroupVersionKind(), mode)
+				if err != nil {
+					return err
+				}
+				pl.Items = items
+			} else if err := r.List(ctx, pl, client.MatchingFields{constants.PropagateKey: mode}, client.InNamespace(parent.name)); err != nil {
+				return fmt.Errorf("failed to list %s in %s with propagate=%s: %w", gvkStr, parent.name, mode, err)
+			}
+			for i := range pl.Items {
+				pres := &pl.Items[i]
+				if _, ok := sources[pres.GetName()]; !ok {
+					names = append(names, pres.GetName())
+				}
+				sources[pres.GetName()] = pres
+				providers[pres.GetName()] = append(providers[pres.GetName()], parent.name)
+			}
 		}
 	}
 
-	ul := l.DeepCopy()
-	if err := r.List(ctx, ul, client.MatchingFields{constants.PropagateKey: constants.PropagateUpdate}, client.InNamespace(parent)); err != nil {
-		return fmt.Errorf("failed to list %s in %s with propagate=update: %w", gvkStr, parent, err)
-	}
-	presNames := make(map[string]bool)
-	for i := range ul.Items {
-		pres := &ul.Items[i]
-		presNames[pres.GetName()] = true
-		if err := r.propagateUpdate(ctx, pres, ns); err != nil {
-			return fmt.Errorf("failed to propagate resource %s/%s of %s with propagate=update: %w", ns, pres.GetName(), gvkStr, err)
+	presFrom := make(map[string]string)
+	for _, name := range names {
+		pres := sources[name]
+		if validatePropagation(pres) != nil {
+			// keep the copy until the annotations of the source are fixed
+			presFrom[name] = pres.GetNamespace()
+			continue
 		}
-	}
-
-	ul2 := l.DeepCopy()
-	if err := r.List(ctx, ul2, client.MatchingFields{constants.PropagateKey: constants.PropagateUpdate}, client.InNamespace(ns)); err != nil {
-		return fmt.Errorf("failed to list %s in %s with propagate=update: %w", gvkStr, ns, err)
-	}
-	for i := range ul2.Items {
-		cres := &ul2.Items[i]
-		from := cres.GetAnnotations()[constants.AnnFrom]
-		if from == "" {
-			// don't delete origins
+		if !propagatesTo(pres, ns) {
+			// copies excluded from the propagation are deleted below
 			continue
 		}
+		if len(providers[name]) > 1 {
+			if err := r.reportOverlap(ctx, pres, ns.Name, providers[name]); err != nil {
+				return err
+			}
+		}
 
-		if from == parent && presNames[cres.GetName()] {
+		excluded, err := isExcluded(r.Client, pres, ns)
+		if err != nil {
+			return err
+		}
+		mode := pres.GetAnnotations()[constants.AnnPropagate]
+		if mode == constants.PropagateCreate {
+			if excluded {
+				if err := r.deleteExcluded(ctx, pres, ns.Name); err != nil {
+					return err
+				}
+				continue
+			}
+			if err := r.propagateCreate(ctx, pres, ns.Name, parentNames); err != nil {
+				return fmt.Errorf("failed to propagate resource %s/%s of %s with propagate=create: %w", ns.Name, name, gvkStr, err)
+			}
 			continue
 		}
-		if err := r.Delete(ctx, cres); err != nil {
-			return fmt.Errorf("failed to delete stale resource %s/%s of %s: %w", ns, cres.GetName(), gvkStr, err)
+		if excluded {
+			continue
+		}
+		presFrom[name] = pres.GetNamespace()
+		if err := r.propagateUpdate(ctx, pres, ns.Name); err != nil {
+			return fmt.Errorf("failed to propagate resource %s/%s of %s with propagate=%s: %w", ns.Name, name, gvkStr, mode, err)
 		}
-		logger.Info("deleted a resource", "namespace", cres.GetNamespace(), "name", cres.GetName(), "gvk", gvkStr)
 	}
 
+	for _, mode := range followModes {
+		ul2 := l.DeepCopy()
+		if err := r.List(ctx, ul2, client.MatchingFields{constants.PropagateKey: mode}, client.InNamespace(ns.Name)); err != nil {
+			return fmt.Errorf("failed to list %s in %s with propagate=%s: %w", gvkStr, ns.Name, mode, err)
+		}
+		for i := range ul2.Items {
+			cres := &ul2.Items[i]
+			from := cres.GetAnnotations()[constants.AnnFrom]
+			if from == "" {
+				// don't delete origins
+				continue
+			}
+
+			if presFrom[cres.GetName()] == from {
+				continue
+			}
+			if err := deleteCopy(ctx, r.Client, cres); err != nil {
+				return fmt.Errorf("failed to delete stale resource %s/%s of %s: %w", ns.Name, cres.GetName(), gvkStr, err)
+			}
+			logger.Info("deleted a resource", "namespace", cres.GetNamespace(), "name", cres.GetName(), "gvk", gvkStr)
+			r.recorder.Eventf(cres, nil, corev1.EventTypeNormal, reasonDeleted, actionDelete,
+				"deleted since the source in %s is no longer propagated", from)
+		}
+	}
+
+	return nil
+}
+
+// reportOverlap reports that objects named after `res` are provided by two or more templates `from` of `ns`.
+// It is reported only when the copy in `ns` is not yet the one of `res`.
+func (r *NamespaceReconciler) reportOverlap(ctx context.Context, res *unstructured.Unstructured, ns string, from []string) error {
+	c := &unstructured.Unstructured{}
+	c.SetGroupVersionKind(res.GroupVersionKind())
+	err := r.Get(ctx, client.ObjectKey{Namespace: ns, Name: res.GetName()}, c)
+	if err != nil && !apierrors.IsNotFound(err) {
+		return err
+	}
+	if err == nil && c.GetAnnotations()[constants.AnnFrom] == res.GetNamespace() {
+		return nil
+	}
+
+	nsObj := &corev1.Namespace{}
+	nsObj.Name = ns
+	r.recorder.Eventf(nsObj, nil, corev1.EventTypeWarning, reasonConflict, actionPropagate,
+		"%s %s is provided by templates %s; the one in %s takes precedence",
+		res.GetKind(), res.GetName(), strings.Join(from, ","), res.GetNamespace())
 	return nil
 }
 
-func (r *NamespaceReconciler) propagateCreate(ctx context.Context, res *unstructured.Unstructured, ns string) error {
+// propagateCreate creates a copy of `res` in namespace `ns` unless it exists.
+// A copy from another namespace in `parents` is replaced, since `res` takes precedence over it.
+func (r *NamespaceReconciler) propagateCreate(ctx context.Context, res *unstructured.Unstructured, ns string, parents map[string]bool) error {
 	gvk := res.GroupVersionKind()
 
 	c := &unstructured.Unstructured{}
 	c.SetGroupVersionKind(gvk)
 	err := r.Get(ctx, client.ObjectKey{Namespace: ns, Name: res.GetName()}, c)
-	if err == nil {
-		return nil
-	}
-	if !apierrors.IsNotFound(err) {
+	if err != nil && !apierrors.IsNotFound(err) {
 		return err
 	}
+	exists := err == nil
+	if exists {
+		from := c.GetAnnotations()[constants.AnnFrom]
+		switch {
+		case from != res.GetNamespace() && parents[from]:
+			// the copy from a template of lower precedence is replaced
+		case !isConflict(c):
+			return nil
+		default:
+			overwrite, err := r.handleConflict(ctx, res, c)
+			if !overwrite {
+				return err
+			}
+		}
+	}
 
-	if err := r.Create(ctx, r.cloneResource(res, ns)); err != nil {
+	clone, err := r.cloneAndRender(ctx, r.Client, res, ns)
+	if err != nil {
+		r.renderFailed(ctx, res, ns, err)
+		return nil
+	}
+	if exists {
+		if _, err := applyCopy(ctx, r.Client, clone, c, constants.PropagateUpdate); err != nil {
+			return err
+		}
+		logger := log.FromContext(ctx)
+		if !isConflict(c) {
+			logger.Info("replaced a resource", "namespace", ns, "name", res.GetName(), "gvk", gvk.String())
+			r.recorder.Eventf(clone, res, corev1.EventTypeNormal, reasonUpdated, actionPropagate,
+				"replaced the copy of %s/%s with a copy of %s/%s", c.GetAnnotations()[constants.AnnFrom], res.GetName(), res.GetNamespace(), res.GetName())
+			return nil
+		}
+		logger.Info("overwrote a conflicting resource", "namespace", ns, "name", res.GetName(), "gvk", gvk.String())
+		r.recorder.Eventf(clone, res, corev1.EventTypeNormal, reasonUpdated, actionPropagate,
+			"overwrote a conflicting object with a copy of %s/%s", res.GetNamespace(), res.GetName())
+		return r.clearConflict(ctx, res, ns)
+	}
+	if err := createCopy(ctx, r.Client, clone, constants.PropagateCreate); err != nil {
 		return utilerrors.Ignore(err, utilerrors.IsNamespaceTerminating)
 	}
 
 	logger := log.FromContext(ctx)
 	logger.Info("created a resource", "namespace", ns, "name", res.GetName(), "gvk", gvk.String())
-	return nil
+	r.recorder.Eventf(clone, res, corev1.EventTypeNormal, reasonCreated, actionPropagate,
+		"created from %s/%s", res.GetNamespace(), res.GetName())
+	return r.clearConflict(ctx, res, ns)
 }
 
 func (r *NamespaceReconciler) propagateUpdate(ctx context.Context, res *unstructured.Unstructured, ns string) error {
@@ -236,32 +436,117 @@ func (r *NamespaceReconciler) propagateUpdate(ctx context.Context, res *unstruct
 	c := &unstructured.Unstructured{}
 	c.SetGroupVersionKind(gvk)
 	err := r.Get(ctx, client.ObjectKey{Namespace: ns, Name: res.GetName()}, c)
-	if err != nil {
-		if !apierrors.IsNotFound(err) {
+	if err != nil && !apierrors.IsNotFound(err) {
+		return err
+	}
+	if err == nil && isConflict(c) {
+		overwrite, err := r.handleConflict(ctx, res, c)
+		if !overwrite {
 			return err
 		}
-		if err := r.Create(ctx, r.cloneResource(res, ns)); err != nil {
+	}
+
+	c2, rerr := r.cloneAndRender(ctx, r.Client, res, ns)
+	if rerr != nil {
+		r.renderFailed(ctx, res, ns, rerr)
+		return nil
+	}
+
+	mode := res.GetAnnotations()[constants.AnnPropagate]
+	if err != nil {
+		if err := createCopy(ctx, r.Client, c2, mode); err != nil {
 			return utilerrors.Ignore(err, utilerrors.IsNamespaceTerminating)
 		}
 		logger.Info("created a resource", "namespace", ns, "name", res.GetName(), "gvk", gvk.String())
+		r.recorder.Eventf(c2, res, corev1.EventTypeNormal, reasonCreated, actionPropagate,
+			"created from %s/%s", res.GetNamespace(), res.GetName())
+		return r.clearConflict(ctx, res, ns)
+	}
+
+	applied, err := applyCopy(ctx, r.Client, c2, c, mode)
+	if err != nil {
+		return err
+	}
+	if !applied {
 		return nil
 	}
 
-	c2 := r.cloneResource(res, ns)
+	logger.Info("applied a resource", "namespace", ns, "name", res.GetName(), "gvk", gvk.String())
+	r.recorder.Eventf(c2, res, corev1.EventTypeNormal, reasonUpdated, actionPropagate,
+		"updated from %s/%s", res.GetNamespace(), res.GetName())
+	return r.clearConflict(ctx, res, ns)
+}
+
+// handleConflict handles `c` conflicting with `res`, and returns true if `c` should be overwritten.
+// If `c` is left intact, its namespace is recorded in the annotation of `res`.
+// For a virtual source, `c` is recorded in the status of its NamespaceTemplate instead.
+func (r *NamespaceReconciler) handleConflict(ctx context.Context, res, c *unstructured.Unstructured) (bool, error) {
+	if !isVirtualSource(res) {
+		overwrite, err := r.resolveConflict(r.recorder, res, c, res, conflictRecorded(res, c.GetNamespace()))
+		if overwrite {
+			return true, nil
+		}
+		if err := setConflicts(ctx, r.Client, res, []string{c.GetNamespace()}, true); err != nil {
+			return false, err
+		}
+		return false, err
+	}
+
+	conflict := templateConflictOf(c)
+	recorded, err := templateConflictRecorded(ctx, r.Client, res.GetNamespace(), conflict)
+	if err != nil {
+		return false, err
+	}
+	overwrite, err := r.resolveConflict(r.recorder, res, c, eventRegarding(ctx, r.Client, res), recorded)
+	if overwrite {
+		return true, nil
+	}
+	if err := setTemplateConflict(ctx, r.Client, res.GetNamespace(), conflict, true); err != nil {
+		return false, err
+	}
+	return false, err
+}
 
-	if equality.Semantic.DeepDerivative(c2, c) {
+// clearConflict removes the record of the conflict of virtual source `res` in namespace `ns`
+// after its copy is propagated.
+func (r *NamespaceReconciler) clearConflict(ctx context.Context, res *unstructured.Unstructured, ns string) error {
+	if !isVirtualSource(res) {
 		return nil
 	}
+	conflict := accuratev2.NamespaceTemplateConflict{
+		APIVersion: res.GetAPIVersion(),
+		Kind:       res.GetKind(),
+		Namespace:  ns,
+		Name:       res.GetName(),
+	}
+	return setTemplateConflict(ctx, r.Client, res.GetNamespace(), conflict, false)
+}
 
-	ac := client.ApplyConfigurationFromUnstructured(c2)
-	if err := r.Apply(ctx, ac, fieldOwner, client.ForceOwnership); err != nil {
-		return fmt.Errorf("failed to apply %s/%s: %w", c2.GetNamespace(), c2.GetName(), err)
+// deleteExcluded deletes the copy of `res` in namespace `ns` that has opted out of it.
+func (r *NamespaceReconciler) deleteExcluded(ctx context.Context, res *unstructured.Unstructured, ns string) error {
+	c := &unstructured.Unstructured{}
+	c.SetGroupVersionKind(res.GroupVersionKind())
+	if err := r.Get(ctx, client.ObjectKey{Namespace: ns, Name: res.GetName()}, c); err != nil {
+		return client.IgnoreNotFound(err)
+	}
+	if c.GetAnnotations()[constants.AnnFrom] != res.GetNamespace() {
+		return nil
 	}
 
-	logger.Info("applied a resource", "namespace", ns, "name", res.GetName(), "gvk", gvk.String())
+	if err := deleteCopy(ctx, r.Client, c); err != nil {
+		return fmt.Errorf("failed to delete %s/%s: %w", ns, c.GetName(), err)
+	}
+	logger := log.FromContext(ctx)
+	logger.Info("deleted an opted-out resource", "namespace", ns, "name", c.GetName(), "gvk", c.GroupVersionKind().String())
+	r.recorder.Eventf(c, nil, corev1.EventTypeNormal, reasonDeleted, actionDelete,
+		"deleted since namespace %s opts out of it", ns)
 	return nil
 }
 
+func (r *NamespaceReconciler) renderFailed(ctx context.Context, res *unstructured.Unstructured, ns string, err error) {
+	cloneFailed(r.recorder, eventRegarding(ctx, r.Client, res), res, ns, err)
+}
+
 func (r *NamespaceReconciler) deleteResource(ctx context.Context, res *unstructured.Unstructured, ns string) error {
 	logger := log.FromContext(ctx)
 
@@ -271,11 +556,14 @@ func (r *NamespaceReconciler) deleteResource(ctx context.Context, res *unstructu
 	l := &unstructured.UnstructuredList{}
 	l.SetGroupVersionKind(gvk)
 
-	if err := r.List(ctx, l, client.MatchingFields{constants.PropagateKey: constants.PropagateUpdate}, client.InNamespace(ns)); err != nil {
+	if err := r.List(ctx, l, client.MatchingFields{constants.PropagateKey: constants.PropagateAny}, client.InNamespace(ns)); err != nil {
 		return fmt.Errorf("failed to list %s in %s: %w", gvkStr, ns, err)
 	}
 	for i := range l.Items {
 		obj := &l.Items[i]
+		if !followsSource(obj.GetAnnotations()[constants.AnnPropagate]) {
+			continue
+		}
 
 		from := obj.GetAnnotations()[constants.AnnFrom]
 		if from == "" {
@@ -283,10 +571,12 @@ func (r *NamespaceReconciler) deleteResource(ctx context.Context, res *unstructu
 			continue
 		}
 
-		if err := r.Delete(ctx, obj); err != nil {
+		if err := deleteCopy(ctx, r.Client, obj); err != nil {
 			return fmt.Errorf("failed to delete %s/%s of %s: %w", ns, obj.GetName(), gvkStr, err)
 		}
 		logger.Info("deleted a resource", "namespace", ns, "name", obj.GetName(), "gvk", gvkStr)
+		r.recorder.Eventf(obj, nil, corev1.EventTypeNormal, reasonDeleted, actionDelete,
+			"deleted since the namespace no longer has a parent or a template")
 	}
 	return nil
 }
@@ -320,8 +610,9 @@ func (r *NamespaceReconciler) reconcileSubNamespace(ctx context.Context, ns *cor
 		}
 	}
 
+	parents := []parentSource{{name: parent}}
 	for _, res := range r.Watched {
-		if err := r.propagateResource(ctx, res, parent, ns.Name); err != nil {
+		if err := r.propagateResource(ctx, res, ns, parents); err != nil {
 			return err
 		}
 	}
@@ -344,10 +635,10 @@ func (r *NamespaceReconciler) reconcileRootNamespace(ctx context.Context, ns *co
 	return nil
 }
 
-func (r *NamespaceReconciler) reconcileInstanceNamespace(ctx context.Context, ns *corev1.Namespace, tmpl string) error {
-	tmplNS := &corev1.Namespace{}
-	if err := r.Get(ctx, client.ObjectKey{Name: tmpl}, tmplNS); err != nil {
-		return fmt.Errorf("failed to get template namespace %s: %w", tmpl, err)
+func (r *NamespaceReconciler) reconcileInstanceNamespace(ctx context.Context, ns *corev1.Namespace, tmpls []string) error {
+	parents, tmplNS, err := r.getTemplates(ctx, tmpls)
+	if err != nil {
+		return err
 	}
 
 	if err := r.propagateMeta(ctx, ns, tmplNS); err != nil {
@@ -355,7 +646,7 @@ func (r *NamespaceReconciler) reconcileInstanceNamespace(ctx context.Context, ns
 	}
 
 	for _, res := range r.Watched {
-		if err := r.propagateResource(ctx, res, tmpl, ns.Name); err != nil {
+		if err := r.propagateResource(ctx, res, ns, parents); err != nil {
 			return err
 		}
 	}
@@ -363,6 +654,42 @@ func (r *NamespaceReconciler) reconcileInstanceNamespace(ctx context.Context, ns
 	return nil
 }
 
+// getTemplates returns the templates `tmpls` in precedence order,
+// and a pseudo template namespace having their labels and annotations merged in that order.
+// Each template is either a template namespace or a NamespaceTemplate.
+func (r *NamespaceReconciler) getTemplates(ctx context.Context, tmpls []string) ([]parentSource, *corev1.Namespace, error) {
+	parents := make([]parentSource, 0, len(tmpls))
+	merged := &corev1.Namespace{}
+	merged.Labels = make(map[string]string)
+	merged.Annotations = make(map[string]string)
+	for _, tmpl := range tmpls {
+		tmplNS := &corev1.Namespace{}
+		var nt *accuratev2.NamespaceTemplate
+		if err := r.Get(ctx, client.ObjectKey{Name: tmpl}, tmplNS); err != nil {
+			if !apierrors.IsNotFound(err) {
+				return nil, nil, fmt.Errorf("failed to get template namespace %s: %w", tmpl, err)
+			}
+			nt, err = getNamespaceTemplate(ctx, r.Client, tmpl)
+			if err != nil {
+				return nil, nil, err
+			}
+			if nt == nil {
+				return nil, nil, fmt.Errorf("neither template namespace nor NamespaceTemplate %s is found", tmpl)
+			}
+			// The labels and annotations of NamespaceTemplate are propagated as those of a template namespace.
+			tmplNS.Name = nt.Name
+			tmplNS.Labels = nt.Spec.Labels
+			tmplNS.Annotations = nt.Spec.Annotations
+		}
+
+		parents = append(parents, parentSource{name: tmpl, nt: nt})
+		merged.Name = tmplNS.Name
+		maps.Copy(merged.Labels, tmplNS.Labels)
+		maps.Copy(merged.Annotations, tmplNS.Annotations)
+	}
+	return parents, merged, nil
+}
+
 func (r *NamespaceReconciler) reconcileTemplateNamespace(ctx context.Context, ns *corev1.Namespace) error {
 	instances := &corev1.NamespaceList{}
 	if err := r.List(ctx, instances, client.MatchingFields{constants.NamespaceTemplateKey: ns.Name}); err != nil {
@@ -371,7 +698,16 @@ func (r *NamespaceReconciler) reconcileTemplateNamespace(ctx context.Context, ns
 
 	for i := range instances.Items {
 		instance := &instances.Items[i]
-		if err := r.propagateMeta(ctx, instance, ns); err != nil {
+		tmplNS := ns
+		if tmpls := templates.Names(instance); len(tmpls) > 1 {
+			// the labels and annotations of the other templates are merged
+			_, merged, err := r.getTemplates(ctx, tmpls)
+			if err != nil {
+				return err
+			}
+			tmplNS = merged
+		}
+		if err := r.propagateMeta(ctx, instance, tmplNS); err != nil {
 			return err
 		}
 	}
@@ -386,6 +722,8 @@ func (r *NamespaceReconciler) SetupWithManager(mgr ctrl.Manager) error {
 		}})
 	}
 
+	r.recorder = mgr.GetEventRecorder(eventSource)
+
 	return ctrl.NewControllerManagedBy(mgr).
 		For(&corev1.Namespace{}).
 		Watches(&accuratev2.SubNamespace{}, handler.Funcs{
@@ -399,9 +737,35 @@ func (r *NamespaceReconciler) SetupWithManager(mgr ctrl.Manager) error {
 				subNSHandler(ev.ObjectOld, q)
 			},
 		}).
+		Watches(&accuratev2.NamespaceTemplate{}, handler.EnqueueRequestsFromMapFunc(r.instancesOf)).
+		Watches(&accuratev2.RootNamespace{}, handler.Funcs{
+			CreateFunc: func(ctx context.Context, ev event.TypedCreateEvent[client.Object], q workqueue.TypedRateLimitingInterface[reconcile.Request]) {
+				subNSHandler(ev.Object, q)
+			},
+			UpdateFunc: func(ctx context.Context, ev event.TypedUpdateEvent[client.Object], q workqueue.TypedRateLimitingInterface[reconcile.Request]) {
+				subNSHandler(ev.ObjectNew, q)
+			},
+			DeleteFunc: func(ctx context.Context, ev event.TypedDeleteEvent[client.Object], q workqueue.TypedRateLimitingInterface[reconcile.Request]) {
+				subNSHandler(ev.Object, q)
+			},
+		}).
 		Complete(r)
 }
 
+// instancesOf returns the requests for the instance namespaces of NamespaceTemplate `o`.
+func (r *NamespaceReconciler) instancesOf(ctx context.Context, o client.Object) []reconcile.Request {
+	instances := &corev1.NamespaceList{}
+	if err := r.List(ctx, instances, client.MatchingFields{constants.NamespaceTemplateKey: o.GetName()}); err != nil {
+		log.FromContext(ctx).Error(err, "failed to list instance namespaces", "template", o.GetName())
+		return nil
+	}
+	requests := make([]reconcile.Request, len(instances.Items))
+	for i := range instances.Items {
+		requests[i] = reconcile.Request{NamespacedName: types.NamespacedName{Name: instances.Items[i].Name}}
+	}
+	return requests
+}
+
 func matchKey(key string, list []string) bool {
 	for _, l := range list {
 		// The glob pattern has been verified to be in the valid format when reading the config file.
@@ -5,7 +5,9 @@ import (
 	"time"
 
 	accuratev2 "github.com/cybozu-go/accurate/api/accurate/v2"
+	accurateconfig "github.com/cybozu-go/accurate/pkg/config"
 	"github.com/cybozu-go/accurate/pkg/constants"
+	"github.com/cybozu-go/accurate/pkg/feature"
 	"github.com/cybozu-go/accurate/pkg/indexing"
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
@@ -13,7 +15,9 @@ import (
 	rbacv1 "k8s.io/api/rbac/v1"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/utils/ptr"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
@@ -302,6 +306,151 @@ var _ = Describe("Namespace controller", func() {
 		Eventually(komega.Object(instance)).Should(HaveField("Labels", Not(HaveKey("team"))))
 	})
 
+	It("should propagate the objects of NamespaceTemplate", func() {
+		nt := &accuratev2.NamespaceTemplate{}
+		nt.Name = "vtmpl"
+		nt.Spec.Labels = map[string]string{"team": "neco", "unrelated": "foo"}
+		nt.Spec.Resources = []accuratev2.NamespaceTemplateResource{
+			{
+				Mode:     constants.PropagateUpdate,
+				Manifest: runtime.RawExtension{Raw: []byte(`{"apiVersion":"v1","kind":"Secret","metadata":{"name":"vtmpl-secret"},"data":{"foo":"YmFy"}}`)},
+			},
+			{
+				Mode:     constants.PropagateCreate,
+				Manifest: runtime.RawExtension{Raw: []byte(`{"apiVersion":"rbac.authorization.k8s.io/v1","kind":"Role","metadata":{"name":"vtmpl-role"}}`)},
+			},
+		}
+		Expect(k8sClient.Create(ctx, nt)).To(Succeed())
+
+		instance := &corev1.Namespace{}
+		instance.Name = "vtmpl-instance"
+		instance.Labels = map[string]string{constants.LabelTemplate: nt.Name}
+		Expect(k8sClient.Create(ctx, instance)).To(Succeed())
+
+		Eventually(komega.Object(instance)).Should(HaveField("Labels", HaveKeyWithValue("team", "neco")))
+		Expect(instance.Labels).NotTo(HaveKey("unrelated"))
+
+		sec := &corev1.Secret{}
+		sec.Namespace = instance.Name
+		sec.Name = "vtmpl-secret"
+		Eventually(komega.Get(sec)).Should(Succeed())
+		Expect(sec.Annotations).To(HaveKeyWithValue(constants.AnnFrom, nt.Name))
+		Expect(sec.Annotations).To(HaveKeyWithValue(constants.AnnPropagate, constants.PropagateUpdate))
+		Expect(sec.Data).To(HaveKeyWithValue("foo", []byte("bar")))
+
+		role := &rbacv1.Role{}
+		role.Namespace = instance.Name
+		role.Name = "vtmpl-role"
+		Eventually(komega.Get(role)).Should(Succeed())
+
+		By("removing the objects from NamespaceTemplate")
+		Expect(komega.Update(nt, func() {
+			nt.Spec.Resources = nil
+		})()).To(Succeed())
+		Eventually(komega.Get(sec)).Should(WithTransform(apierrors.IsNotFound, BeTrue()))
+		Consistently(komega.Get(role)).Should(Succeed())
+	})
+
+	It("should record conflicts with NamespaceTemplate in its status", func() {
+		nt := &accuratev2.NamespaceTemplate{}
+		nt.Name = "vtmpl-conflict"
+		nt.Spec.Resources = []accuratev2.NamespaceTemplateResource{
+			{
+				Mode:     constants.PropagateCreate,
+				Manifest: runtime.RawExtension{Raw: []byte(`{"apiVersion":"v1","kind":"Secret","metadata":{"name":"vtmpl-conflict-secret"},"data":{"foo":"YmFy"}}`)},
+			},
+		}
+		Expect(k8sClient.Create(ctx, nt)).To(Succeed())
+
+		instance := &corev1.Namespace{}
+		instance.Name = "vtmpl-conflict-instance"
+		Expect(k8sClient.Create(ctx, instance)).To(Succeed())
+
+		sec := &corev1.Secret{}
+		sec.Namespace = instance.Name
+		sec.Name = "vtmpl-conflict-secret"
+		sec.Data = map[string][]byte{"foo": []byte("tenant")}
+		Expect(k8sClient.Create(ctx, sec)).To(Succeed())
+
+		Expect(komega.Update(instance, func() {
+			instance.Labels = map[string]string{constants.LabelTemplate: nt.Name}
+		})()).To(Succeed())
+
+		conflict := accuratev2.NamespaceTemplateConflict{
+			APIVersion: "v1",
+			Kind:       "Secret",
+			Namespace:  instance.Name,
+			Name:       sec.Name,
+		}
+		Eventually(komega.Object(nt)).Should(HaveField("Status.Conflicts", ConsistOf(conflict)))
+		Consistently(komega.Object(sec)).Should(HaveField("Data", HaveKeyWithValue("foo", []byte("tenant"))))
+
+		By("deleting the conflicting object")
+		Expect(k8sClient.Delete(ctx, sec)).To(Succeed())
+		Expect(komega.Update(nt, func() {
+			nt.Spec.Labels = map[string]string{"team": "neco"}
+		})()).To(Succeed())
+		Eventually(komega.Object(nt)).Should(HaveField("Status.Conflicts", BeEmpty()))
+		Eventually(komega.Object(sec)).Should(HaveField("Annotations", HaveKeyWithValue(constants.AnnFrom, nt.Name)))
+	})
+
+	It("should merge multiple templates in precedence order", func() {
+		base := &corev1.Namespace{}
+		base.Name = "multi-base"
+		base.Labels = map[string]string{
+			constants.LabelType: constants.NSTypeTemplate,
+			"team":              "base",
+			"foo.bar/baz":       "base",
+		}
+		Expect(k8sClient.Create(ctx, base)).To(Succeed())
+
+		for _, name := range []string{"multi-shared", "multi-base-only"} {
+			secret := &corev1.Secret{}
+			secret.Namespace = base.Name
+			secret.Name = name
+			secret.Annotations = map[string]string{constants.AnnPropagate: constants.PropagateUpdate}
+			secret.Data = map[string][]byte{"from": []byte("base")}
+			Expect(k8sClient.Create(ctx, secret)).To(Succeed())
+		}
+
+		nt := &accuratev2.NamespaceTemplate{}
+		nt.Name = "multi-monitoring"
+		nt.Spec.Labels = map[string]string{"team": "monitoring"}
+		nt.Spec.Resources = []accuratev2.NamespaceTemplateResource{
+			{
+				Manifest: runtime.RawExtension{Raw: []byte(`{"apiVersion":"v1","kind":"Secret","metadata":{"name":"multi-shared"},"data":{"from":"bW9uaXRvcmluZw=="}}`)},
+			},
+		}
+		Expect(k8sClient.Create(ctx, nt)).To(Succeed())
+
+		instance := &corev1.Namespace{}
+		instance.Name = "multi-instance"
+		instance.Annotations = map[string]string{constants.AnnTemplates: "multi-base,multi-monitoring"}
+		Expect(k8sClient.Create(ctx, instance)).To(Succeed())
+
+		Eventually(komega.Object(instance)).Should(HaveField("Labels", HaveKeyWithValue("team", "monitoring")))
+		Expect(instance.Labels).To(HaveKeyWithValue("foo.bar/baz", "base"))
+
+		shared := &corev1.Secret{}
+		shared.Namespace = instance.Name
+		shared.Name = "multi-shared"
+		Eventually(komega.Object(shared)).Should(HaveField("Annotations", HaveKeyWithValue(constants.AnnFrom, nt.Name)))
+		Expect(shared.Data).To(HaveKeyWithValue("from", []byte("monitoring")))
+		Consistently(komega.Object(shared)).Should(HaveField("Data", HaveKeyWithValue("from", []byte("monitoring"))))
+
+		baseOnly := &corev1.Secret{}
+		baseOnly.Namespace = instance.Name
+		baseOnly.Name = "multi-base-only"
+		Eventually(komega.Object(baseOnly)).Should(HaveField("Annotations", HaveKeyWithValue(constants.AnnFrom, base.Name)))
+
+		By("removing the object from the template of higher precedence")
+		Expect(komega.Update(nt, func() {
+			nt.Spec.Resources = nil
+		})()).To(Succeed())
+		Eventually(komega.Object(shared)).Should(HaveField("Annotations", HaveKeyWithValue(constants.AnnFrom, base.Name)))
+		Expect(shared.Data).To(HaveKeyWithValue("from", []byte("base")))
+	})
+
 	It("should not delete resources in an independent namespace", func() {
 		secret := &corev1.Secret{}
 		secret.Namespace = "default"
@@ -325,6 +474,124 @@ var _ = Describe("Namespace controller", func() {
 		Consistently(komega.Get(secret), 1, 0.1).Should(Succeed())
 	})
 
+	It("should delete stale labels and annotations set by an update operation", func() {
+		Expect(accurateconfig.DefaultMutableFeatureGate.SetFromMap(map[string]bool{string(feature.PropagateMetaDeletion): true})).To(Succeed())
+		DeferCleanup(func() error {
+			return accurateconfig.DefaultMutableFeatureGate.SetFromMap(map[string]bool{string(feature.PropagateMetaDeletion): false})
+		})
+
+		root := &corev1.Namespace{}
+		root.Name = "stale-root"
+		root.Labels = map[string]string{
+			constants.LabelType: constants.NSTypeRoot,
+			"team":              "neco",
+		}
+		Expect(k8sClient.Create(ctx, root)).To(Succeed())
+
+		sub := &corev1.Namespace{}
+		sub.Name = "stale-sub"
+		sub.Labels = map[string]string{constants.LabelParent: root.Name}
+		Expect(k8sClient.Create(ctx, sub)).To(Succeed())
+		Eventually(komega.Object(sub)).Should(HaveField("Labels", HaveKeyWithValue("team", "neco")))
+
+		By("setting labels as Accurate and as another manager")
+		patch := []byte(`{"metadata":{"labels":{"legacy.glob/a":"accurate"},"annotations":{"memo":"accurate"}}}`)
+		Expect(k8sClient.Patch(ctx, sub, client.RawPatch(types.MergePatchType, patch), fieldOwner)).To(Succeed())
+		patch = []byte(`{"metadata":{"labels":{"user.glob/a":"user"}}}`)
+		Expect(k8sClient.Patch(ctx, sub, client.RawPatch(types.MergePatchType, patch))).To(Succeed())
+
+		Eventually(komega.Object(sub)).Should(HaveField("Labels", Not(HaveKey("legacy.glob/a"))))
+		Expect(sub.Annotations).NotTo(HaveKey("memo"))
+		Expect(sub.Labels).To(HaveKeyWithValue("user.glob/a", "user"))
+		Expect(sub.Labels).To(HaveKeyWithValue("team", "neco"))
+	})
+
+	It("should propagate the keys of RootNamespace in its tree", func() {
+		root := &corev1.Namespace{}
+		root.Name = "rootkeys-root"
+		root.Labels = map[string]string{
+			constants.LabelType:  constants.NSTypeRoot,
+			"tree.example.com/a": "a",
+		}
+		root.Annotations = map[string]string{"tree.example.com/b": "b"}
+		Expect(k8sClient.Create(ctx, root)).To(Succeed())
+
+		sub := &corev1.Namespace{}
+		sub.Name = "rootkeys-sub"
+		sub.Labels = map[string]string{constants.LabelParent: root.Name}
+		Expect(k8sClient.Create(ctx, sub)).To(Succeed())
+
+		subsub := &corev1.Namespace{}
+		subsub.Name = "rootkeys-sub-sub"
+		subsub.Labels = map[string]string{constants.LabelParent: sub.Name}
+		Expect(k8sClient.Create(ctx, subsub)).To(Succeed())
+		Consistently(komega.Object(sub)).Should(HaveField("Labels", Not(HaveKey("tree.example.com/a"))))
+
+		rn := &accuratev2.RootNamespace{}
+		rn.Name = root.Name
+		rn.Spec.LabelKeys = []string{"tree.example.com/*"}
+		rn.Spec.AnnotationKeys = []string{"tree.example.com/b"}
+		Expect(k8sClient.Create(ctx, rn)).To(Succeed())
+
+		Eventually(komega.Object(subsub)).Should(HaveField("Labels", HaveKeyWithValue("tree.example.com/a", "a")))
+		Expect(subsub.Annotations).To(HaveKeyWithValue("tree.example.com/b", "b"))
+	})
+
+	It("should not propagate resources that a namespace opts out of", func() {
+		root := &corev1.Namespace{}
+		root.Name = "excl-root"
+		root.Labels = map[string]string{constants.LabelType: constants.NSTypeRoot}
+		Expect(k8sClient.Create(ctx, root)).To(Succeed())
+
+		for name, mode := range map[string]string{"sec-excl-c": constants.PropagateCreate, "sec-excl-u": constants.PropagateUpdate} {
+			sec := &corev1.Secret{}
+			sec.Namespace = root.Name
+			sec.Name = name
+			sec.Annotations = map[string]string{constants.AnnPropagate: mode}
+			sec.Data = map[string][]byte{"foo": []byte("bar")}
+			Expect(k8sClient.Create(ctx, sec)).To(Succeed())
+		}
+
+		sub1 := &corev1.Namespace{}
+		sub1.Name = "excl-sub1"
+		sub1.Labels = map[string]string{constants.LabelParent: root.Name}
+		Expect(k8sClient.Create(ctx, sub1)).To(Succeed())
+
+		for _, name := range []string{"sec-excl-c", "sec-excl-u"} {
+			sec := &corev1.Secret{}
+			sec.Namespace = sub1.Name
+			sec.Name = name
+			Eventually(komega.Get(sec)).Should(Succeed())
+		}
+
+		By("opting out of the resources")
+		Expect(komega.Update(sub1, func() {
+			sub1.Annotations = map[string]string{
+				constants.AnnPropagateExclude: "secrets/sec-excl-c, secrets/sec-excl-u",
+			}
+		})()).To(Succeed())
+
+		for _, name := range []string{"sec-excl-c", "sec-excl-u"} {
+			sec := &corev1.Secret{}
+			sec.Namespace = sub1.Name
+			sec.Name = name
+			Eventually(komega.Get(sec)).Should(WithTransform(apierrors.IsNotFound, BeTrue()))
+		}
+
+		By("creating a grandchild namespace")
+		sub2 := &corev1.Namespace{}
+		sub2.Name = "excl-sub2"
+		sub2.Labels = map[string]string{constants.LabelParent: sub1.Name}
+		Expect(k8sClient.Create(ctx, sub2)).To(Succeed())
+
+		for _, name := range []string{"sec-excl-c", "sec-excl-u"} {
+			sec := &corev1.Secret{}
+			sec.Namespace = sub2.Name
+			sec.Name = name
+			Consistently(komega.Get(sec)).Should(WithTransform(apierrors.IsNotFound, BeTrue()))
+		}
+	})
+
 	It("should implement a sub namespace correctly", func() {
 		root := &corev1.Namespace{}
 		root.Name = "root"
@@ -0,0 +1,159 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"slices"
+
+	accuratev2 "github.com/cybozu-go/accurate/api/accurate/v2"
+	"github.com/cybozu-go/accurate/pkg/constants"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+//+kubebuilder:rbac:groups=accurate.cybozu.com,resources=namespacetemplates,verbs=get;list;watch
+//+kubebuilder:rbac:groups=accurate.cybozu.com,resources=namespacetemplates/status,verbs=get;update;patch
+
+// virtualSources returns the objects of kind `gvk` in `nt` propagated in `mode`.
+// They are sources as if they were in a namespace named after `nt`,
+// so their copies are annotated with `accurate.cybozu.com/from=<nt.Name>`.
+func virtualSources(nt *accuratev2.NamespaceTemplate, gvk schema.GroupVersionKind, mode string) ([]unstructured.Unstructured, error) {
+	var sources []unstructured.Unstructured
+	for i, res := range nt.Spec.Resources {
+		resMode := res.Mode
+		if resMode == "" {
+			resMode = constants.PropagateUpdate
+		}
+		if resMode != mode {
+			continue
+		}
+
+		obj := unstructured.Unstructured{}
+		if err := obj.UnmarshalJSON(res.Manifest.Raw); err != nil {
+			return nil, fmt.Errorf("invalid manifest at spec.resources[%d] of NamespaceTemplate %s: %w", i, nt.Name, err)
+		}
+		if obj.GroupVersionKind() != gvk {
+			continue
+		}
+		obj.SetNamespace(nt.Name)
+		ann := obj.GetAnnotations()
+		if ann == nil {
+			ann = make(map[string]string)
+		}
+		ann[constants.AnnPropagate] = resMode
+		obj.SetAnnotations(ann)
+		sources = append(sources, obj)
+	}
+	return sources, nil
+}
+
+// isVirtualSource returns true if `res` is made from a NamespaceTemplate.
+// Objects read from the API server always have UIDs, while virtual sources do not.
+func isVirtualSource(res *unstructured.Unstructured) bool {
+	return res.GetUID() == ""
+}
+
+// getVirtualSource returns the object `name` of kind `gvk` in NamespaceTemplate `tmpl` as a source,
+// or nil if there is no such object.
+// A template namespace of the same name takes precedence over NamespaceTemplate.
+func getVirtualSource(ctx context.Context, c client.Reader, gvk schema.GroupVersionKind, tmpl, name string) (*unstructured.Unstructured, error) {
+	nt, err := getNamespaceTemplate(ctx, c, tmpl)
+	if nt == nil || err != nil {
+		return nil, err
+	}
+	for _, mode := range []string{constants.PropagateCreate, constants.PropagateUpdate, constants.PropagateMerge} {
+		sources, err := virtualSources(nt, gvk, mode)
+		if err != nil {
+			return nil, err
+		}
+		for i := range sources {
+			if sources[i].GetName() == name {
+				return &sources[i], nil
+			}
+		}
+	}
+	return nil, nil
+}
+
+// getNamespaceTemplate returns NamespaceTemplate `name`, or nil if it does not exist
+// or a template namespace of the same name exists.
+func getNamespaceTemplate(ctx context.Context, c client.Reader, name string) (*accuratev2.NamespaceTemplate, error) {
+	ns := &corev1.Namespace{}
+	err := c.Get(ctx, client.ObjectKey{Name: name}, ns)
+	if err == nil {
+		return nil, nil
+	}
+	if !apierrors.IsNotFound(err) {
+		return nil, fmt.Errorf("failed to get namespace %s: %w", name, err)
+	}
+
+	nt := &accuratev2.NamespaceTemplate{}
+	if err := c.Get(ctx, client.ObjectKey{Name: name}, nt); err != nil {
+		if apierrors.IsNotFound(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get NamespaceTemplate %s: %w", name, err)
+	}
+	return nt, nil
+}
+
+// eventRegarding returns the object that events about `src` are recorded for.
+// Events about a virtual source are recorded for its NamespaceTemplate, since the source does not exist.
+func eventRegarding(ctx context.Context, c client.Reader, src *unstructured.Unstructured) runtime.Object {
+	if !isVirtualSource(src) {
+		return src
+	}
+	nt := &accuratev2.NamespaceTemplate{}
+	if err := c.Get(ctx, client.ObjectKey{Name: src.GetNamespace()}, nt); err != nil {
+		log.FromContext(ctx).Error(err, "failed to get NamespaceTemplate to record events", "name", src.GetNamespace())
+		return src
+	}
+	return nt
+}
+
+// templateConflictOf returns the entry of status.conflicts of NamespaceTemplate for `existing`.
+func templateConflictOf(existing *unstructured.Unstructured) accuratev2.NamespaceTemplateConflict {
+	return accuratev2.NamespaceTemplateConflict{
+		APIVersion: existing.GetAPIVersion(),
+		Kind:       existing.GetKind(),
+		Namespace:  existing.GetNamespace(),
+		Name:       existing.GetName(),
+	}
+}
+
+// templateConflictRecorded returns true if `conflict` is in status.conflicts of NamespaceTemplate `tmpl`.
+func templateConflictRecorded(ctx context.Context, c client.Reader, tmpl string, conflict accuratev2.NamespaceTemplateConflict) (bool, error) {
+	nt := &accuratev2.NamespaceTemplate{}
+	if err := c.Get(ctx, client.ObjectKey{Name: tmpl}, nt); err != nil {
+		return false, client.IgnoreNotFound(err)
+	}
+	return slices.Contains(nt.Status.Conflicts, conflict), nil
+}
+
+// setTemplateConflict adds `conflict` to status.conflicts of NamespaceTemplate `tmpl` if `add` is true,
+// or removes it otherwise.
+func setTemplateConflict(ctx context.Context, c client.Client, tmpl string, conflict accuratev2.NamespaceTemplateConflict, add bool) error {
+	nt := &accuratev2.NamespaceTemplate{}
+	if err := c.Get(ctx, client.ObjectKey{Name: tmpl}, nt); err != nil {
+		return client.IgnoreNotFound(err)
+	}
+	if slices.Contains(nt.Status.Conflicts, conflict) == add {
+		return nil
+	}
+
+	orig := nt.DeepCopy()
+	if add {
+		nt.Status.Conflicts = append(nt.Status.Conflicts, conflict)
+	} else {
+		nt.Status.Conflicts = slices.DeleteFunc(nt.Status.Conflicts, func(c accuratev2.NamespaceTemplateConflict) bool { return c == conflict })
+	}
+	if err := c.Status().Patch(ctx, nt, client.MergeFrom(orig)); err != nil {
+		return fmt.Errorf("failed to record conflicts of NamespaceTemplate %s: %w", tmpl, err)
+	}
+	return nil
+}
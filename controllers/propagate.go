@@ -2,23 +2,34 @@ package controllers
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"slices"
+	"strings"
+	"sync"
+	"time"
 
 	utilerrors "github.com/cybozu-go/accurate/internal/util/errors"
+	"github.com/cybozu-go/accurate/internal/util/templates"
 	"github.com/cybozu-go/accurate/pkg/config"
 	"github.com/cybozu-go/accurate/pkg/constants"
 	"github.com/cybozu-go/accurate/pkg/feature"
 	corev1 "k8s.io/api/core/v1"
-	"k8s.io/apimachinery/pkg/api/equality"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/events"
+	"k8s.io/utils/ptr"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
 	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
 	"sigs.k8s.io/controller-runtime/pkg/log"
 	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/source"
 )
 
 // Deprecated: Part of the deprecated propagate-generated feature subject for
@@ -28,6 +39,14 @@ const notGenerated = "false"
 type ResourceCloner struct {
 	LabelKeyExcludes      []string
 	AnnotationKeyExcludes []string
+
+	// TemplateLabelKeys and TemplateAnnotationKeys select the namespace
+	// labels and annotations that can be referenced from templates.
+	TemplateLabelKeys      []string
+	TemplateAnnotationKeys []string
+
+	// Watches are the options of watched resources.
+	Watches map[schema.GroupVersionKind]config.Watch
 }
 
 func (rc *ResourceCloner) cloneResource(res *unstructured.Unstructured, ns string) *unstructured.Unstructured {
@@ -36,9 +55,10 @@ func (rc *ResourceCloner) cloneResource(res *unstructured.Unstructured, ns strin
 	delete(c.Object, "status")
 	c.SetNamespace(ns)
 	c.SetName(res.GetName())
+	w := rc.Watches[res.GroupVersionKind()]
 	labels := make(map[string]string)
 	for k, v := range res.GetLabels() {
-		if matchKey(k, rc.LabelKeyExcludes) {
+		if matchKey(k, rc.LabelKeyExcludes) || matchKey(k, w.LabelKeyExcludes) {
 			continue
 		}
 		labels[k] = v
@@ -47,28 +67,82 @@ func (rc *ResourceCloner) cloneResource(res *unstructured.Unstructured, ns strin
 	c.SetLabels(labels)
 	annotations := make(map[string]string)
 	for k, v := range res.GetAnnotations() {
-		if matchKey(k, rc.AnnotationKeyExcludes) {
+		if matchKey(k, rc.AnnotationKeyExcludes) || matchKey(k, w.AnnotationKeyExcludes) {
 			continue
 		}
 		annotations[k] = v
 	}
+	delete(annotations, constants.AnnConflicts)
 	annotations[constants.AnnFrom] = res.GetNamespace()
+	setRemainingDepth(annotations, res)
 	c.SetAnnotations(annotations)
 
-	// special treatment for ServiceAccount
-	if c.GetAPIVersion() == "v1" && c.GetKind() == "ServiceAccount" {
-		delete(c.Object, "secrets")
+	stripFields := w.StripFields
+	if stripFields == nil {
+		stripFields = defaultStripFields[res.GroupVersionKind()]
+	}
+	for _, p := range stripFields {
+		stripField(c.Object, config.StripFieldPath(p))
 	}
 
 	return c
 }
 
+// defaultStripFields are the fields removed from copies of resources whose StripFields are not set.
+var defaultStripFields = map[schema.GroupVersionKind][]string{
+	// Kubernetes 1.23 or lower may have added token Secrets that are specific to the namespace.
+	{Version: "v1", Kind: "ServiceAccount"}: {"secrets"},
+}
+
+// stripField removes the field at `path` from `obj`.
+// If a value on the path is a list, the rest of the path is removed from every item.
+func stripField(obj map[string]any, path []string) {
+	if len(path) == 1 {
+		delete(obj, path[0])
+		return
+	}
+
+	switch v := obj[path[0]].(type) {
+	case map[string]any:
+		stripField(v, path[1:])
+	case []any:
+		for _, item := range v {
+			if m, ok := item.(map[string]any); ok {
+				stripField(m, path[1:])
+			}
+		}
+	}
+}
+
+// errModeNotAllowed is returned when a resource is propagated in a mode not allowed for it.
+var errModeNotAllowed = errors.New("propagation mode not allowed")
+
+// modeAllowed returns true if `res` can be propagated in `mode`.
+func (rc *ResourceCloner) modeAllowed(res *unstructured.Unstructured, mode string) bool {
+	modes := rc.Watches[res.GroupVersionKind()].Modes
+	return len(modes) == 0 || slices.Contains(modes, mode)
+}
+
 // PropagateController propagates objects of a namespace-scoped resource.
 type PropagateController struct {
 	client.Client
 	ResourceCloner
-	reader client.Reader
-	res    *unstructured.Unstructured
+	reader   client.Reader
+	recorder events.EventRecorder
+	res      *unstructured.Unstructured
+
+	// mu protects ResourceCloner from being replaced during reconciliation.
+	mu sync.RWMutex
+
+	// reported holds the values of invalid annotations reported by events,
+	// so that they are reported again only when they change.
+	reported sync.Map
+}
+
+// invalidAnnotation is the key of PropagateController.reported.
+type invalidAnnotation struct {
+	key    types.NamespacedName
+	reason string
 }
 
 // NewPropagateController creates a new PropagateController.
@@ -86,6 +160,8 @@ func NewPropagateController(res *unstructured.Unstructured, cloner ResourceClone
 // Reconcile implements reconcile.Reconciler interface.
 func (r *PropagateController) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
 	logger := log.FromContext(ctx)
+	r.mu.RLock()
+	defer r.mu.RUnlock()
 	logger.V(5).Info("reconciling")
 
 	obj := r.res.DeepCopy()
@@ -93,6 +169,8 @@ func (r *PropagateController) Reconcile(ctx context.Context, req ctrl.Request) (
 		if !apierrors.IsNotFound(err) {
 			return ctrl.Result{}, err
 		}
+		r.reported.Delete(invalidAnnotation{key: req.NamespacedName, reason: reasonInvalidDepth})
+		r.reported.Delete(invalidAnnotation{key: req.NamespacedName, reason: reasonInvalidSelector})
 		if err := r.handleDelete(ctx, req); err != nil {
 			logger.Error(err, "failed to handle deleted object")
 			return ctrl.Result{}, err
@@ -106,24 +184,59 @@ func (r *PropagateController) Reconcile(ctx context.Context, req ctrl.Request) (
 
 	ann := obj.GetAnnotations()
 	if from := ann[constants.AnnFrom]; from != "" {
-		p := r.res.DeepCopy()
-		if err := r.Get(ctx, client.ObjectKey{Namespace: from, Name: req.Name}, p); err != nil {
-			if !apierrors.IsNotFound(err) {
-				return ctrl.Result{}, fmt.Errorf("failed to lookup the parent resource in %s: %w", from, err)
-			}
-
-			if ann[constants.AnnPropagate] == constants.PropagateUpdate {
-				if err := r.Delete(ctx, obj); err != nil {
+		p, err := r.getSource(ctx, req, from)
+		if err != nil {
+			return ctrl.Result{}, err
+		}
+		if p == nil {
+			if followsSource(ann[constants.AnnPropagate]) {
+				if err := deleteCopy(ctx, r.Client, obj); err != nil {
 					logger.Error(err, "failed to delete")
 					return ctrl.Result{}, err
 				}
 				logger.Info("deleted")
+				r.recorder.Eventf(obj, nil, corev1.EventTypeNormal, reasonDeleted, actionDelete,
+					"deleted since the source in %s was deleted", from)
 				return ctrl.Result{}, nil
 			}
 		} else {
-			if p.GetAnnotations()[constants.AnnPropagate] == constants.PropagateUpdate {
+			ns := &corev1.Namespace{}
+			if err := r.Get(ctx, client.ObjectKey{Name: req.Namespace}, ns); err != nil {
+				return ctrl.Result{}, fmt.Errorf("failed to get namespace %s: %w", req.Namespace, err)
+			}
+			excluded, err := isExcluded(r.Client, obj, ns)
+			if err != nil {
+				return ctrl.Result{}, err
+			}
+			if excluded {
+				if err := deleteCopy(ctx, r.Client, obj); err != nil {
+					logger.Error(err, "failed to delete")
+					return ctrl.Result{}, err
+				}
+				logger.Info("deleted", "reason", "opted out")
+				r.recorder.Eventf(obj, nil, corev1.EventTypeNormal, reasonDeleted, actionDelete,
+					"deleted since namespace %s opts out of it", req.Namespace)
+				return ctrl.Result{}, nil
+			}
+
+			if mode := p.GetAnnotations()[constants.AnnPropagate]; followsSource(mode) {
+				if validatePropagation(p) != nil {
+					// keep the copy until the annotations of the source are fixed
+					return ctrl.Result{}, nil
+				}
+				if !propagatesTo(p, ns) {
+					// the parent resource is no longer propagated to this namespace
+					if err := deleteCopy(ctx, r.Client, obj); err != nil {
+						logger.Error(err, "failed to delete")
+						return ctrl.Result{}, err
+					}
+					logger.Info("deleted", "reason", "excluded")
+					r.recorder.Eventf(obj, p, corev1.EventTypeNormal, reasonDeleted, actionDelete,
+						"deleted since the source in %s is no longer propagated to this namespace", from)
+					return ctrl.Result{}, nil
+				}
 				if err := r.propagateUpdate(ctx, obj, p); err != nil {
-					logger.Error(err, "failed to propagate an object", "mode", "update", "parent", "exist")
+					logger.Error(err, "failed to propagate an object", "mode", mode, "parent", "exist")
 					return ctrl.Result{}, err
 				}
 				return ctrl.Result{}, nil
@@ -131,15 +244,24 @@ func (r *PropagateController) Reconcile(ctx context.Context, req ctrl.Request) (
 		}
 	}
 
+	_, _, err := remainingDepth(obj)
+	r.reportInvalid(obj, reasonInvalidDepth, constants.AnnPropagateDepth, err)
+	_, err = namespaceSelector(obj)
+	r.reportInvalid(obj, reasonInvalidSelector, constants.AnnPropagateNamespaceSelector, err)
+	if validatePropagation(obj) != nil {
+		// the existing copies are kept until the annotations are fixed
+		return ctrl.Result{}, nil
+	}
+
 	switch ann[constants.AnnPropagate] {
 	case constants.PropagateCreate:
 		if err := r.propagateCreate(ctx, obj); err != nil {
 			logger.Error(err, "failed to propagate an object", "mode", "create")
 			return ctrl.Result{}, err
 		}
-	case constants.PropagateUpdate:
+	case constants.PropagateUpdate, constants.PropagateMerge:
 		if err := r.propagateUpdate(ctx, obj, nil); err != nil {
-			logger.Error(err, "failed to propagate an object", "mode", "update", "parent", "none")
+			logger.Error(err, "failed to propagate an object", "mode", ann[constants.AnnPropagate], "parent", "none")
 			return ctrl.Result{}, err
 		}
 	case "":
@@ -155,6 +277,98 @@ func (r *PropagateController) Reconcile(ctx context.Context, req ctrl.Request) (
 	return ctrl.Result{}, nil
 }
 
+// reportInvalid records a Warning event of `reason` if `err` is not nil and
+// the value of `annotation` of `obj` has not been reported yet.
+func (r *PropagateController) reportInvalid(obj *unstructured.Unstructured, reason, annotation string, err error) {
+	k := invalidAnnotation{key: client.ObjectKeyFromObject(obj), reason: reason}
+	if err == nil {
+		r.reported.Delete(k)
+		return
+	}
+	v := obj.GetAnnotations()[annotation]
+	if prev, ok := r.reported.Load(k); ok && prev == v {
+		return
+	}
+	r.reported.Store(k, v)
+	r.recorder.Eventf(obj, nil, corev1.EventTypeWarning, reason, actionPropagate, "%v", err)
+}
+
+// getSource returns the source of the copy `req` in namespace `from`, or nil if it does not exist.
+// If the copy is in an instance namespace of NamespaceTemplate `from`, the source is taken from it.
+// The source in a template that another template of higher precedence overrides is not returned.
+func (r *PropagateController) getSource(ctx context.Context, req ctrl.Request, from string) (*unstructured.Unstructured, error) {
+	p := r.res.DeepCopy()
+	err := r.Get(ctx, client.ObjectKey{Namespace: from, Name: req.Name}, p)
+	if err != nil && !apierrors.IsNotFound(err) {
+		return nil, fmt.Errorf("failed to lookup the parent resource in %s: %w", from, err)
+	}
+	found := err == nil
+
+	ns := &corev1.Namespace{}
+	if err := r.Get(ctx, client.ObjectKey{Name: req.Namespace}, ns); err != nil {
+		if apierrors.IsNotFound(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get namespace %s: %w", req.Namespace, err)
+	}
+	tmpls := templates.Names(ns)
+	if ns.Labels[constants.LabelParent] != "" || !slices.Contains(tmpls, from) {
+		if found {
+			return p, nil
+		}
+		return nil, nil
+	}
+
+	shadowed, err := r.shadowed(ctx, ns, from, req.Name)
+	if shadowed || err != nil {
+		return nil, err
+	}
+	if found {
+		return p, nil
+	}
+	return getVirtualSource(ctx, r.Client, r.res.GroupVersionKind(), from, req.Name)
+}
+
+// lookupSource returns the object `name` in the namespace or NamespaceTemplate `tmpl` if it is propagated,
+// or nil if there is no such object.
+func (r *PropagateController) lookupSource(ctx context.Context, tmpl, name string) (*unstructured.Unstructured, error) {
+	obj := r.res.DeepCopy()
+	err := r.Get(ctx, client.ObjectKey{Namespace: tmpl, Name: name}, obj)
+	if err == nil {
+		if obj.GetAnnotations()[constants.AnnPropagate] == "" {
+			return nil, nil
+		}
+		return obj, nil
+	}
+	if !apierrors.IsNotFound(err) {
+		return nil, fmt.Errorf("failed to get %s/%s: %w", tmpl, name, err)
+	}
+	return getVirtualSource(ctx, r.Client, r.res.GroupVersionKind(), tmpl, name)
+}
+
+// shadowed returns true if a template of `ns` taking precedence over template `from`
+// provides an object named `name`, so that the object in `from` is not propagated to `ns`.
+func (r *PropagateController) shadowed(ctx context.Context, ns *corev1.Namespace, from, name string) (bool, error) {
+	if ns.Labels[constants.LabelParent] != "" {
+		return false, nil
+	}
+	tmpls := templates.Names(ns)
+	i := slices.Index(tmpls, from)
+	if i < 0 {
+		return false, nil
+	}
+	for _, tmpl := range tmpls[i+1:] {
+		obj, err := r.lookupSource(ctx, tmpl, name)
+		if err != nil {
+			return false, err
+		}
+		if obj != nil {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
 func (r *PropagateController) getChildren(ctx context.Context, name string) (*corev1.NamespaceList, error) {
 	ns := &corev1.Namespace{}
 	if err := r.Get(ctx, client.ObjectKey{Name: name}, ns); err != nil {
@@ -184,86 +398,190 @@ func (r *PropagateController) handleDelete(ctx context.Context, req ctrl.Request
 	}
 
 	// re-create it if there is a parent resource
-	p, ok := ns.Labels[constants.LabelParent]
-	if !ok {
-		p = ns.Labels[constants.LabelTemplate]
-	}
-	if p != "" {
-		parent := &corev1.Namespace{}
-		if err := r.Get(ctx, client.ObjectKey{Name: p}, parent); err != nil {
-			kind := "parent"
-			if !ok {
-				kind = "template"
+	var obj *unstructured.Unstructured
+	var p string
+	if parent, ok := ns.Labels[constants.LabelParent]; ok {
+		if parent != "" {
+			if err := r.Get(ctx, client.ObjectKey{Name: parent}, &corev1.Namespace{}); err != nil {
+				return fmt.Errorf("failed to get parent namespace %s: %w", parent, err)
+			}
+			obj = r.res.DeepCopy()
+			if err := r.Get(ctx, client.ObjectKey{Namespace: parent, Name: req.Name}, obj); err != nil {
+				if !apierrors.IsNotFound(err) {
+					return fmt.Errorf("failed to get %s/%s: %w", parent, req.Name, err)
+				}
+				obj = nil
+			}
+			p = parent
+		}
+	} else {
+		// the template of the highest precedence having the object is used.
+		tmpls := templates.Names(ns)
+		for i := len(tmpls) - 1; i >= 0; i-- {
+			src, err := r.lookupSource(ctx, tmpls[i], req.Name)
+			if err != nil {
+				return err
+			}
+			if src != nil {
+				obj = src
+				p = tmpls[i]
+				break
 			}
-			return fmt.Errorf("failed to get %s namespace %s: %w", kind, p, err)
+		}
+	}
+	if obj != nil && validatePropagation(obj) != nil {
+		// the copies are not changed until the annotations of the source are fixed
+		return nil
+	}
+	if obj != nil && propagatesTo(obj, ns) {
+		excluded, err := isExcluded(r.Client, obj, ns)
+		if err != nil {
+			return err
 		}
 
-		obj := r.res.DeepCopy()
-		if err := r.Get(ctx, client.ObjectKey{Namespace: p, Name: req.Name}, obj); err != nil {
-			if !apierrors.IsNotFound(err) {
-				return fmt.Errorf("failed to get %s/%s: %w", p, req.Name, err)
+		mode := obj.GetAnnotations()[constants.AnnPropagate]
+		switch {
+		case excluded:
+			// the namespace has opted out of the resource
+		case followsSource(mode), mode == constants.PropagateCreate:
+			clone, err := r.cloneAndRender(ctx, r.Client, obj, req.Namespace)
+			if err != nil {
+				r.renderFailed(ctx, obj, req.Namespace, err)
+				return nil
 			}
-		} else {
-			switch obj.GetAnnotations()[constants.AnnPropagate] {
-			case constants.PropagateCreate, constants.PropagateUpdate:
-				if err := r.Create(ctx, r.cloneResource(obj, req.Namespace)); err != nil {
-					if utilerrors.IsNamespaceTerminating(err) {
-						return nil
-					}
-					return fmt.Errorf("failed to re-create %s/%s: %w", req.Namespace, req.Name, err)
+			if err := createCopy(ctx, r.Client, clone, mode); err != nil {
+				if utilerrors.IsNamespaceTerminating(err) {
+					return nil
 				}
-				logger.Info("re-created", "from", fmt.Sprintf("%s/%s", p, req.Name))
-				return nil
+				return fmt.Errorf("failed to re-create %s/%s: %w", req.Namespace, req.Name, err)
 			}
+			logger.Info("re-created", "from", fmt.Sprintf("%s/%s", p, req.Name))
+			r.recorder.Eventf(clone, obj, corev1.EventTypeNormal, reasonCreated, actionPropagate,
+				"re-created from %s/%s", p, req.Name)
+			return nil
 		}
 	}
 
 	// delete propagated resources in child namespaces
-	children, err := r.getChildren(ctx, req.Namespace)
+	return r.deleteChildResources(ctx, req.Namespace, req.Name)
+}
+
+func (r *PropagateController) deleteChildResources(ctx context.Context, ns, name string) error {
+	children, err := r.getChildren(ctx, ns)
 	if err != nil {
 		return err
 	}
 	for _, child := range children.Items {
-		obj := r.res.DeepCopy()
-		if err := r.Get(ctx, client.ObjectKey{Namespace: child.Name, Name: req.Name}, obj); err != nil {
-			if apierrors.IsNotFound(err) {
-				continue
-			}
-			return fmt.Errorf("failed to look up %s/%s: %w", child.Name, req.Name, err)
+		if err := r.deleteChildResource(ctx, child.Name, name, ns); err != nil {
+			return err
 		}
+	}
 
-		if obj.GetAnnotations()[constants.AnnPropagate] != constants.PropagateUpdate {
-			continue
-		}
+	return nil
+}
 
-		if err := r.Delete(ctx, obj); err != nil {
-			return fmt.Errorf("failed to cascade delete %s/%s: %w", child.Name, req.Name, err)
+// deleteChildResource deletes the copy `name` from namespace `from` in namespace `ns`.
+func (r *PropagateController) deleteChildResource(ctx context.Context, ns, name, from string) error {
+	logger := log.FromContext(ctx)
+	obj := r.res.DeepCopy()
+	if err := r.Get(ctx, client.ObjectKey{Namespace: ns, Name: name}, obj); err != nil {
+		if apierrors.IsNotFound(err) {
+			return nil
 		}
-		logger.Info("deleted a child resource", "subnamespace", child.Name)
+		return fmt.Errorf("failed to look up %s/%s: %w", ns, name, err)
+	}
+
+	if obj.GetAnnotations()[constants.AnnFrom] != from || !followsSource(obj.GetAnnotations()[constants.AnnPropagate]) {
+		return nil
 	}
 
+	if err := deleteCopy(ctx, r.Client, obj); err != nil {
+		return fmt.Errorf("failed to cascade delete %s/%s: %w", ns, name, err)
+	}
+	logger.Info("deleted a child resource", "subnamespace", ns)
+	r.recorder.Eventf(obj, nil, corev1.EventTypeNormal, reasonDeleted, actionDelete,
+		"deleted since the source in %s is deleted or no longer propagated to this namespace", obj.GetAnnotations()[constants.AnnFrom])
 	return nil
 }
 
 func (r *PropagateController) propagateCreate(ctx context.Context, obj *unstructured.Unstructured) error {
 	logger := log.FromContext(ctx)
+	if !canPropagate(obj) {
+		return setConflicts(ctx, r.Client, obj, nil, false)
+	}
+
 	children, err := r.getChildren(ctx, obj.GetNamespace())
 	if err != nil {
 		return err
 	}
 
 	name := obj.GetName()
+	var conflicts []string
+	var changed bool
+	// since is the latest time when the copies were up to date, used to observe the propagation latency.
+	var since time.Time
 	for _, child := range children.Items {
+		if !propagatesTo(obj, &child) {
+			continue
+		}
+		excluded, err := isExcluded(r.Client, obj, &child)
+		if err != nil {
+			return err
+		}
+		if excluded {
+			continue
+		}
+		shadowed, err := r.shadowed(ctx, &child, obj.GetNamespace(), name)
+		if err != nil {
+			return err
+		}
+		if shadowed {
+			continue
+		}
+
 		cres := r.res.DeepCopy()
-		err := r.Get(ctx, client.ObjectKey{Namespace: child.Name, Name: name}, cres)
+		err = r.Get(ctx, client.ObjectKey{Namespace: child.Name, Name: name}, cres)
 		if err == nil {
+			if !isConflict(cres) {
+				since = latest(since, lastApplied(cres))
+				continue
+			}
+			overwrite, err := r.resolveConflict(r.recorder, obj, cres, obj, conflictRecorded(obj, cres.GetNamespace()))
+			if !overwrite {
+				conflicts = append(conflicts, child.Name)
+			}
+			if err != nil {
+				return errors.Join(err, setConflicts(ctx, r.Client, obj, conflicts, true))
+			}
+			if !overwrite {
+				continue
+			}
+
+			clone, err := r.cloneAndRender(ctx, r.Client, obj, child.Name)
+			if err != nil {
+				r.renderFailed(ctx, obj, child.Name, err)
+				continue
+			}
+			if _, err := applyCopy(ctx, r.Client, clone, cres, constants.PropagateUpdate); err != nil {
+				return err
+			}
+			logger.Info("overwrote a conflicting child resource", "subnamespace", child.Name)
+			r.recorder.Eventf(clone, obj, corev1.EventTypeNormal, reasonUpdated, actionPropagate,
+				"overwrote a conflicting object with a copy of %s/%s", obj.GetNamespace(), name)
+			changed = true
 			continue
 		}
 		if !apierrors.IsNotFound(err) {
 			return fmt.Errorf("failed to look up %s/%s: %w", child.Name, name, err)
 		}
 
-		if err := r.Create(ctx, r.cloneResource(obj, child.Name)); err != nil {
+		since = latest(since, child.CreationTimestamp.Time)
+		clone, err := r.cloneAndRender(ctx, r.Client, obj, child.Name)
+		if err != nil {
+			r.renderFailed(ctx, obj, child.Name, err)
+			continue
+		}
+		if err := createCopy(ctx, r.Client, clone, constants.PropagateCreate); err != nil {
 			if utilerrors.IsNamespaceTerminating(err) {
 				return nil
 			}
@@ -271,9 +589,15 @@ func (r *PropagateController) propagateCreate(ctx context.Context, obj *unstruct
 		}
 
 		logger.Info("created a child resource", "subnamespace", child.Name)
+		r.recorder.Eventf(clone, obj, corev1.EventTypeNormal, reasonCreated, actionPropagate,
+			"created from %s/%s", obj.GetNamespace(), name)
+		changed = true
 	}
 
-	return nil
+	if changed {
+		observePropagation(obj, since)
+	}
+	return setConflicts(ctx, r.Client, obj, conflicts, false)
 }
 
 func (r *PropagateController) propagateUpdate(ctx context.Context, obj, parent *unstructured.Unstructured) error {
@@ -281,34 +605,80 @@ func (r *PropagateController) propagateUpdate(ctx context.Context, obj, parent *
 	name := obj.GetName()
 
 	if parent != nil {
-		clone := r.cloneResource(parent, obj.GetNamespace())
+		clone, err := r.cloneAndRender(ctx, r.Client, parent, obj.GetNamespace())
+		if err != nil {
+			r.renderFailed(ctx, parent, obj.GetNamespace(), err)
+			return nil
+		}
 
-		if !equality.Semantic.DeepDerivative(clone, obj) {
-			ac := client.ApplyConfigurationFromUnstructured(clone)
-			if err := r.Apply(ctx, ac, fieldOwner, client.ForceOwnership); err != nil {
-				return fmt.Errorf("failed to apply %s/%s: %w", clone.GetNamespace(), clone.GetName(), err)
-			}
+		applied, err := applyCopy(ctx, r.Client, clone, obj, parent.GetAnnotations()[constants.AnnPropagate])
+		if err != nil {
+			return err
+		}
+		if applied {
 			logger.Info("applied", "from", parent.GetNamespace())
+			r.recorder.Eventf(obj, parent, corev1.EventTypeNormal, reasonUpdated, actionPropagate,
+				"updated from %s/%s", parent.GetNamespace(), name)
 			return nil
 		}
 	}
 
+	// delete copies in child namespaces beyond the depth limit.
+	if !canPropagate(obj) {
+		if err := r.deleteChildResources(ctx, obj.GetNamespace(), name); err != nil {
+			return err
+		}
+		return setConflicts(ctx, r.Client, obj, nil, false)
+	}
+
+	mode := obj.GetAnnotations()[constants.AnnPropagate]
+
 	// propagate to child namespaces, if any.
 	children, err := r.getChildren(ctx, obj.GetNamespace())
 	if err != nil {
 		return err
 	}
 
+	var conflicts []string
+	var changed bool
+	// since is the latest time when the copies were up to date, used to observe the propagation latency.
+	var since time.Time
 	for _, child := range children.Items {
+		if !propagatesTo(obj, &child) {
+			if err := r.deleteChildResource(ctx, child.Name, name, obj.GetNamespace()); err != nil {
+				return err
+			}
+			continue
+		}
+		excluded, err := isExcluded(r.Client, obj, &child)
+		if err != nil {
+			return err
+		}
+		if excluded {
+			continue
+		}
+		shadowed, err := r.shadowed(ctx, &child, obj.GetNamespace(), name)
+		if err != nil {
+			return err
+		}
+		if shadowed {
+			continue
+		}
+
 		cres := r.res.DeepCopy()
-		err := r.Get(ctx, client.ObjectKey{Namespace: child.Name, Name: name}, cres)
+		err = r.Get(ctx, client.ObjectKey{Namespace: child.Name, Name: name}, cres)
 		if err != nil {
 			if !apierrors.IsNotFound(err) {
 				return fmt.Errorf("failed to lookup %s/%s: %w", child.Name, name, err)
 			}
 
-			clone := r.cloneResource(obj, child.Name)
-			if err := r.Create(ctx, clone); err != nil {
+			since = latest(since, child.CreationTimestamp.Time)
+			clone, err := r.cloneAndRender(ctx, r.Client, obj, child.Name)
+			if err != nil {
+				r.renderFailed(ctx, obj, child.Name, err)
+				continue
+			}
+			if err := createCopy(ctx, r.Client, clone, mode); err != nil {
 				if utilerrors.IsNamespaceTerminating(err) {
 					return nil
 				}
@@ -316,23 +686,54 @@ func (r *PropagateController) propagateUpdate(ctx context.Context, obj, parent *
 			}
 
 			logger.Info("created a child resource", "subnamespace", child.Name)
+			r.recorder.Eventf(clone, obj, corev1.EventTypeNormal, reasonCreated, actionPropagate,
+				"created from %s/%s", obj.GetNamespace(), name)
+			changed = true
 			continue
 		}
 
-		clone := r.cloneResource(obj, child.Name)
+		if isConflict(cres) {
+			overwrite, err := r.resolveConflict(r.recorder, obj, cres, obj, conflictRecorded(obj, cres.GetNamespace()))
+			if !overwrite {
+				conflicts = append(conflicts, child.Name)
+			}
+			if err != nil {
+				return errors.Join(err, setConflicts(ctx, r.Client, obj, conflicts, true))
+			}
+			if !overwrite {
+				continue
+			}
+		} else {
+			since = latest(since, lastApplied(cres))
+		}
 
-		if equality.Semantic.DeepDerivative(clone, cres) {
+		clone, err := r.cloneAndRender(ctx, r.Client, obj, child.Name)
+		if err != nil {
+			r.renderFailed(ctx, obj, child.Name, err)
 			continue
 		}
 
-		ac := client.ApplyConfigurationFromUnstructured(clone)
-		if err := r.Apply(ctx, ac, fieldOwner, client.ForceOwnership); err != nil {
-			return fmt.Errorf("failed to apply %s/%s: %w", clone.GetNamespace(), clone.GetName(), err)
+		applied, err := applyCopy(ctx, r.Client, clone, cres, mode)
+		if err != nil {
+			return err
+		}
+		if !applied {
+			continue
 		}
 		logger.Info("applied a child resource", "subnamespace", child.Name)
+		r.recorder.Eventf(cres, obj, corev1.EventTypeNormal, reasonUpdated, actionPropagate,
+			"updated from %s/%s", obj.GetNamespace(), name)
+		changed = true
 	}
 
-	return nil
+	if changed {
+		observePropagation(obj, since)
+	}
+	return setConflicts(ctx, r.Client, obj, conflicts, false)
+}
+
+func (r *PropagateController) renderFailed(ctx context.Context, src *unstructured.Unstructured, ns string, err error) {
+	cloneFailed(r.recorder, eventRegarding(ctx, r.Client, src), src, ns, err)
 }
 
 // Deprecated: Part of the deprecated propagate-generated feature subject for
@@ -375,8 +776,14 @@ func (r *PropagateController) checkController(ctx context.Context, obj *unstruct
 	return nil
 }
 
-// SetupWithManager sets up the controller with the Manager.
-func (r *PropagateController) SetupWithManager(mgr ctrl.Manager) error {
+// setResourceCloner replaces the ResourceCloner of the running controller.
+func (r *PropagateController) setResourceCloner(cloner ResourceCloner) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.ResourceCloner = cloner
+}
+
+func (r *PropagateController) setup(mgr ctrl.Manager) predicate.Funcs {
 	pred := func(obj client.Object) bool {
 		ann := obj.GetAnnotations()
 		if _, ok := ann[constants.AnnFrom]; ok {
@@ -397,13 +804,53 @@ func (r *PropagateController) SetupWithManager(mgr ctrl.Manager) error {
 
 	r.Client = mgr.GetClient()
 	r.reader = mgr.GetAPIReader()
+	r.recorder = mgr.GetEventRecorder(eventSource)
+
+	return predicate.Funcs{
+		CreateFunc: func(e event.CreateEvent) bool { return pred(e.Object) },
+		UpdateFunc: func(e event.UpdateEvent) bool { return pred(e.ObjectOld) || pred(e.ObjectNew) },
+		DeleteFunc: func(e event.DeleteEvent) bool { return pred(e.Object) },
+	}
+}
+
+// SetupWithManager sets up the controller with the Manager.
+func (r *PropagateController) SetupWithManager(mgr ctrl.Manager) error {
+	pred := r.setup(mgr)
 
 	return ctrl.NewControllerManagedBy(mgr).
 		For(r.res).
-		WithEventFilter(predicate.Funcs{
-			CreateFunc: func(e event.CreateEvent) bool { return pred(e.Object) },
-			UpdateFunc: func(e event.UpdateEvent) bool { return pred(e.ObjectOld) || pred(e.ObjectNew) },
-			DeleteFunc: func(e event.DeleteEvent) bool { return pred(e.Object) },
-		}).
+		WithEventFilter(pred).
 		Complete(r)
 }
+
+// newUnmanagedController creates a controller that is not added to `mgr`,
+// so that it can be stopped while the manager is running.
+// If `copyHandler` is not nil, it also handles the events of the resource.
+func (r *PropagateController) newUnmanagedController(mgr ctrl.Manager, copyHandler handler.EventHandler) (controller.Controller, error) {
+	pred := r.setup(mgr)
+
+	gvk := r.res.GroupVersionKind()
+	c, err := controller.NewUnmanaged(strings.ToLower(gvk.Kind), controller.Options{
+		Reconciler: r,
+		Logger: mgr.GetLogger().WithValues(
+			"controllerGroup", gvk.Group,
+			"controllerKind", gvk.Kind,
+		),
+		// the same controller may be created again when the resource is watched again.
+		SkipNameValidation: ptr.To(true),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	src := source.Kind(mgr.GetCache(), client.Object(r.res), &handler.EnqueueRequestForObject{}, pred)
+	if err := c.Watch(src); err != nil {
+		return nil, err
+	}
+	if copyHandler != nil {
+		if err := c.Watch(source.Kind(mgr.GetCache(), client.Object(r.res), copyHandler)); err != nil {
+			return nil, err
+		}
+	}
+	return c, nil
+}
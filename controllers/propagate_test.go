@@ -4,11 +4,16 @@ import (
 	"context"
 	"time"
 
+	accurateconfig "github.com/cybozu-go/accurate/pkg/config"
 	"github.com/cybozu-go/accurate/pkg/constants"
 	"github.com/cybozu-go/accurate/pkg/indexing"
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	dto "github.com/prometheus/client_model/go"
 	corev1 "k8s.io/api/core/v1"
+	eventsv1 "k8s.io/api/events/v1"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
@@ -52,38 +57,10 @@ var _ = Describe("SubNamespace controller", func() {
 			Expect(k8sClient.Delete(ctx, &svcList.Items[i])).To(Succeed())
 		}
 
-		mgr, err := ctrl.NewManager(k8sCfg, ctrl.Options{
-			Scheme:         scheme,
-			LeaderElection: false,
-			Metrics:        server.Options{BindAddress: "0"},
-			Controller: config.Controller{
-				SkipNameValidation: ptr.To(true),
-			},
-		})
-		Expect(err).ToNot(HaveOccurred())
-
-		err = indexing.SetupIndexForNamespace(ctx, mgr)
-		Expect(err).NotTo(HaveOccurred())
-		err = indexing.SetupIndexForResource(ctx, mgr, svcRes)
-		Expect(err).NotTo(HaveOccurred())
-
-		cloner := ResourceCloner{
+		stopFunc = startPropagateController(ctx, svcRes, ResourceCloner{
 			AnnotationKeyExcludes: []string{"*excluded-annotation.io/*"},
 			LabelKeyExcludes:      []string{"*excluded-label.io/*"},
-		}
-		pc := NewPropagateController(svcRes, cloner)
-		err = pc.SetupWithManager(mgr)
-		Expect(err).NotTo(HaveOccurred())
-
-		ctx, cancel := context.WithCancel(ctx)
-		stopFunc = cancel
-		go func() {
-			err := mgr.Start(ctx)
-			if err != nil {
-				panic(err)
-			}
-		}()
-		time.Sleep(100 * time.Millisecond)
+		})
 	})
 
 	AfterEach(func() {
@@ -219,6 +196,18 @@ var _ = Describe("SubNamespace controller", func() {
 	})
 
 	It("should propagate resources for mode=update", func() {
+		created := propagationOperations.WithLabelValues("", "v1", "Service", constants.PropagateUpdate, operationCreate)
+		deleted := propagationOperations.WithLabelValues("", "v1", "Service", constants.PropagateUpdate, operationDelete)
+		createdBefore := testutil.ToFloat64(created)
+		deletedBefore := testutil.ToFloat64(deleted)
+		observedCount := func() uint64 {
+			m := &dto.Metric{}
+			h := propagationLevelDuration.WithLabelValues("", "v1", "Service", constants.PropagateUpdate)
+			Expect(h.(prometheus.Metric).Write(m)).To(Succeed())
+			return m.GetHistogram().GetSampleCount()
+		}
+		observedBefore := observedCount()
+
 		By("creating a resource in the root namespace")
 		svc1 := &corev1.Service{}
 		svc1.Namespace = rootNS
@@ -258,6 +247,10 @@ var _ = Describe("SubNamespace controller", func() {
 		Expect(svc1Sub1Sub.Annotations).To(HaveKeyWithValue(constants.AnnFrom, sub1NS))
 		Expect(svc1Sub1Sub.Spec.Ports).To(HaveLen(1))
 		Expect(svc1Sub1Sub.Spec.Ports[0].Port).To(BeNumerically("==", 3333))
+		Expect(testutil.ToFloat64(created)).To(BeNumerically(">=", createdBefore+3))
+		Expect(observedCount()).To(BeNumerically(">", observedBefore))
+		time.Sleep(100 * time.Millisecond)
+		observed := observedCount()
 
 		By("deleting a sub-resource to check that Accurate re-creates it")
 		uid := svc1Sub2.UID
@@ -275,7 +268,12 @@ var _ = Describe("SubNamespace controller", func() {
 		time.Sleep(100 * time.Millisecond)
 		Expect(svc1Sub1Sub.ResourceVersion).To(Equal(rv2))
 
+		By("checking that re-creating and restoring sub-resources are not observed as propagation")
+		Expect(observedCount()).To(Equal(observed))
+
 		By("updating a root resource to check that Accurate propagates the change to sub-resources")
+		// the times of managedFields have a resolution of seconds.
+		time.Sleep(time.Second)
 		Expect(komega.Update(svc1, func() {
 			svc1.Labels = map[string]string{"foo": "bar"}
 		})()).To(Succeed())
@@ -286,6 +284,8 @@ var _ = Describe("SubNamespace controller", func() {
 			svc.Namespace = ns
 			Eventually(komega.Object(svc)).Should(HaveField("Labels", HaveKeyWithValue("foo", "bar")))
 		}
+		// observed once for the children of the root and once for the child of sub1.
+		Eventually(observedCount).Should(BeNumerically(">=", observed+2))
 
 		By("deleting a root resource to check that Accurate cascades the deletion")
 		Expect(k8sClient.Delete(ctx, svc1)).To(Succeed())
@@ -296,6 +296,230 @@ var _ = Describe("SubNamespace controller", func() {
 			svc.Namespace = ns
 			Eventually(komega.Get(svc)).Should(WithTransform(apierrors.IsNotFound, BeTrue()))
 		}
+		Expect(testutil.ToFloat64(deleted)).To(BeNumerically(">=", deletedBefore+3))
+	})
+
+	It("should propagate resources for mode=merge", func() {
+		By("creating a resource in the root namespace")
+		svc := &corev1.Service{}
+		svc.Namespace = rootNS
+		svc.Name = "svc-merge"
+		svc.Annotations = map[string]string{
+			constants.AnnPropagate: constants.PropagateMerge,
+			"foo":                  "bar",
+		}
+		svc.Spec.ClusterIP = "None"
+		svc.Spec.Ports = []corev1.ServicePort{{Port: 3333, TargetPort: intstr.FromInt32(3333)}}
+		Expect(k8sClient.Create(ctx, svc)).To(Succeed())
+
+		clone := &corev1.Service{}
+		clone.Name = "svc-merge"
+		clone.Namespace = sub1NS
+		Eventually(komega.Get(clone)).Should(Succeed())
+		Expect(clone.Annotations).To(HaveKeyWithValue(constants.AnnFrom, rootNS))
+		Expect(clone.Annotations).To(HaveKeyWithValue("foo", "bar"))
+
+		By("updating a sub-resource to check that Accurate keeps the changes")
+		Expect(komega.Update(clone, func() {
+			clone.Annotations["foo"] = "tenant"
+			clone.Annotations["extra"] = "tenant"
+			clone.Annotations[constants.AnnPropagate] = constants.PropagateCreate
+		})()).To(Succeed())
+		Eventually(komega.Object(clone)).Should(HaveField("Annotations", HaveKeyWithValue(constants.AnnPropagate, constants.PropagateMerge)))
+
+		By("updating a root resource to check that Accurate propagates the change to sub-resources")
+		Expect(komega.Update(svc, func() {
+			svc.Annotations["foo"] = "baz"
+			svc.Labels = map[string]string{"foo": "bar"}
+		})()).To(Succeed())
+		Eventually(komega.Object(clone)).Should(HaveField("Labels", HaveKeyWithValue("foo", "bar")))
+		Expect(clone.Annotations).To(HaveKeyWithValue("foo", "tenant"))
+		Expect(clone.Annotations).To(HaveKeyWithValue("extra", "tenant"))
+
+		subClone := &corev1.Service{}
+		subClone.Name = "svc-merge"
+		subClone.Namespace = sub1SubNS
+		Eventually(komega.Object(subClone)).Should(HaveField("Labels", HaveKeyWithValue("foo", "bar")))
+		Expect(subClone.Annotations).To(HaveKeyWithValue("foo", "tenant"))
+
+		By("deleting a root resource to check that Accurate cascades the deletion")
+		Expect(k8sClient.Delete(ctx, svc)).To(Succeed())
+		for _, ns := range []string{sub1NS, sub2NS, sub1SubNS} {
+			svc := &corev1.Service{}
+			svc.Name = "svc-merge"
+			svc.Namespace = ns
+			Eventually(komega.Get(svc)).Should(WithTransform(apierrors.IsNotFound, BeTrue()))
+		}
+	})
+
+	It("should limit the propagation depth", func() {
+		svc := &corev1.Service{}
+		svc.Namespace = rootNS
+		svc.Name = "svc-depth"
+		svc.Annotations = map[string]string{
+			constants.AnnPropagate:      constants.PropagateUpdate,
+			constants.AnnPropagateDepth: "2",
+		}
+		svc.Spec.ClusterIP = "None"
+		svc.Spec.Ports = []corev1.ServicePort{{Port: 3333, TargetPort: intstr.FromInt32(3333)}}
+		Expect(k8sClient.Create(ctx, svc)).To(Succeed())
+
+		expected := map[string]string{
+			sub1NS:    "1",
+			sub2NS:    "1",
+			sub1SubNS: "0",
+		}
+		for ns, depth := range expected {
+			clone := &corev1.Service{}
+			clone.Name = "svc-depth"
+			clone.Namespace = ns
+			Eventually(komega.Object(clone)).Should(HaveField("Annotations", HaveKeyWithValue(constants.AnnPropagateDepth, depth)))
+		}
+
+		By("lowering the depth to check that Accurate deletes copies beyond the limit")
+		Expect(komega.Update(svc, func() {
+			svc.Annotations[constants.AnnPropagateDepth] = "1"
+		})()).To(Succeed())
+
+		clone := &corev1.Service{}
+		clone.Name = "svc-depth"
+		clone.Namespace = sub1SubNS
+		Eventually(komega.Get(clone)).Should(WithTransform(apierrors.IsNotFound, BeTrue()))
+		Consistently(komega.Get(clone)).Should(WithTransform(apierrors.IsNotFound, BeTrue()))
+
+		for _, ns := range []string{sub1NS, sub2NS} {
+			clone := &corev1.Service{}
+			clone.Name = "svc-depth"
+			clone.Namespace = ns
+			Eventually(komega.Object(clone)).Should(HaveField("Annotations", HaveKeyWithValue(constants.AnnPropagateDepth, "0")))
+		}
+
+		By("setting an invalid depth to check that Accurate keeps the copies")
+		Expect(komega.Update(svc, func() {
+			svc.Annotations[constants.AnnPropagateDepth] = "abc"
+		})()).To(Succeed())
+
+		for _, ns := range []string{sub1NS, sub2NS} {
+			clone := &corev1.Service{}
+			clone.Name = "svc-depth"
+			clone.Namespace = ns
+			Consistently(komega.Object(clone)).Should(HaveField("Annotations", HaveKeyWithValue(constants.AnnPropagateDepth, "0")))
+		}
+
+		By("updating the resource to check that the invalid depth is reported only once")
+		Expect(komega.Update(svc, func() {
+			svc.Labels = map[string]string{"foo": "bar"}
+		})()).To(Succeed())
+		invalidDepth := And(
+			HaveField("Regarding.Name", "svc-depth"),
+			HaveField("Reason", "InvalidDepth"),
+		)
+		evList := &eventsv1.EventList{}
+		Eventually(komega.ObjectList(evList, client.InNamespace(rootNS))).Should(HaveField("Items", ContainElement(invalidDepth)))
+		Consistently(komega.ObjectList(evList, client.InNamespace(rootNS))).ShouldNot(HaveField("Items",
+			ContainElement(And(invalidDepth, HaveField("Series", Not(BeNil()))))))
+	})
+
+	It("should propagate resources only to selected namespaces", func() {
+		svc := &corev1.Service{}
+		svc.Namespace = rootNS
+		svc.Name = "svc-selector"
+		svc.Annotations = map[string]string{
+			constants.AnnPropagate:                  constants.PropagateUpdate,
+			constants.AnnPropagateNamespaceSelector: constants.LabelParent + "=" + rootNS,
+			constants.AnnPropagateNamespaceExcludes: sub2NS,
+		}
+		svc.Spec.ClusterIP = "None"
+		svc.Spec.Ports = []corev1.ServicePort{{Port: 3333, TargetPort: intstr.FromInt32(3333)}}
+		Expect(k8sClient.Create(ctx, svc)).To(Succeed())
+
+		svcSub1 := &corev1.Service{}
+		svcSub1.Name = "svc-selector"
+		svcSub1.Namespace = sub1NS
+		Eventually(komega.Get(svcSub1)).Should(Succeed())
+
+		for _, ns := range []string{sub2NS, sub1SubNS} {
+			clone := &corev1.Service{}
+			clone.Name = "svc-selector"
+			clone.Namespace = ns
+			Consistently(komega.Get(clone)).Should(WithTransform(apierrors.IsNotFound, BeTrue()))
+		}
+
+		By("changing the excluded namespaces")
+		Expect(komega.Update(svc, func() {
+			svc.Annotations[constants.AnnPropagateNamespaceExcludes] = sub1NS
+		})()).To(Succeed())
+
+		svcSub2 := &corev1.Service{}
+		svcSub2.Name = "svc-selector"
+		svcSub2.Namespace = sub2NS
+		Eventually(komega.Get(svcSub2)).Should(Succeed())
+		Eventually(komega.Get(svcSub1)).Should(WithTransform(apierrors.IsNotFound, BeTrue()))
+	})
+
+	It("should not propagate resources that a namespace opts out of", func() {
+		ns := &corev1.Namespace{}
+		ns.Name = sub2NS
+		Expect(komega.Update(ns, func() {
+			ns.Annotations = map[string]string{constants.AnnPropagateExclude: "services/svc-optout"}
+		})()).To(Succeed())
+		DeferCleanup(komega.Update(ns, func() {
+			delete(ns.Annotations, constants.AnnPropagateExclude)
+		}))
+
+		svc := &corev1.Service{}
+		svc.Namespace = rootNS
+		svc.Name = "svc-optout"
+		svc.Annotations = map[string]string{constants.AnnPropagate: constants.PropagateUpdate}
+		svc.Spec.ClusterIP = "None"
+		svc.Spec.Ports = []corev1.ServicePort{{Port: 3333, TargetPort: intstr.FromInt32(3333)}}
+		Expect(k8sClient.Create(ctx, svc)).To(Succeed())
+
+		svcSub1 := &corev1.Service{}
+		svcSub1.Name = "svc-optout"
+		svcSub1.Namespace = sub1NS
+		Eventually(komega.Get(svcSub1)).Should(Succeed())
+
+		svcSub2 := &corev1.Service{}
+		svcSub2.Name = "svc-optout"
+		svcSub2.Namespace = sub2NS
+		Consistently(komega.Get(svcSub2)).Should(WithTransform(apierrors.IsNotFound, BeTrue()))
+	})
+
+	It("should render templates for each namespace", func() {
+		svc := &corev1.Service{}
+		svc.Namespace = rootNS
+		svc.Name = "svc-tmpl"
+		svc.Annotations = map[string]string{
+			constants.AnnPropagate:         constants.PropagateUpdate,
+			constants.AnnPropagateTemplate: "true",
+			"tree":                         "{{ .Namespace }}/{{ .Parent }}/{{ .Root }}/{{ .Depth }}",
+		}
+		svc.Spec.ClusterIP = "None"
+		svc.Spec.Ports = []corev1.ServicePort{{Port: 3333, TargetPort: intstr.FromInt32(3333)}}
+		Expect(k8sClient.Create(ctx, svc)).To(Succeed())
+
+		expected := map[string]string{
+			sub1NS:    "prop-sub1/prop-root/prop-root/1",
+			sub2NS:    "prop-sub2/prop-root/prop-root/1",
+			sub1SubNS: "prop-sub1-sub/prop-sub1/prop-root/2",
+		}
+		for ns, tree := range expected {
+			clone := &corev1.Service{}
+			clone.Name = "svc-tmpl"
+			clone.Namespace = ns
+			Eventually(komega.Object(clone)).Should(HaveField("Annotations", HaveKeyWithValue("tree", tree)))
+		}
+
+		By("referring to an undefined field")
+		Expect(komega.Update(svc, func() {
+			svc.Annotations["tree"] = "{{ .Undefined }}"
+		})()).To(Succeed())
+
+		clone := &corev1.Service{}
+		clone.Name = "svc-tmpl"
+		clone.Namespace = sub1NS
+		Consistently(komega.Object(clone)).Should(HaveField("Annotations", HaveKeyWithValue("tree", expected[sub1NS])))
 	})
 
 	DescribeTable("should NOT propagate excluded resource labels/annotations",
@@ -372,3 +596,183 @@ var _ = Describe("SubNamespace controller", func() {
 		Eventually(komega.Object(svc2)).Should(HaveField("Annotations", HaveKeyWithValue(constants.AnnGenerated, notGenerated)))
 	})
 })
+
+// startPropagateController starts a manager running a PropagateController for `res` with `cloner`,
+// and returns the function to stop it.
+func startPropagateController(ctx context.Context, res *unstructured.Unstructured, cloner ResourceCloner) func() {
+	mgr, err := ctrl.NewManager(k8sCfg, ctrl.Options{
+		Scheme:         scheme,
+		LeaderElection: false,
+		Metrics:        server.Options{BindAddress: "0"},
+		Controller: config.Controller{
+			SkipNameValidation: ptr.To(true),
+		},
+	})
+	Expect(err).ToNot(HaveOccurred())
+
+	err = indexing.SetupIndexForNamespace(ctx, mgr)
+	Expect(err).NotTo(HaveOccurred())
+	err = indexing.SetupIndexForResource(ctx, mgr, res)
+	Expect(err).NotTo(HaveOccurred())
+
+	pc := NewPropagateController(res, cloner)
+	err = pc.SetupWithManager(mgr)
+	Expect(err).NotTo(HaveOccurred())
+
+	ctx, cancel := context.WithCancel(ctx)
+	go func() {
+		err := mgr.Start(ctx)
+		if err != nil {
+			panic(err)
+		}
+	}()
+	time.Sleep(100 * time.Millisecond)
+	return cancel
+}
+
+var _ = Describe("PropagateController with per-resource options", func() {
+	ctx := context.Background()
+	var stopFunc func()
+
+	const (
+		rootNS = "prop-root"
+		sub1NS = "prop-sub1"
+		sub2NS = "prop-sub2"
+	)
+
+	svcRes := &unstructured.Unstructured{}
+	svcRes.SetGroupVersionKind(schema.GroupVersionKind{
+		Group:   corev1.GroupName,
+		Version: corev1.SchemeGroupVersion.Version,
+		Kind:    "Service",
+	})
+
+	BeforeEach(func() {
+		svcList := &corev1.ServiceList{}
+		Expect(k8sClient.List(ctx, svcList)).To(Succeed())
+		for i := range svcList.Items {
+			Expect(k8sClient.Delete(ctx, &svcList.Items[i])).To(Succeed())
+		}
+
+		stopFunc = startPropagateController(ctx, svcRes, ResourceCloner{
+			Watches: map[schema.GroupVersionKind]accurateconfig.Watch{
+				svcRes.GroupVersionKind(): {
+					ConflictPolicy:        accurateconfig.ConflictPolicySkip,
+					LabelKeyExcludes:      []string{"*svc-excluded.io/*"},
+					AnnotationKeyExcludes: []string{"*svc-excluded.io/*"},
+					StripFields:           []string{"spec.publishNotReadyAddresses", "spec.ports.appProtocol"},
+				},
+			},
+		})
+	})
+
+	AfterEach(func() {
+		stopFunc()
+		time.Sleep(100 * time.Millisecond)
+	})
+
+	It("should leave conflicting resources intact", func() {
+		own := &corev1.Service{}
+		own.Namespace = sub1NS
+		own.Name = "svc-conflict"
+		own.Spec.ClusterIP = "None"
+		own.Spec.Ports = []corev1.ServicePort{{Port: 4444, TargetPort: intstr.FromInt32(4444)}}
+		Expect(k8sClient.Create(ctx, own)).To(Succeed())
+
+		svc := &corev1.Service{}
+		svc.Namespace = rootNS
+		svc.Name = "svc-conflict"
+		svc.Annotations = map[string]string{constants.AnnPropagate: constants.PropagateUpdate}
+		svc.Spec.ClusterIP = "None"
+		svc.Spec.Ports = []corev1.ServicePort{{Port: 3333, TargetPort: intstr.FromInt32(3333)}}
+		Expect(k8sClient.Create(ctx, svc)).To(Succeed())
+
+		svcSub2 := &corev1.Service{}
+		svcSub2.Name = "svc-conflict"
+		svcSub2.Namespace = sub2NS
+		Eventually(komega.Get(svcSub2)).Should(Succeed())
+
+		Eventually(komega.Object(svc)).Should(HaveField("Annotations", HaveKeyWithValue(constants.AnnConflicts, sub1NS)))
+		Consistently(komega.Object(own)).Should(HaveField("Annotations", Not(HaveKey(constants.AnnFrom))))
+		Expect(own.Spec.Ports[0].Port).To(BeNumerically("==", 4444))
+		Expect(svcSub2.Annotations).NotTo(HaveKey(constants.AnnConflicts))
+	})
+
+	It("should NOT propagate labels/annotations excluded for the kind", func() {
+		const excluded = "svc-excluded.io/foo"
+
+		svc := &corev1.Service{}
+		svc.Namespace = rootNS
+		svc.Name = "svc-excluded"
+		svc.Annotations = map[string]string{
+			constants.AnnPropagate: constants.PropagateUpdate,
+			excluded:               "bar",
+			"foo.bar/baz":          "baz",
+		}
+		svc.Labels = map[string]string{
+			excluded:      "bar",
+			"foo.bar/baz": "baz",
+		}
+		svc.Spec.ClusterIP = "None"
+		svc.Spec.Ports = []corev1.ServicePort{{Port: 3333, TargetPort: intstr.FromInt32(3333)}}
+		Expect(k8sClient.Create(ctx, svc)).To(Succeed())
+
+		clone := &corev1.Service{}
+		clone.Name = "svc-excluded"
+		clone.Namespace = sub1NS
+		Eventually(komega.Get(clone)).Should(Succeed())
+
+		Expect(clone.Annotations).To(Not(HaveKey(excluded)))
+		Expect(clone.Annotations).To(HaveKeyWithValue("foo.bar/baz", "baz"))
+		Expect(clone.Labels).To(Not(HaveKey(excluded)))
+		Expect(clone.Labels).To(HaveKeyWithValue("foo.bar/baz", "baz"))
+	})
+
+	It("should strip configured fields from copies", func() {
+		svc := &corev1.Service{}
+		svc.Namespace = rootNS
+		svc.Name = "svc-strip"
+		svc.Annotations = map[string]string{constants.AnnPropagate: constants.PropagateUpdate}
+		svc.Spec.ClusterIP = "None"
+		svc.Spec.PublishNotReadyAddresses = true
+		svc.Spec.Ports = []corev1.ServicePort{{
+			Port:        3333,
+			TargetPort:  intstr.FromInt32(3333),
+			AppProtocol: ptr.To("http"),
+		}}
+		Expect(k8sClient.Create(ctx, svc)).To(Succeed())
+
+		clone := &corev1.Service{}
+		clone.Namespace = sub1NS
+		clone.Name = "svc-strip"
+		Eventually(komega.Get(clone)).Should(Succeed())
+		Expect(clone.Spec.PublishNotReadyAddresses).To(BeFalse())
+		Expect(clone.Spec.Ports).To(HaveLen(1))
+		Expect(clone.Spec.Ports[0].Port).To(BeNumerically("==", 3333))
+		Expect(clone.Spec.Ports[0].AppProtocol).To(BeNil())
+	})
+})
+
+var _ = Describe("ResourceCloner", func() {
+	It("should strip secrets of ServiceAccount unless stripFields is set", func() {
+		sa := &unstructured.Unstructured{}
+		sa.SetAPIVersion("v1")
+		sa.SetKind("ServiceAccount")
+		sa.SetNamespace("clone-sa")
+		sa.SetName("sa")
+		sa.Object["secrets"] = []any{map[string]any{"name": "sa-token"}}
+		sa.Object["automountServiceAccountToken"] = false
+
+		rc := &ResourceCloner{}
+		c := rc.cloneResource(sa, "clone-sa-sub")
+		Expect(c.Object).NotTo(HaveKey("secrets"))
+		Expect(c.Object).To(HaveKey("automountServiceAccountToken"))
+
+		rc.Watches = map[schema.GroupVersionKind]accurateconfig.Watch{
+			sa.GroupVersionKind(): {StripFields: []string{"automountServiceAccountToken"}},
+		}
+		c = rc.cloneResource(sa, "clone-sa-sub")
+		Expect(c.Object).To(HaveKey("secrets"))
+		Expect(c.Object).NotTo(HaveKey("automountServiceAccountToken"))
+	})
+})
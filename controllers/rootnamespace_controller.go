@@ -0,0 +1,190 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+
+	accuratev2 "github.com/cybozu-go/accurate/api/accurate/v2"
+	"github.com/cybozu-go/accurate/internal/util/tree"
+	"github.com/cybozu-go/accurate/pkg/constants"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/events"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+// RootNamespaceReconciler reconciles a RootNamespace object
+type RootNamespaceReconciler struct {
+	client.Client
+
+	recorder events.EventRecorder
+}
+
+//+kubebuilder:rbac:groups=accurate.cybozu.com,resources=rootnamespaces,verbs=get;list;watch
+//+kubebuilder:rbac:groups=accurate.cybozu.com,resources=rootnamespaces/status,verbs=get;update;patch
+
+// Reconcile implements reconcile.Reconciler interface.
+func (r *RootNamespaceReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	rn := &accuratev2.RootNamespace{}
+	if err := r.Get(ctx, req.NamespacedName, rn); err != nil {
+		return ctrl.Result{}, client.IgnoreNotFound(err)
+	}
+	if rn.DeletionTimestamp != nil {
+		return ctrl.Result{}, nil
+	}
+
+	orig := rn.DeepCopy()
+	cond, err := r.reconcileRoot(ctx, rn)
+	if err != nil {
+		return ctrl.Result{}, fmt.Errorf("failed to reconcile: %w", err)
+	}
+	cond.Type = accuratev2.RootNamespaceReady
+	cond.ObservedGeneration = rn.Generation
+	meta.SetStatusCondition(&rn.Status.Conditions, cond)
+	rn.Status.ObservedGeneration = rn.Generation
+	if equality.Semantic.DeepEqual(orig.Status, rn.Status) {
+		return ctrl.Result{}, nil
+	}
+	if err := r.Status().Patch(ctx, rn, client.MergeFrom(orig)); err != nil {
+		return ctrl.Result{}, fmt.Errorf("failed to update status: %w", err)
+	}
+	return ctrl.Result{}, nil
+}
+
+// reconcileRoot makes the namespace declared by `rn` a root namespace and summarizes the tree in the status.
+// It returns the Ready condition without its type.
+func (r *RootNamespaceReconciler) reconcileRoot(ctx context.Context, rn *accuratev2.RootNamespace) (metav1.Condition, error) {
+	logger := log.FromContext(ctx)
+	rn.Status.Namespaces = 0
+	rn.Status.Depth = 0
+	rn.Status.Conflicts = 0
+
+	ns := &corev1.Namespace{}
+	if err := r.Get(ctx, client.ObjectKey{Name: rn.Name}, ns); err != nil {
+		if !apierrors.IsNotFound(err) {
+			return metav1.Condition{}, fmt.Errorf("failed to get namespace %s: %w", rn.Name, err)
+		}
+		return metav1.Condition{
+			Status:  metav1.ConditionFalse,
+			Reason:  accuratev2.RootNamespaceNotFound,
+			Message: fmt.Sprintf("namespace %s is not found", rn.Name),
+		}, nil
+	}
+
+	if parent := ns.Labels[constants.LabelParent]; parent != "" {
+		return metav1.Condition{
+			Status:  metav1.ConditionFalse,
+			Reason:  accuratev2.RootNamespaceNotRoot,
+			Message: fmt.Sprintf("namespace %s is a sub-namespace of %s", ns.Name, parent),
+		}, nil
+	}
+	if typ := ns.Labels[constants.LabelType]; typ != "" && typ != constants.NSTypeRoot {
+		return metav1.Condition{
+			Status:  metav1.ConditionFalse,
+			Reason:  accuratev2.RootNamespaceNotRoot,
+			Message: fmt.Sprintf("namespace %s is a %s namespace", ns.Name, typ),
+		}, nil
+	}
+
+	if ns.Labels[constants.LabelType] != constants.NSTypeRoot {
+		orig := ns.DeepCopy()
+		if ns.Labels == nil {
+			ns.Labels = make(map[string]string)
+		}
+		ns.Labels[constants.LabelType] = constants.NSTypeRoot
+		if err := r.Patch(ctx, ns, client.MergeFrom(orig)); err != nil {
+			return metav1.Condition{}, fmt.Errorf("failed to make namespace %s a root namespace: %w", ns.Name, err)
+		}
+		logger.Info("made namespace a root namespace", "name", ns.Name)
+		r.recorder.Eventf(ns, rn, corev1.EventTypeNormal, reasonDeclared, actionDeclareRoot,
+			"made a root namespace by RootNamespace %s", rn.Name)
+	}
+
+	visited := map[string]bool{ns.Name: true}
+	level := []string{ns.Name}
+	for depth := int32(0); len(level) > 0; depth++ {
+		var next []string
+		for _, name := range level {
+			conflicts, err := r.countConflicts(ctx, name)
+			if err != nil {
+				return metav1.Condition{}, err
+			}
+			rn.Status.Conflicts += conflicts
+
+			children := &corev1.NamespaceList{}
+			if err := r.List(ctx, children, client.MatchingFields{constants.NamespaceParentKey: name}); err != nil {
+				return metav1.Condition{}, fmt.Errorf("failed to list the children of %s: %w", name, err)
+			}
+			for _, child := range children.Items {
+				if visited[child.Name] {
+					continue
+				}
+				visited[child.Name] = true
+				next = append(next, child.Name)
+			}
+		}
+		if len(next) > 0 {
+			rn.Status.Namespaces += int32(len(next))
+			rn.Status.Depth = depth + 1
+		}
+		level = next
+	}
+
+	return metav1.Condition{
+		Status: metav1.ConditionTrue,
+		Reason: accuratev2.RootNamespaceActive,
+	}, nil
+}
+
+// countConflicts returns the number of SubNamespaces in `ns` that conflict with existing namespaces.
+func (r *RootNamespaceReconciler) countConflicts(ctx context.Context, ns string) (int32, error) {
+	subs := &accuratev2.SubNamespaceList{}
+	if err := r.List(ctx, subs, client.InNamespace(ns)); err != nil {
+		return 0, fmt.Errorf("failed to list sub namespaces in %s: %w", ns, err)
+	}
+	var count int32
+	for _, sn := range subs.Items {
+		if isConflicting(&sn) {
+			count++
+		}
+	}
+	return count, nil
+}
+
+// SetupWithManager sets up the controller with the Manager.
+func (r *RootNamespaceReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	mapToRoot := func(ctx context.Context, name string) []reconcile.Request {
+		root, err := tree.FindRoot(ctx, r, name)
+		if err != nil {
+			log.FromContext(ctx).Error(err, "failed to find the root namespace", "namespace", name)
+			return nil
+		}
+		return []reconcile.Request{{NamespacedName: client.ObjectKey{Name: root}}}
+	}
+	nsHandler := func(ctx context.Context, o client.Object) []reconcile.Request {
+		// Both the old and new objects are mapped on updates,
+		// so the trees that a namespace leaves and joins are both reconciled.
+		if parent := o.GetLabels()[constants.LabelParent]; parent != "" {
+			return mapToRoot(ctx, parent)
+		}
+		return []reconcile.Request{{NamespacedName: client.ObjectKey{Name: o.GetName()}}}
+	}
+	subNSHandler := func(ctx context.Context, o client.Object) []reconcile.Request {
+		return mapToRoot(ctx, o.GetNamespace())
+	}
+
+	r.recorder = mgr.GetEventRecorder(eventSource)
+
+	return ctrl.NewControllerManagedBy(mgr).
+		For(&accuratev2.RootNamespace{}).
+		Watches(&corev1.Namespace{}, handler.EnqueueRequestsFromMapFunc(nsHandler)).
+		Watches(&accuratev2.SubNamespace{}, handler.EnqueueRequestsFromMapFunc(subNSHandler)).
+		Complete(r)
+}
@@ -0,0 +1,127 @@
+package controllers
+
+import (
+	"context"
+	"time"
+
+	accuratev2 "github.com/cybozu-go/accurate/api/accurate/v2"
+	"github.com/cybozu-go/accurate/pkg/constants"
+	"github.com/cybozu-go/accurate/pkg/indexing"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/utils/ptr"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/config"
+	"sigs.k8s.io/controller-runtime/pkg/envtest/komega"
+	"sigs.k8s.io/controller-runtime/pkg/metrics/server"
+)
+
+var _ = Describe("RootNamespace controller", func() {
+	ctx := context.Background()
+	var stopFunc func()
+
+	BeforeEach(func() {
+		mgr, err := ctrl.NewManager(k8sCfg, ctrl.Options{
+			Scheme:         scheme,
+			LeaderElection: false,
+			Metrics:        server.Options{BindAddress: "0"},
+			Controller: config.Controller{
+				SkipNameValidation: ptr.To(true),
+			},
+		})
+		Expect(err).ToNot(HaveOccurred())
+
+		rnr := &RootNamespaceReconciler{
+			Client: mgr.GetClient(),
+		}
+		err = rnr.SetupWithManager(mgr)
+		Expect(err).ToNot(HaveOccurred())
+
+		err = indexing.SetupIndexForNamespace(ctx, mgr)
+		Expect(err).NotTo(HaveOccurred())
+
+		ctx, cancel := context.WithCancel(ctx)
+		stopFunc = cancel
+		go func() {
+			err := mgr.Start(ctx)
+			if err != nil {
+				panic(err)
+			}
+		}()
+		time.Sleep(100 * time.Millisecond)
+	})
+
+	AfterEach(func() {
+		stopFunc()
+		time.Sleep(100 * time.Millisecond)
+	})
+
+	It("should make a root namespace and summarize the tree", func() {
+		rn := &accuratev2.RootNamespace{}
+		rn.Name = "rootns1"
+		Expect(k8sClient.Create(ctx, rn)).To(Succeed())
+
+		Eventually(komega.Object(rn)).Should(HaveField("Status.Conditions", ContainElement(And(
+			HaveField("Type", accuratev2.RootNamespaceReady),
+			HaveField("Reason", accuratev2.RootNamespaceNotFound),
+		))))
+
+		ns := &corev1.Namespace{}
+		ns.Name = "rootns1"
+		Expect(k8sClient.Create(ctx, ns)).To(Succeed())
+
+		Eventually(komega.Object(ns)).Should(HaveField("Labels", HaveKeyWithValue(constants.LabelType, constants.NSTypeRoot)))
+		Eventually(func() bool {
+			Expect(komega.Get(rn)()).To(Succeed())
+			return meta.IsStatusConditionTrue(rn.Status.Conditions, accuratev2.RootNamespaceReady)
+		}).Should(BeTrue())
+
+		sub := &corev1.Namespace{}
+		sub.Name = "rootns1-sub"
+		sub.Labels = map[string]string{constants.LabelParent: ns.Name}
+		Expect(k8sClient.Create(ctx, sub)).To(Succeed())
+
+		subsub := &corev1.Namespace{}
+		subsub.Name = "rootns1-sub-sub"
+		subsub.Labels = map[string]string{constants.LabelParent: sub.Name}
+		Expect(k8sClient.Create(ctx, subsub)).To(Succeed())
+
+		Eventually(komega.Object(rn)).Should(And(
+			HaveField("Status.Namespaces", BeEquivalentTo(2)),
+			HaveField("Status.Depth", BeEquivalentTo(2)),
+		))
+
+		By("moving a namespace out of the tree")
+		Expect(komega.Update(subsub, func() {
+			delete(subsub.Labels, constants.LabelParent)
+		})()).To(Succeed())
+		Eventually(komega.Object(rn)).Should(And(
+			HaveField("Status.Namespaces", BeEquivalentTo(1)),
+			HaveField("Status.Depth", BeEquivalentTo(1)),
+		))
+	})
+
+	It("should not make a sub-namespace a root namespace", func() {
+		root := &corev1.Namespace{}
+		root.Name = "rootns2"
+		root.Labels = map[string]string{constants.LabelType: constants.NSTypeRoot}
+		Expect(k8sClient.Create(ctx, root)).To(Succeed())
+
+		sub := &corev1.Namespace{}
+		sub.Name = "rootns2-sub"
+		sub.Labels = map[string]string{constants.LabelParent: root.Name}
+		Expect(k8sClient.Create(ctx, sub)).To(Succeed())
+
+		rn := &accuratev2.RootNamespace{}
+		rn.Name = sub.Name
+		Expect(k8sClient.Create(ctx, rn)).To(Succeed())
+
+		Eventually(komega.Object(rn)).Should(HaveField("Status.Conditions", ContainElement(And(
+			HaveField("Type", accuratev2.RootNamespaceReady),
+			HaveField("Reason", accuratev2.RootNamespaceNotRoot),
+		))))
+		Consistently(komega.Object(sub)).Should(HaveField("Labels", Not(HaveKey(constants.LabelType))))
+	})
+})
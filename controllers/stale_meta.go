@@ -0,0 +1,102 @@
+package controllers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/cybozu-go/accurate/pkg/constants"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/structured-merge-diff/v6/fieldpath"
+)
+
+// metaOwners holds the managers of namespace labels and annotations.
+type metaOwners struct {
+	accurate fieldpath.Set
+	others   fieldpath.Set
+}
+
+func newMetaOwners(ns *corev1.Namespace) (*metaOwners, error) {
+	o := &metaOwners{}
+	for _, mf := range ns.ManagedFields {
+		if mf.Subresource != "" || mf.FieldsV1 == nil {
+			continue
+		}
+		s := &fieldpath.Set{}
+		if err := s.FromJSON(bytes.NewReader(mf.FieldsV1.Raw)); err != nil {
+			return nil, fmt.Errorf("failed to parse managed fields of %s: %w", mf.Manager, err)
+		}
+		if mf.Manager == string(fieldOwner) {
+			o.accurate = *o.accurate.Union(s)
+		} else {
+			o.others = *o.others.Union(s)
+		}
+	}
+	return o, nil
+}
+
+// ownedOnlyByAccurate returns true if `key` in metadata.`field` is managed only by Accurate.
+func (o *metaOwners) ownedOnlyByAccurate(field, key string) bool {
+	p := fieldpath.MakePathOrDie("metadata", field, key)
+	return o.accurate.Has(p) && !o.others.Has(p)
+}
+
+// deleteStaleMeta removes labels and annotations of `ns` that Accurate has set
+// but no longer propagates. Keys also managed by others are kept.
+//
+// `isPropagated` reports whether a key is subject to propagation, and `labels` and
+// `annotations` are the keys currently propagated to `ns`.
+func (r *NamespaceReconciler) deleteStaleMeta(ctx context.Context, ns *corev1.Namespace,
+	isPropagated func(field, key string) bool, labels, annotations map[string]string) error {
+	owners, err := newMetaOwners(ns)
+	if err != nil {
+		return err
+	}
+
+	stale := func(field string, current, desired map[string]string) map[string]any {
+		m := make(map[string]any)
+		for k := range current {
+			if _, ok := desired[k]; ok {
+				continue
+			}
+			if strings.HasPrefix(k, constants.MetaPrefix) || k == constants.LabelCreatedBy {
+				continue
+			}
+			if !isPropagated(field, k) || !owners.ownedOnlyByAccurate(field, k) {
+				continue
+			}
+			m[k] = nil
+		}
+		return m
+	}
+	staleLabels := stale("labels", ns.Labels, labels)
+	staleAnnotations := stale("annotations", ns.Annotations, annotations)
+	if len(staleLabels) == 0 && len(staleAnnotations) == 0 {
+		return nil
+	}
+
+	patch := map[string]any{
+		"metadata": map[string]any{
+			"labels":      staleLabels,
+			"annotations": staleAnnotations,
+		},
+	}
+	patchBytes, err := json.Marshal(patch)
+	if err != nil {
+		return err
+	}
+	if err := r.Patch(ctx, ns, client.RawPatch(types.MergePatchType, patchBytes), fieldOwner); err != nil {
+		return fmt.Errorf("failed to delete stale labels/annotations of namespace %s: %w", ns.Name, err)
+	}
+
+	logger := log.FromContext(ctx)
+	logger.Info("deleted stale labels/annotations", "namespace", ns.Name, "labels", len(staleLabels), "annotations", len(staleAnnotations))
+	r.recorder.Eventf(ns, nil, corev1.EventTypeNormal, reasonDeleted, actionPropagate,
+		"deleted %d stale labels and %d stale annotations", len(staleLabels), len(staleAnnotations))
+	return nil
+}
@@ -3,6 +3,7 @@ package controllers
 import (
 	"context"
 	"fmt"
+	"slices"
 	"time"
 
 	accuratev2 "github.com/cybozu-go/accurate/api/accurate/v2"
@@ -12,9 +13,11 @@ import (
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/apimachinery/pkg/util/json"
 	metav1ac "k8s.io/client-go/applyconfigurations/meta/v1"
+	"k8s.io/client-go/tools/events"
 	kstatus "sigs.k8s.io/cli-utils/pkg/kstatus/status"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/builder"
@@ -30,6 +33,12 @@ import (
 // SubNamespaceReconciler reconciles a SubNamespace object
 type SubNamespaceReconciler struct {
 	client.Client
+
+	// Watches runs the controllers for the propagated resources counted in the status.
+	// If nil, the count is always zero.
+	Watches *Watches
+
+	recorder events.EventRecorder
 }
 
 //+kubebuilder:rbac:groups=accurate.cybozu.com,resources=subnamespaces,verbs=get;list;watch;create;update;patch;delete
@@ -43,7 +52,13 @@ func (r *SubNamespaceReconciler) Reconcile(ctx context.Context, req ctrl.Request
 
 	sn := &accuratev2.SubNamespace{}
 	if err := r.Get(ctx, req.NamespacedName, sn); err != nil {
-		return ctrl.Result{}, client.IgnoreNotFound(err)
+		if !apierrors.IsNotFound(err) {
+			return ctrl.Result{}, err
+		}
+		if err := r.createForAutoGrafted(ctx, req.NamespacedName); err != nil {
+			return ctrl.Result{}, fmt.Errorf("failed to create SubNamespace for an auto-grafted namespace: %w", err)
+		}
+		return ctrl.Result{}, nil
 	}
 
 	if sn.DeletionTimestamp != nil {
@@ -82,17 +97,85 @@ func (r *SubNamespaceReconciler) finalize(ctx context.Context, sn *accuratev2.Su
 		return r.removeFinalizer(ctx, sn)
 	}
 
+	if sn.Spec.DeletionPolicy == accuratev2.DeletionPolicyOrphan {
+		if err := r.orphan(ctx, sn, ns); err != nil {
+			return err
+		}
+		return r.removeFinalizer(ctx, sn)
+	}
+
 	if err := r.Delete(ctx, ns); err != nil {
 		if !apierrors.IsNotFound(err) {
 			return fmt.Errorf("failed to delete namespace %s: %w", ns.Name, err)
 		}
 	} else {
 		logger.Info("deleted namespace", "name", ns.Name)
+		r.recorder.Eventf(sn, ns, corev1.EventTypeNormal, reasonDeleted, actionDeleteNamespace,
+			"deleted namespace %s", ns.Name)
 	}
 
 	return r.removeFinalizer(ctx, sn)
 }
 
+// orphan detaches `ns` from its parent and keeps it.
+// The propagated resources in `ns` are deleted by the namespace reconciler unless they are kept.
+func (r *SubNamespaceReconciler) orphan(ctx context.Context, sn *accuratev2.SubNamespace, ns *corev1.Namespace) error {
+	logger := log.FromContext(ctx)
+
+	if sn.Spec.KeepPropagatedResources {
+		if err := r.keepPropagated(ctx, ns.Name); err != nil {
+			return err
+		}
+	}
+
+	orig := ns.DeepCopy()
+	delete(ns.Labels, constants.LabelParent)
+	delete(ns.Labels, constants.LabelCreatedBy)
+	if err := r.Patch(ctx, ns, client.MergeFrom(orig)); err != nil {
+		return fmt.Errorf("failed to orphan namespace %s: %w", ns.Name, err)
+	}
+	logger.Info("orphaned namespace", "name", ns.Name)
+	r.recorder.Eventf(sn, ns, corev1.EventTypeNormal, reasonOrphaned, actionOrphanNamespace,
+		"orphaned namespace %s", ns.Name)
+	r.recorder.Eventf(ns, sn, corev1.EventTypeNormal, reasonOrphaned, actionOrphanNamespace,
+		"orphaned from %s by deleting SubNamespace %s/%s", sn.Namespace, sn.Namespace, sn.Name)
+	return nil
+}
+
+// keepPropagated turns the resources propagated into `ns` into ordinary resources
+// by removing the annotations and the label that make them copies.
+func (r *SubNamespaceReconciler) keepPropagated(ctx context.Context, ns string) error {
+	if r.Watches == nil {
+		return nil
+	}
+
+	for _, res := range r.Watches.Resources() {
+		l := &unstructured.UnstructuredList{}
+		l.SetGroupVersionKind(res.GroupVersionKind())
+		if err := r.List(ctx, l, client.InNamespace(ns), client.MatchingFields{constants.PropagateKey: constants.PropagateAny}); err != nil {
+			return fmt.Errorf("failed to list %s: %w", res.GroupVersionKind().String(), err)
+		}
+		for i := range l.Items {
+			obj := &l.Items[i]
+			if obj.GetAnnotations()[constants.AnnFrom] == "" {
+				continue
+			}
+			orig := obj.DeepCopy()
+			ann := obj.GetAnnotations()
+			delete(ann, constants.AnnFrom)
+			delete(ann, constants.AnnPropagate)
+			obj.SetAnnotations(ann)
+			labels := obj.GetLabels()
+			delete(labels, constants.LabelCreatedBy)
+			obj.SetLabels(labels)
+			if err := r.Patch(ctx, obj, client.MergeFrom(orig)); err != nil {
+				return fmt.Errorf("failed to keep %s/%s of %s: %w", ns, obj.GetName(), res.GroupVersionKind().String(), err)
+			}
+		}
+	}
+	return nil
+}
+
 func (r *SubNamespaceReconciler) removeFinalizer(ctx context.Context, sn *accuratev2.SubNamespace) error {
 	if !controllerutil.ContainsFinalizer(sn, constants.Finalizer) {
 		return nil
@@ -118,12 +201,15 @@ func (r *SubNamespaceReconciler) removeFinalizer(ctx context.Context, sn *accura
 func (r *SubNamespaceReconciler) reconcileNS(ctx context.Context, sn *accuratev2.SubNamespace) error {
 	logger := log.FromContext(ctx)
 
+	// progress is the reason of the Reconciling condition when the namespace is created or adopted in this reconciliation.
+	var progress string
 	ns := &corev1.Namespace{}
 	if err := r.Get(ctx, client.ObjectKey{Name: sn.Name}, ns); err != nil {
 		if !apierrors.IsNotFound(err) {
 			return err
 		}
 
+		progress = accuratev2.SubNamespaceCreating
 		ns = &corev1.Namespace{}
 		ns.Name = sn.Name
 		ns.Labels = map[string]string{
@@ -134,17 +220,54 @@ func (r *SubNamespaceReconciler) reconcileNS(ctx context.Context, sn *accuratev2
 			return fmt.Errorf("failed to create namespace %s: %w", ns.Name, err)
 		}
 		logger.Info("created a sub namespace", "name", sn.Name)
+		r.recorder.Eventf(sn, ns, corev1.EventTypeNormal, reasonCreated, actionCreateNamespace,
+			"created namespace %s", ns.Name)
+		r.recorder.Eventf(ns, sn, corev1.EventTypeNormal, reasonCreated, actionCreateNamespace,
+			"created for SubNamespace %s/%s", sn.Namespace, sn.Name)
 	}
 
+	status := accuratev2ac.SubNamespaceStatus().
+		WithObservedGeneration(sn.Generation)
 	ac := accuratev2ac.SubNamespace(sn.Name, sn.Namespace).
-		WithStatus(
-			accuratev2ac.SubNamespaceStatus().
-				WithObservedGeneration(sn.Generation),
-		)
+		WithStatus(status)
+
+	if ns.Labels[constants.LabelParent] != sn.Namespace && ns.Annotations[constants.AnnAdoptableBy] == sn.Namespace {
+		denied, err := r.adopt(ctx, sn, ns)
+		if err != nil {
+			return err
+		}
+		if denied != "" {
+			status.WithConditions(
+				conditionPatch(sn.Status.Conditions,
+					metav1ac.Condition().
+						WithType(string(kstatus.ConditionStalled)).
+						WithStatus(metav1.ConditionTrue).
+						WithObservedGeneration(sn.Generation).
+						WithReason(accuratev2.SubNamespaceAdoptionDenied).
+						WithMessage(denied),
+				),
+				conditionPatch(sn.Status.Conditions,
+					metav1ac.Condition().
+						WithType(accuratev2.SubNamespaceReady).
+						WithStatus(metav1.ConditionFalse).
+						WithObservedGeneration(sn.Generation).
+						WithReason(accuratev2.SubNamespaceAdoptionDenied).
+						WithMessage(fmt.Sprintf("Namespace %s cannot be adopted", ns.Name)),
+				),
+			)
+			return r.Status().Apply(ctx, ac, fieldOwner, client.ForceOwnership)
+		}
+		progress = accuratev2.SubNamespaceAdopting
+	}
 
 	if ns.Labels[constants.LabelParent] != sn.Namespace {
 		logger.Info("a conflicting namespace already exists")
-		ac.Status.WithConditions(
+		// report the conflict only when it is newly found
+		if !isConflicting(sn) {
+			r.recorder.Eventf(sn, ns, corev1.EventTypeWarning, reasonConflict, actionCreateNamespace,
+				"namespace %s already exists and is not a child of %s", ns.Name, sn.Namespace)
+		}
+		status.WithConditions(
 			conditionPatch(sn.Status.Conditions,
 				metav1ac.Condition().
 					WithType(string(kstatus.ConditionStalled)).
@@ -153,12 +276,200 @@ func (r *SubNamespaceReconciler) reconcileNS(ctx context.Context, sn *accuratev2
 					WithReason(accuratev2.SubNamespaceConflict).
 					WithMessage("Conflicting namespace already exists"),
 			),
+			conditionPatch(sn.Status.Conditions,
+				metav1ac.Condition().
+					WithType(accuratev2.SubNamespaceReady).
+					WithStatus(metav1.ConditionFalse).
+					WithObservedGeneration(sn.Generation).
+					WithReason(accuratev2.SubNamespaceConflict).
+					WithMessage(fmt.Sprintf("Namespace %s is not a child of %s", ns.Name, sn.Namespace)),
+			),
+		)
+		return r.Status().Apply(ctx, ac, fieldOwner, client.ForceOwnership)
+	}
+
+	path, err := r.namespacePath(ctx, ns)
+	if err != nil {
+		return err
+	}
+	var propagated int32
+	if r.Watches != nil {
+		propagated = r.Watches.CopyCount(ns.Name)
+	}
+	status.
+		WithNamespaceUID(ns.UID).
+		WithNamespacePhase(ns.Status.Phase).
+		WithPath(path...).
+		WithPropagatedResources(propagated)
+
+	if ns.DeletionTimestamp != nil || ns.Status.Phase == corev1.NamespaceTerminating {
+		// The namespace will be re-created when it is deleted.
+		status.WithConditions(
+			conditionPatch(sn.Status.Conditions,
+				metav1ac.Condition().
+					WithType(string(kstatus.ConditionReconciling)).
+					WithStatus(metav1.ConditionTrue).
+					WithObservedGeneration(sn.Generation).
+					WithReason(accuratev2.SubNamespaceTerminating).
+					WithMessage("Waiting for the namespace to be deleted to re-create it"),
+			),
+			conditionPatch(sn.Status.Conditions,
+				metav1ac.Condition().
+					WithType(accuratev2.SubNamespaceReady).
+					WithStatus(metav1.ConditionFalse).
+					WithObservedGeneration(sn.Generation).
+					WithReason(accuratev2.SubNamespaceTerminating).
+					WithMessage(fmt.Sprintf("Namespace %s is being deleted", ns.Name)),
+			),
 		)
+		return r.Status().Apply(ctx, ac, fieldOwner, client.ForceOwnership)
 	}
 
+	if progress != "" {
+		// The status update triggers another reconciliation, which makes the SubNamespace ready.
+		status.WithConditions(
+			conditionPatch(sn.Status.Conditions,
+				metav1ac.Condition().
+					WithType(string(kstatus.ConditionReconciling)).
+					WithStatus(metav1.ConditionTrue).
+					WithObservedGeneration(sn.Generation).
+					WithReason(progress).
+					WithMessage(fmt.Sprintf("Namespace %s is being set up", ns.Name)),
+			),
+			conditionPatch(sn.Status.Conditions,
+				metav1ac.Condition().
+					WithType(accuratev2.SubNamespaceReady).
+					WithStatus(metav1.ConditionFalse).
+					WithObservedGeneration(sn.Generation).
+					WithReason(progress).
+					WithMessage(fmt.Sprintf("Namespace %s is being set up", ns.Name)),
+			),
+		)
+		return r.Status().Apply(ctx, ac, fieldOwner, client.ForceOwnership)
+	}
+
+	status.WithConditions(
+		conditionPatch(sn.Status.Conditions,
+			metav1ac.Condition().
+				WithType(string(kstatus.ConditionReconciling)).
+				WithStatus(metav1.ConditionFalse).
+				WithObservedGeneration(sn.Generation).
+				WithReason(accuratev2.SubNamespaceActive).
+				WithMessage(fmt.Sprintf("Namespace %s is active", ns.Name)),
+		),
+		conditionPatch(sn.Status.Conditions,
+			metav1ac.Condition().
+				WithType(accuratev2.SubNamespaceReady).
+				WithStatus(metav1.ConditionTrue).
+				WithObservedGeneration(sn.Generation).
+				WithReason(accuratev2.SubNamespaceActive).
+				WithMessage(fmt.Sprintf("Namespace %s is active", ns.Name)),
+		),
+	)
 	return r.Status().Apply(ctx, ac, fieldOwner, client.ForceOwnership)
 }
 
+// createForAutoGrafted creates SubNamespace `key` for the namespace grafted by the auto-graft rules,
+// so that the namespace is managed in the same way as those created from SubNamespaces.
+// The annotation of the namespace is removed once the SubNamespace is created or denied.
+// If it is denied, the namespace is taken out of the tree as well.
+func (r *SubNamespaceReconciler) createForAutoGrafted(ctx context.Context, key types.NamespacedName) error {
+	logger := log.FromContext(ctx)
+
+	ns := &corev1.Namespace{}
+	if err := r.Get(ctx, client.ObjectKey{Name: key.Name}, ns); err != nil {
+		return client.IgnoreNotFound(err)
+	}
+	if ns.DeletionTimestamp != nil || ns.Annotations[constants.AnnAutoGrafted] == "" || ns.Labels[constants.LabelParent] != key.Namespace {
+		return nil
+	}
+
+	orig := ns.DeepCopy()
+	delete(ns.Annotations, constants.AnnAutoGrafted)
+
+	sn := &accuratev2.SubNamespace{}
+	sn.Namespace = key.Namespace
+	sn.Name = key.Name
+	if err := r.Create(ctx, sn); err != nil {
+		if !apierrors.IsForbidden(err) && !apierrors.IsInvalid(err) {
+			return err
+		}
+		logger.Info("creating SubNamespace denied", "error", err.Error())
+		r.recorder.Eventf(ns, nil, corev1.EventTypeWarning, reasonConflict, actionCreateSubNamespace,
+			"failed to create SubNamespace %s/%s; the namespace is not grafted: %v", sn.Namespace, sn.Name, err)
+		delete(ns.Labels, constants.LabelParent)
+	} else {
+		logger.Info("created SubNamespace for an auto-grafted namespace", "name", ns.Name)
+		r.recorder.Eventf(ns, sn, corev1.EventTypeNormal, reasonCreated, actionCreateSubNamespace,
+			"created SubNamespace %s/%s", sn.Namespace, sn.Name)
+	}
+
+	if err := r.Patch(ctx, ns, client.MergeFrom(orig)); err != nil {
+		return fmt.Errorf("failed to update auto-grafted namespace %s: %w", ns.Name, err)
+	}
+	return nil
+}
+
+// adopt makes an existing namespace `ns` a child of the namespace of `sn`.
+// The change is validated by the namespace webhook in the same way as users' changes.
+// If the webhook denies it, the reason is returned.
+func (r *SubNamespaceReconciler) adopt(ctx context.Context, sn *accuratev2.SubNamespace, ns *corev1.Namespace) (string, error) {
+	logger := log.FromContext(ctx)
+
+	orig := ns.DeepCopy()
+	if ns.Labels == nil {
+		ns.Labels = make(map[string]string)
+	}
+	ns.Labels[constants.LabelParent] = sn.Namespace
+	delete(ns.Labels, constants.LabelType)
+	delete(ns.Labels, constants.LabelTemplate)
+	delete(ns.Annotations, constants.AnnTemplates)
+	delete(ns.Annotations, constants.AnnAdoptableBy)
+	if err := r.Patch(ctx, ns, client.MergeFrom(orig)); err != nil {
+		if apierrors.IsForbidden(err) || apierrors.IsInvalid(err) {
+			logger.Info("adoption denied", "error", err.Error())
+			// report the denial only when it is newly found
+			cond := meta.FindStatusCondition(sn.Status.Conditions, string(kstatus.ConditionStalled))
+			if cond == nil || cond.Reason != accuratev2.SubNamespaceAdoptionDenied {
+				r.recorder.Eventf(sn, orig, corev1.EventTypeWarning, reasonConflict, actionAdoptNamespace,
+					"failed to adopt namespace %s: %v", ns.Name, err)
+			}
+			return err.Error(), nil
+		}
+		return "", fmt.Errorf("failed to adopt namespace %s: %w", ns.Name, err)
+	}
+
+	logger.Info("adopted namespace", "name", ns.Name)
+	r.recorder.Eventf(sn, ns, corev1.EventTypeNormal, reasonAdopted, actionAdoptNamespace,
+		"adopted namespace %s", ns.Name)
+	r.recorder.Eventf(ns, sn, corev1.EventTypeNormal, reasonAdopted, actionAdoptNamespace,
+		"adopted by SubNamespace %s/%s", sn.Namespace, sn.Name)
+	return "", nil
+}
+
+// namespacePath returns the names of the namespaces from the root namespace to `ns`.
+func (r *SubNamespaceReconciler) namespacePath(ctx context.Context, ns *corev1.Namespace) ([]string, error) {
+	path := []string{ns.Name}
+	for parent := ns.Labels[constants.LabelParent]; parent != ""; {
+		// guard against loops made by hand-edited labels
+		if slices.Contains(path, parent) {
+			break
+		}
+		path = append(path, parent)
+
+		p := &corev1.Namespace{}
+		if err := r.Get(ctx, client.ObjectKey{Name: parent}, p); err != nil {
+			if apierrors.IsNotFound(err) {
+				break
+			}
+			return nil, fmt.Errorf("failed to get namespace %s: %w", parent, err)
+		}
+		parent = p.Labels[constants.LabelParent]
+	}
+	slices.Reverse(path)
+	return path, nil
+}
+
 // SetupWithManager sets up the controller with the Manager.
 func (r *SubNamespaceReconciler) SetupWithManager(mgr ctrl.Manager) error {
 	nsHandler := func(ctx context.Context, o client.Object) (requests []reconcile.Request) {
@@ -169,6 +480,12 @@ func (r *SubNamespaceReconciler) SetupWithManager(mgr ctrl.Manager) error {
 				Name:      o.GetName(),
 			}})
 		}
+		if adopter := o.GetAnnotations()[constants.AnnAdoptableBy]; adopter != "" && adopter != parent {
+			requests = append(requests, reconcile.Request{NamespacedName: types.NamespacedName{
+				Namespace: adopter,
+				Name:      o.GetName(),
+			}})
+		}
 		if o.GetDeletionTimestamp() != nil {
 			// The namespace is in terminating state.
 			// Let's find all subnamespaces that might want to recreate it.
@@ -189,14 +506,36 @@ func (r *SubNamespaceReconciler) SetupWithManager(mgr ctrl.Manager) error {
 		return
 	}
 
-	return ctrl.NewControllerManagedBy(mgr).
+	// copyHandler updates the count of propagated resources of the SubNamespace of the copy's namespace.
+	copyHandler := func(ctx context.Context, o client.Object) []reconcile.Request {
+		ns := &corev1.Namespace{}
+		if err := r.Get(ctx, client.ObjectKey{Name: o.GetNamespace()}, ns); err != nil {
+			return nil
+		}
+		parent := ns.Labels[constants.LabelParent]
+		if parent == "" {
+			return nil
+		}
+		return []reconcile.Request{{NamespacedName: types.NamespacedName{
+			Namespace: parent,
+			Name:      ns.Name,
+		}}}
+	}
+
+	r.recorder = mgr.GetEventRecorder(eventSource)
+
+	b := ctrl.NewControllerManagedBy(mgr).
 		For(&accuratev2.SubNamespace{}).
 		Watches(&corev1.Namespace{}, handler.EnqueueRequestsFromMapFunc(nsHandler), builder.WithPredicates(predicate.Funcs{
 			CreateFunc: func(e event.TypedCreateEvent[client.Object]) bool {
-				return false
+				// namespaces grafted by the auto-graft rules need SubNamespaces
+				return e.Object.GetAnnotations()[constants.AnnAutoGrafted] != ""
 			},
-		})).
-		Complete(r)
+		}))
+	if r.Watches != nil {
+		b = b.WatchesRawSource(r.Watches.CopySource(handler.EnqueueRequestsFromMapFunc(copyHandler)))
+	}
+	return b.Complete(r)
 }
 
 func conditionPatch(existingConditions []metav1.Condition, condition *metav1ac.ConditionApplyConfiguration) *metav1ac.ConditionApplyConfiguration {
@@ -211,3 +550,9 @@ func conditionPatch(existingConditions []metav1.Condition, condition *metav1ac.C
 
 	return condition
 }
+
+// isConflicting returns true if `sn` is stalled because its namespace exists
+// but is not its child and cannot be adopted.
+func isConflicting(sn *accuratev2.SubNamespace) bool {
+	return meta.IsStatusConditionTrue(sn.Status.Conditions, string(kstatus.ConditionStalled))
+}
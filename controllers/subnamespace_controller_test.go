@@ -10,10 +10,17 @@ import (
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
 	corev1 "k8s.io/api/core/v1"
+	eventsv1 "k8s.io/api/events/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/utils/ptr"
+	kstatus "sigs.k8s.io/cli-utils/pkg/kstatus/status"
 	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/config"
 	"sigs.k8s.io/controller-runtime/pkg/envtest/komega"
 	"sigs.k8s.io/controller-runtime/pkg/metrics/server"
@@ -34,14 +41,25 @@ var _ = Describe("SubNamespace controller", func() {
 		})
 		Expect(err).ToNot(HaveOccurred())
 
+		watches, err := NewWatches(mgr)
+		Expect(err).NotTo(HaveOccurred())
+
 		snr := &SubNamespaceReconciler{
-			Client: mgr.GetClient(),
+			Client:  mgr.GetClient(),
+			Watches: watches,
 		}
 		err = snr.SetupWithManager(mgr)
 		Expect(err).ToNot(HaveOccurred())
 
 		err = indexing.SetupIndexForSubNamespace(ctx, mgr)
 		Expect(err).NotTo(HaveOccurred())
+		err = indexing.SetupIndexForNamespace(ctx, mgr)
+		Expect(err).NotTo(HaveOccurred())
+
+		cmRes := &unstructured.Unstructured{}
+		cmRes.SetGroupVersionKind(schema.GroupVersionKind{Version: "v1", Kind: "ConfigMap"})
+		err = watches.Add(ctx, []*unstructured.Unstructured{cmRes}, ResourceCloner{})
+		Expect(err).NotTo(HaveOccurred())
 
 		ctx, cancel := context.WithCancel(ctx)
 		stopFunc = cancel
@@ -76,14 +94,128 @@ var _ = Describe("SubNamespace controller", func() {
 
 		Expect(sub1.Labels).To(HaveKeyWithValue(constants.LabelCreatedBy, "accurate"))
 		Expect(sub1.Labels).To(HaveKeyWithValue(constants.LabelParent, "test1"))
-		Eventually(komega.Object(sn)).Should(HaveField("Status.ObservedGeneration", BeNumerically(">", 0)))
-		Expect(sn.Status.Conditions).To(BeEmpty())
+		Eventually(func() bool {
+			Expect(komega.Get(sn)()).To(Succeed())
+			return meta.IsStatusConditionTrue(sn.Status.Conditions, accuratev2.SubNamespaceReady)
+		}).Should(BeTrue())
+		Expect(meta.IsStatusConditionFalse(sn.Status.Conditions, string(kstatus.ConditionReconciling))).To(BeTrue())
+		Expect(sn.Status.NamespaceUID).To(Equal(sub1.UID))
+		Expect(sn.Status.NamespacePhase).To(Equal(corev1.NamespaceActive))
+		Expect(sn.Status.Path).To(Equal([]string{"test1", "test1-sub1"}))
+		Expect(sn.Status.PropagatedResources).To(BeZero())
+
+		evList := &eventsv1.EventList{}
+		Eventually(komega.ObjectList(evList, client.InNamespace("test1"))).Should(HaveField("Items", ContainElement(And(
+			HaveField("Regarding.Name", "test1-sub1"),
+			HaveField("Reason", "Created"),
+			HaveField("Type", corev1.EventTypeNormal),
+		))))
 
 		Expect(k8sClient.Delete(ctx, sn)).To(Succeed())
 
 		Eventually(komega.Object(sub1)).Should(HaveField("DeletionTimestamp", Not(BeNil())))
 	})
 
+	It("should count propagated resources", func() {
+		ns := &corev1.Namespace{}
+		ns.Name = "test9"
+		Expect(k8sClient.Create(ctx, ns)).To(Succeed())
+
+		sn := &accuratev2.SubNamespace{}
+		sn.Namespace = "test9"
+		sn.Name = "test9-sub1"
+		Expect(k8sClient.Create(ctx, sn)).To(Succeed())
+
+		sub1 := &corev1.Namespace{}
+		sub1.Name = "test9-sub1"
+		Eventually(komega.Get(sub1)).Should(Succeed())
+		Eventually(komega.Object(sn)).Should(HaveField("Status.ObservedGeneration", BeNumerically(">", 0)))
+		Expect(sn.Status.PropagatedResources).To(BeZero())
+
+		cm := &corev1.ConfigMap{}
+		cm.Namespace = "test9"
+		cm.Name = "test9-cm"
+		cm.Annotations = map[string]string{constants.AnnPropagate: constants.PropagateUpdate}
+		Expect(k8sClient.Create(ctx, cm)).To(Succeed())
+
+		Eventually(komega.Get(&corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Namespace: "test9-sub1", Name: "test9-cm"}})).Should(Succeed())
+		Eventually(komega.Object(sn)).Should(HaveField("Status.PropagatedResources", BeEquivalentTo(1)))
+
+		Expect(k8sClient.Delete(ctx, cm)).To(Succeed())
+		Eventually(komega.Object(sn)).Should(HaveField("Status.PropagatedResources", BeZero()))
+	})
+
+	It("should orphan sub-namespaces with the Orphan deletion policy", func() {
+		ns := &corev1.Namespace{}
+		ns.Name = "test5"
+		Expect(k8sClient.Create(ctx, ns)).To(Succeed())
+
+		sn := &accuratev2.SubNamespace{}
+		sn.Namespace = "test5"
+		sn.Name = "test5-sub1"
+		sn.Finalizers = []string{constants.Finalizer}
+		sn.Spec.DeletionPolicy = accuratev2.DeletionPolicyOrphan
+		Expect(k8sClient.Create(ctx, sn)).To(Succeed())
+
+		sub1 := &corev1.Namespace{}
+		sub1.Name = "test5-sub1"
+		Eventually(komega.Get(sub1)).Should(Succeed())
+		Expect(sub1.Labels).To(HaveKeyWithValue(constants.LabelParent, "test5"))
+
+		Expect(k8sClient.Delete(ctx, sn)).To(Succeed())
+		Eventually(komega.Get(sn)).Should(WithTransform(apierrors.IsNotFound, BeTrue()))
+
+		Expect(komega.Get(sub1)()).To(Succeed())
+		Expect(sub1.DeletionTimestamp).To(BeNil())
+		Expect(sub1.Labels).NotTo(HaveKey(constants.LabelParent))
+		Expect(sub1.Labels).NotTo(HaveKey(constants.LabelCreatedBy))
+
+		evList := &eventsv1.EventList{}
+		Eventually(komega.ObjectList(evList, client.InNamespace("test5"))).Should(HaveField("Items", ContainElement(And(
+			HaveField("Regarding.Name", "test5-sub1"),
+			HaveField("Reason", "Orphaned"),
+		))))
+	})
+
+	It("should keep propagated resources when orphaning sub-namespaces", func() {
+		ns := &corev1.Namespace{}
+		ns.Name = "test10"
+		Expect(k8sClient.Create(ctx, ns)).To(Succeed())
+
+		sn := &accuratev2.SubNamespace{}
+		sn.Namespace = "test10"
+		sn.Name = "test10-sub1"
+		sn.Finalizers = []string{constants.Finalizer}
+		sn.Spec.DeletionPolicy = accuratev2.DeletionPolicyOrphan
+		sn.Spec.KeepPropagatedResources = true
+		Expect(k8sClient.Create(ctx, sn)).To(Succeed())
+
+		sub1 := &corev1.Namespace{}
+		sub1.Name = "test10-sub1"
+		Eventually(komega.Get(sub1)).Should(Succeed())
+
+		cm := &corev1.ConfigMap{}
+		cm.Namespace = "test10"
+		cm.Name = "test10-cm"
+		cm.Annotations = map[string]string{constants.AnnPropagate: constants.PropagateCreate}
+		Expect(k8sClient.Create(ctx, cm)).To(Succeed())
+
+		copied := &corev1.ConfigMap{}
+		copied.Namespace = "test10-sub1"
+		copied.Name = "test10-cm"
+		Eventually(komega.Get(copied)).Should(Succeed())
+		Expect(copied.Labels).To(HaveKeyWithValue(constants.LabelCreatedBy, constants.CreatedBy))
+
+		Expect(k8sClient.Delete(ctx, sn)).To(Succeed())
+		Eventually(komega.Get(sn)).Should(WithTransform(apierrors.IsNotFound, BeTrue()))
+
+		Expect(komega.Get(copied)()).To(Succeed())
+		Expect(copied.DeletionTimestamp).To(BeNil())
+		Expect(copied.Annotations).NotTo(HaveKey(constants.AnnFrom))
+		Expect(copied.Annotations).NotTo(HaveKey(constants.AnnPropagate))
+		Expect(copied.Labels).NotTo(HaveKey(constants.LabelCreatedBy))
+	})
+
 	It("should detect conflicts", func() {
 		ns := &corev1.Namespace{}
 		ns.Name = "test2"
@@ -99,8 +231,13 @@ var _ = Describe("SubNamespace controller", func() {
 		Expect(k8sClient.Create(ctx, sn)).To(Succeed())
 
 		Eventually(komega.Object(sn)).Should(HaveField("Status.ObservedGeneration", BeNumerically(">", 0)))
-		Expect(sn.Status.Conditions).To(HaveLen(1))
-		Expect(sn.Status.Conditions[0].Reason).To(Equal(accuratev2.SubNamespaceConflict))
+		stalled := meta.FindStatusCondition(sn.Status.Conditions, string(kstatus.ConditionStalled))
+		Expect(stalled).NotTo(BeNil())
+		Expect(stalled.Reason).To(Equal(accuratev2.SubNamespaceConflict))
+		ready := meta.FindStatusCondition(sn.Status.Conditions, accuratev2.SubNamespaceReady)
+		Expect(ready).NotTo(BeNil())
+		Expect(ready.Status).To(Equal(metav1.ConditionFalse))
+		Expect(ready.Reason).To(Equal(accuratev2.SubNamespaceConflict))
 
 		// It's tempting to test if a conflict can be resolved by deleting the conflicting namespace,
 		// but this is currently not possible because EnvTest does not support namespace deletion.
@@ -108,6 +245,94 @@ var _ = Describe("SubNamespace controller", func() {
 		// This feature should be tested in e2e-tests.
 	})
 
+	It("should adopt an existing namespace that allows it", func() {
+		ns := &corev1.Namespace{}
+		ns.Name = "test6"
+		Expect(k8sClient.Create(ctx, ns)).To(Succeed())
+
+		ns2 := &corev1.Namespace{}
+		ns2.Name = "test6-sub1"
+		ns2.Labels = map[string]string{constants.LabelType: constants.NSTypeRoot, "team": "a"}
+		Expect(k8sClient.Create(ctx, ns2)).To(Succeed())
+
+		sn := &accuratev2.SubNamespace{}
+		sn.Namespace = "test6"
+		sn.Name = "test6-sub1"
+		Expect(k8sClient.Create(ctx, sn)).To(Succeed())
+
+		Eventually(komega.Object(sn)).Should(HaveField("Status.Conditions", ContainElement(And(
+			HaveField("Type", string(kstatus.ConditionStalled)),
+			HaveField("Reason", accuratev2.SubNamespaceConflict),
+		))))
+
+		Expect(komega.Update(ns2, func() {
+			ns2.Annotations = map[string]string{constants.AnnAdoptableBy: "test6"}
+		})()).To(Succeed())
+
+		Eventually(komega.Object(ns2)).Should(HaveField("Labels", HaveKeyWithValue(constants.LabelParent, "test6")))
+		Expect(ns2.Labels).NotTo(HaveKey(constants.LabelType))
+		Expect(ns2.Labels).To(HaveKeyWithValue("team", "a"))
+		Expect(ns2.Annotations).NotTo(HaveKey(constants.AnnAdoptableBy))
+
+		Eventually(func() bool {
+			Expect(komega.Get(sn)()).To(Succeed())
+			return meta.IsStatusConditionTrue(sn.Status.Conditions, accuratev2.SubNamespaceReady)
+		}).Should(BeTrue())
+		Expect(meta.FindStatusCondition(sn.Status.Conditions, string(kstatus.ConditionStalled))).To(BeNil())
+
+		evList := &eventsv1.EventList{}
+		Eventually(komega.ObjectList(evList, client.InNamespace("test6"))).Should(HaveField("Items", ContainElement(And(
+			HaveField("Regarding.Name", "test6-sub1"),
+			HaveField("Reason", "Adopted"),
+		))))
+	})
+
+	It("should create a SubNamespace for an auto-grafted namespace", func() {
+		ns := &corev1.Namespace{}
+		ns.Name = "test7"
+		Expect(k8sClient.Create(ctx, ns)).To(Succeed())
+
+		ns2 := &corev1.Namespace{}
+		ns2.Name = "test7-sub1"
+		ns2.Labels = map[string]string{constants.LabelParent: "test7"}
+		ns2.Annotations = map[string]string{constants.AnnAutoGrafted: "true"}
+		Expect(k8sClient.Create(ctx, ns2)).To(Succeed())
+
+		sn := &accuratev2.SubNamespace{}
+		sn.Namespace = "test7"
+		sn.Name = "test7-sub1"
+		Eventually(komega.Get(sn)).Should(Succeed())
+		Eventually(komega.Object(ns2)).Should(HaveField("Annotations", Not(HaveKey(constants.AnnAutoGrafted))))
+
+		Eventually(func() bool {
+			Expect(komega.Get(sn)()).To(Succeed())
+			return meta.IsStatusConditionTrue(sn.Status.Conditions, accuratev2.SubNamespaceReady)
+		}).Should(BeTrue())
+	})
+
+	It("should not graft an auto-grafted namespace if its SubNamespace is denied", func() {
+		ns := &corev1.Namespace{}
+		ns.Name = "test8"
+		Expect(k8sClient.Create(ctx, ns)).To(Succeed())
+		// Nothing can be created in a terminating namespace.
+		Expect(k8sClient.Delete(ctx, ns)).To(Succeed())
+
+		ns2 := &corev1.Namespace{}
+		ns2.Name = "test8-sub1"
+		ns2.Labels = map[string]string{constants.LabelParent: "test8"}
+		ns2.Annotations = map[string]string{constants.AnnAutoGrafted: "true"}
+		Expect(k8sClient.Create(ctx, ns2)).To(Succeed())
+
+		Eventually(komega.Object(ns2)).Should(And(
+			HaveField("Labels", Not(HaveKey(constants.LabelParent))),
+			HaveField("Annotations", Not(HaveKey(constants.AnnAutoGrafted))),
+		))
+		sn := &accuratev2.SubNamespace{}
+		sn.Namespace = "test8"
+		sn.Name = "test8-sub1"
+		Expect(apierrors.IsNotFound(komega.Get(sn)())).To(BeTrue())
+	})
+
 	It("should not delete a conflicting sub-namespace", func() {
 		ns := &corev1.Namespace{}
 		ns.Name = "test3"
@@ -127,8 +352,11 @@ var _ = Describe("SubNamespace controller", func() {
 			sub1.Labels[constants.LabelParent] = "foo"
 		})()).To(Succeed())
 
-		Eventually(komega.Object(sn)).Should(HaveField("Status.Conditions", HaveLen(1)))
-		Expect(sn.Status.Conditions[0].Reason).To(Equal(accuratev2.SubNamespaceConflict))
+		Eventually(func() bool {
+			Expect(komega.Get(sn)()).To(Succeed())
+			return meta.IsStatusConditionTrue(sn.Status.Conditions, string(kstatus.ConditionStalled))
+		}).Should(BeTrue())
+		Expect(meta.IsStatusConditionFalse(sn.Status.Conditions, accuratev2.SubNamespaceReady)).To(BeTrue())
 
 		Expect(k8sClient.Delete(ctx, sn)).To(Succeed())
 
@@ -0,0 +1,227 @@
+package controllers
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"text/template"
+
+	"github.com/cybozu-go/accurate/internal/util/templates"
+	"github.com/cybozu-go/accurate/pkg/constants"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/tools/events"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// templateData is the data available to propagated resources annotated
+// with `accurate.cybozu.com/propagate-template=true`.
+type templateData struct {
+	// Namespace is the name of the namespace receiving the copy.
+	Namespace string
+	// Parent is the name of the parent or the template namespace.
+	Parent string
+	// Root is the name of the root namespace of the tree.
+	Root string
+	// Depth is the number of levels between the root and the namespace.
+	Depth int
+	// Labels are the namespace labels selected by TemplateLabelKeys.
+	Labels map[string]string
+	// Annotations are the namespace annotations selected by TemplateAnnotationKeys.
+	Annotations map[string]string
+}
+
+func isTemplate(res *unstructured.Unstructured) bool {
+	return res.GetAnnotations()[constants.AnnPropagateTemplate] == "true"
+}
+
+// cloneAndRender returns a copy of `res` for namespace `ns`.
+// If `res` is a template, string fields of the copy are rendered against `ns`.
+func (rc *ResourceCloner) cloneAndRender(ctx context.Context, r client.Reader, res *unstructured.Unstructured, ns string) (*unstructured.Unstructured, error) {
+	if mode := res.GetAnnotations()[constants.AnnPropagate]; mode != "" && !rc.modeAllowed(res, mode) {
+		return nil, fmt.Errorf("%w: %s", errModeNotAllowed, mode)
+	}
+	if !isTemplate(res) {
+		return rc.cloneResource(res, ns), nil
+	}
+
+	// Copies of a template have already been rendered for their own namespace,
+	// so the copy for `ns` must be made from the original source.
+	origin, err := rc.templateOrigin(ctx, r, res)
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := rc.templateData(ctx, r, ns)
+	if err != nil {
+		return nil, err
+	}
+
+	c := rc.cloneResource(origin, ns)
+	ann := c.GetAnnotations()
+	ann[constants.AnnFrom] = res.GetNamespace()
+	setRemainingDepth(ann, res)
+	c.SetAnnotations(ann)
+
+	for k, v := range c.Object {
+		switch k {
+		case "apiVersion", "kind", "metadata":
+			continue
+		}
+		rendered, err := renderValue(v, data)
+		if err != nil {
+			return nil, fmt.Errorf("failed to render %s: %w", k, err)
+		}
+		c.Object[k] = rendered
+	}
+
+	labels, err := renderStringMap(c.GetLabels(), data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to render labels: %w", err)
+	}
+	c.SetLabels(labels)
+	annotations, err := renderStringMap(c.GetAnnotations(), data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to render annotations: %w", err)
+	}
+	c.SetAnnotations(annotations)
+
+	return c, nil
+}
+
+// cloneFailed reports the failure of cloneAndRender to make a copy of `src` for namespace `ns`.
+// The event is recorded for `regarding`, which differs from `src` for virtual sources.
+func cloneFailed(recorder events.EventRecorder, regarding runtime.Object, src *unstructured.Unstructured, ns string, err error) {
+	mode := src.GetAnnotations()[constants.AnnPropagate]
+	if errors.Is(err, errModeNotAllowed) {
+		recorder.Eventf(regarding, nil, corev1.EventTypeWarning, reasonModeNotAllowed, actionPropagate,
+			"propagation mode %s is not allowed for %s", mode, src.GetKind())
+		recordFailure(src, mode, failureModeNotAllowed)
+		return
+	}
+	recorder.Eventf(regarding, nil, corev1.EventTypeWarning, reasonRenderFailed, actionPropagate,
+		"failed to render the template for namespace %s: %v", ns, err)
+	recordFailure(src, mode, failureRender)
+}
+
+func (rc *ResourceCloner) templateOrigin(ctx context.Context, r client.Reader, res *unstructured.Unstructured) (*unstructured.Unstructured, error) {
+	seen := map[string]bool{res.GetNamespace(): true}
+	for res.GetAnnotations()[constants.AnnFrom] != "" && isTemplate(res) {
+		from := res.GetAnnotations()[constants.AnnFrom]
+		if seen[from] {
+			return nil, fmt.Errorf("circular reference of %s found in %s", res.GetName(), from)
+		}
+		seen[from] = true
+
+		p := &unstructured.Unstructured{}
+		p.SetGroupVersionKind(res.GroupVersionKind())
+		if err := r.Get(ctx, client.ObjectKey{Namespace: from, Name: res.GetName()}, p); err != nil {
+			return nil, fmt.Errorf("failed to get the source of %s/%s: %w", res.GetNamespace(), res.GetName(), err)
+		}
+		res = p
+	}
+	return res, nil
+}
+
+func (rc *ResourceCloner) templateData(ctx context.Context, r client.Reader, name string) (*templateData, error) {
+	ns := &corev1.Namespace{}
+	if err := r.Get(ctx, client.ObjectKey{Name: name}, ns); err != nil {
+		return nil, fmt.Errorf("failed to get namespace %s: %w", name, err)
+	}
+
+	data := &templateData{
+		Namespace:   ns.Name,
+		Parent:      ns.Labels[constants.LabelParent],
+		Root:        ns.Name,
+		Labels:      make(map[string]string),
+		Annotations: make(map[string]string),
+	}
+	if tmpls := templates.Names(ns); data.Parent == "" && len(tmpls) > 0 {
+		// the template of the highest precedence
+		data.Parent = tmpls[len(tmpls)-1]
+	}
+	for k, v := range ns.Labels {
+		if matchKey(k, rc.TemplateLabelKeys) {
+			data.Labels[k] = v
+		}
+	}
+	for k, v := range ns.Annotations {
+		if matchKey(k, rc.TemplateAnnotationKeys) {
+			data.Annotations[k] = v
+		}
+	}
+
+	seen := map[string]bool{ns.Name: true}
+	for p := ns.Labels[constants.LabelParent]; p != ""; {
+		if seen[p] {
+			return nil, fmt.Errorf("circular reference found in the ancestors of %s", name)
+		}
+		seen[p] = true
+
+		parent := &corev1.Namespace{}
+		if err := r.Get(ctx, client.ObjectKey{Name: p}, parent); err != nil {
+			return nil, fmt.Errorf("failed to get namespace %s: %w", p, err)
+		}
+		data.Root = parent.Name
+		data.Depth++
+		p = parent.Labels[constants.LabelParent]
+	}
+
+	return data, nil
+}
+
+func renderValue(v any, data *templateData) (any, error) {
+	switch v := v.(type) {
+	case string:
+		return renderString(v, data)
+	case map[string]any:
+		for k, e := range v {
+			rendered, err := renderValue(e, data)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", k, err)
+			}
+			v[k] = rendered
+		}
+		return v, nil
+	case []any:
+		for i, e := range v {
+			rendered, err := renderValue(e, data)
+			if err != nil {
+				return nil, fmt.Errorf("[%d]: %w", i, err)
+			}
+			v[i] = rendered
+		}
+		return v, nil
+	}
+	return v, nil
+}
+
+func renderStringMap(m map[string]string, data *templateData) (map[string]string, error) {
+	for k, v := range m {
+		rendered, err := renderString(v, data)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", k, err)
+		}
+		m[k] = rendered
+	}
+	return m, nil
+}
+
+func renderString(s string, data *templateData) (string, error) {
+	if !strings.Contains(s, "{{") {
+		return s, nil
+	}
+
+	tmpl, err := template.New("").Option("missingkey=error").Parse(s)
+	if err != nil {
+		return "", err
+	}
+	buf := &bytes.Buffer{}
+	if err := tmpl.Execute(buf, data); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
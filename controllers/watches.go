@@ -0,0 +1,288 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"sync"
+
+	"github.com/cybozu-go/accurate/pkg/constants"
+	"github.com/cybozu-go/accurate/pkg/indexing"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/util/workqueue"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sigs.k8s.io/controller-runtime/pkg/source"
+)
+
+// Watches runs PropagateControllers for watched resources.
+// Unlike controllers set up by SetupWithManager, the controllers can be
+// added and removed while the manager is running.
+type Watches struct {
+	mgr ctrl.Manager
+
+	mu        sync.Mutex
+	ctx       context.Context
+	resources []*unstructured.Unstructured
+	running   map[schema.GroupVersionKind]*watch
+	copies    chan event.GenericEvent
+
+	// countMu protects counted.  It is separated from mu because
+	// the event handlers of the controllers run while mu is held to stop them.
+	countMu sync.Mutex
+	// counted is the set of copies in each namespace.
+	counted map[string]map[copyKey]bool
+}
+
+// copyKey identifies a copy in a namespace.
+type copyKey struct {
+	gvk  schema.GroupVersionKind
+	name string
+}
+
+type watch struct {
+	res    *unstructured.Unstructured
+	pc     *PropagateController
+	ctrl   controller.Controller
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+var _ manager.LeaderElectionRunnable = &Watches{}
+
+// NewWatches creates Watches and adds it to `mgr`.
+// The controllers are started when `mgr` becomes the leader.
+func NewWatches(mgr ctrl.Manager) (*Watches, error) {
+	w := &Watches{
+		mgr:     mgr,
+		running: make(map[schema.GroupVersionKind]*watch),
+		counted: make(map[string]map[copyKey]bool),
+	}
+	if err := mgr.Add(w); err != nil {
+		return nil, err
+	}
+	return w, nil
+}
+
+// Resources returns the watched resources.
+func (w *Watches) Resources() []*unstructured.Unstructured {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return slices.Clone(w.resources)
+}
+
+// CopyCount returns the number of copies of the watched resources in namespace `ns`.
+// The copies are counted from the events of the running controllers.
+func (w *Watches) CopyCount(ns string) int32 {
+	w.countMu.Lock()
+	defer w.countMu.Unlock()
+	return int32(len(w.counted[ns]))
+}
+
+// CopySource returns a source of events for propagated copies of the watched resources.
+// The events are sent when copies are created or deleted.
+// It must be called before resources are added.
+func (w *Watches) CopySource(h handler.EventHandler) source.Source {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.copies == nil {
+		w.copies = make(chan event.GenericEvent)
+	}
+	return source.Channel(w.copies, h)
+}
+
+// Add sets up indexers and controllers for resources in `watched` that are not watched yet.
+// Controllers for resources already watched use `cloner` afterwards.
+// If it fails, nothing is changed.
+func (w *Watches) Add(ctx context.Context, watched []*unstructured.Unstructured, cloner ResourceCloner) error {
+	logger := log.FromContext(ctx)
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	var added []*watch
+	for _, res := range watched {
+		gvk := res.GroupVersionKind()
+		if _, ok := w.running[gvk]; ok {
+			continue
+		}
+		wt, err := w.newWatch(ctx, res, cloner)
+		if err != nil {
+			for _, wt := range append(added, &watch{res: res}) {
+				if err := w.mgr.GetCache().RemoveInformer(ctx, wt.res); err != nil {
+					logger.Error(err, "failed to remove informer", "gvk", wt.res.GroupVersionKind().String())
+				}
+			}
+			return err
+		}
+		added = append(added, wt)
+	}
+
+	for _, wt := range w.running {
+		wt.pc.setResourceCloner(cloner)
+	}
+	for _, wt := range added {
+		w.running[wt.res.GroupVersionKind()] = wt
+		w.resources = append(w.resources, wt.res)
+		if w.ctx != nil {
+			w.start(wt)
+		}
+		logger.Info("watching", "gvk", wt.res.GroupVersionKind().String())
+	}
+	return nil
+}
+
+// Prune stops controllers and informers for resources not in `watched`.
+func (w *Watches) Prune(ctx context.Context, watched []*unstructured.Unstructured) error {
+	logger := log.FromContext(ctx)
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	keep := make(map[schema.GroupVersionKind]bool)
+	for _, res := range watched {
+		keep[res.GroupVersionKind()] = true
+	}
+	w.resources = slices.DeleteFunc(w.resources, func(res *unstructured.Unstructured) bool {
+		return !keep[res.GroupVersionKind()]
+	})
+
+	for gvk, wt := range w.running {
+		if keep[gvk] {
+			continue
+		}
+		if wt.cancel != nil {
+			wt.cancel()
+			<-wt.done
+		}
+		delete(w.running, gvk)
+		w.uncount(gvk)
+		if err := w.mgr.GetCache().RemoveInformer(ctx, wt.res); err != nil {
+			return fmt.Errorf("failed to remove informer for %s: %w", gvk.String(), err)
+		}
+		logger.Info("stopped watching", "gvk", gvk.String())
+	}
+	return nil
+}
+
+func (w *Watches) newWatch(ctx context.Context, res *unstructured.Unstructured, cloner ResourceCloner) (*watch, error) {
+	gvk := res.GroupVersionKind()
+	if err := indexing.SetupIndexForResource(ctx, w.mgr, res); err != nil {
+		return nil, fmt.Errorf("failed to setup indexer for %s: %w", gvk.String(), err)
+	}
+	pc := NewPropagateController(res, cloner)
+	c, err := pc.newUnmanagedController(w.mgr, w.copyHandler(gvk, w.copies))
+	if err != nil {
+		return nil, fmt.Errorf("unable to create %s controller: %w", gvk.String(), err)
+	}
+	return &watch{res: res, pc: pc, ctrl: c}, nil
+}
+
+// copyHandler returns a handler that counts copies of `gvk` and sends their events to `copies` if it is not nil.
+// Updates are handled only when objects become copies or stop being copies.
+func (w *Watches) copyHandler(gvk schema.GroupVersionKind, copies chan<- event.GenericEvent) handler.Funcs {
+	isCopy := func(obj client.Object) bool {
+		return obj.GetAnnotations()[constants.AnnFrom] != ""
+	}
+	update := func(ctx context.Context, obj client.Object, counted bool) {
+		w.count(obj.GetNamespace(), copyKey{gvk: gvk, name: obj.GetName()}, counted)
+		if copies == nil {
+			return
+		}
+		select {
+		case copies <- event.GenericEvent{Object: obj}:
+		case <-ctx.Done():
+		}
+	}
+
+	return handler.Funcs{
+		CreateFunc: func(ctx context.Context, e event.CreateEvent, _ workqueue.TypedRateLimitingInterface[reconcile.Request]) {
+			if isCopy(e.Object) {
+				update(ctx, e.Object, true)
+			}
+		},
+		UpdateFunc: func(ctx context.Context, e event.UpdateEvent, _ workqueue.TypedRateLimitingInterface[reconcile.Request]) {
+			if isCopy(e.ObjectOld) != isCopy(e.ObjectNew) {
+				update(ctx, e.ObjectNew, isCopy(e.ObjectNew))
+			}
+		},
+		DeleteFunc: func(ctx context.Context, e event.DeleteEvent, _ workqueue.TypedRateLimitingInterface[reconcile.Request]) {
+			if isCopy(e.Object) {
+				update(ctx, e.Object, false)
+			}
+		},
+	}
+}
+
+func (w *Watches) count(ns string, key copyKey, counted bool) {
+	w.countMu.Lock()
+	defer w.countMu.Unlock()
+	if !counted {
+		delete(w.counted[ns], key)
+		if len(w.counted[ns]) == 0 {
+			delete(w.counted, ns)
+		}
+		return
+	}
+	if w.counted[ns] == nil {
+		w.counted[ns] = make(map[copyKey]bool)
+	}
+	w.counted[ns][key] = true
+}
+
+// uncount forgets the copies of `gvk` that are no longer watched.
+func (w *Watches) uncount(gvk schema.GroupVersionKind) {
+	w.countMu.Lock()
+	defer w.countMu.Unlock()
+	for ns, keys := range w.counted {
+		for key := range keys {
+			if key.gvk == gvk {
+				delete(keys, key)
+			}
+		}
+		if len(keys) == 0 {
+			delete(w.counted, ns)
+		}
+	}
+}
+
+func (w *Watches) start(wt *watch) {
+	ctx, cancel := context.WithCancel(w.ctx)
+	wt.cancel = cancel
+	wt.done = make(chan struct{})
+	go func() {
+		defer close(wt.done)
+		if err := wt.ctrl.Start(ctx); err != nil {
+			w.mgr.GetLogger().Error(err, "failed to run controller", "gvk", wt.res.GroupVersionKind().String())
+		}
+	}()
+}
+
+// Start implements manager.Runnable interface.
+func (w *Watches) Start(ctx context.Context) error {
+	w.mu.Lock()
+	w.ctx = ctx
+	for _, wt := range w.running {
+		w.start(wt)
+	}
+	w.mu.Unlock()
+
+	<-ctx.Done()
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	for _, wt := range w.running {
+		<-wt.done
+	}
+	return nil
+}
+
+// NeedLeaderElection implements manager.LeaderElectionRunnable interface.
+func (w *Watches) NeedLeaderElection() bool {
+	return true
+}
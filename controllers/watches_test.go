@@ -0,0 +1,111 @@
+package controllers
+
+import (
+	"context"
+	"time"
+
+	"github.com/cybozu-go/accurate/pkg/constants"
+	"github.com/cybozu-go/accurate/pkg/indexing"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/utils/ptr"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/config"
+	"sigs.k8s.io/controller-runtime/pkg/envtest/komega"
+	"sigs.k8s.io/controller-runtime/pkg/metrics/server"
+)
+
+var _ = Describe("Watches", func() {
+	ctx := context.Background()
+	var stopFunc func()
+	var watches *Watches
+
+	cmRes := &unstructured.Unstructured{}
+	cmRes.SetGroupVersionKind(schema.GroupVersionKind{
+		Group:   corev1.GroupName,
+		Version: corev1.SchemeGroupVersion.Version,
+		Kind:    "ConfigMap",
+	})
+
+	BeforeEach(func() {
+		mgr, err := ctrl.NewManager(k8sCfg, ctrl.Options{
+			Scheme:         scheme,
+			LeaderElection: false,
+			Metrics:        server.Options{BindAddress: "0"},
+			Controller: config.Controller{
+				SkipNameValidation: ptr.To(true),
+			},
+		})
+		Expect(err).ToNot(HaveOccurred())
+
+		err = indexing.SetupIndexForNamespace(ctx, mgr)
+		Expect(err).NotTo(HaveOccurred())
+
+		watches, err = NewWatches(mgr)
+		Expect(err).NotTo(HaveOccurred())
+
+		ctx, cancel := context.WithCancel(ctx)
+		stopFunc = cancel
+		go func() {
+			err := mgr.Start(ctx)
+			if err != nil {
+				panic(err)
+			}
+		}()
+		time.Sleep(100 * time.Millisecond)
+	})
+
+	AfterEach(func() {
+		stopFunc()
+		time.Sleep(100 * time.Millisecond)
+	})
+
+	It("should start and stop watching resources while running", func() {
+		root := &corev1.Namespace{}
+		root.Name = "watches-root"
+		root.Labels = map[string]string{constants.LabelType: constants.NSTypeRoot}
+		Expect(k8sClient.Create(ctx, root)).To(Succeed())
+		sub := &corev1.Namespace{}
+		sub.Name = "watches-sub"
+		sub.Labels = map[string]string{constants.LabelParent: "watches-root"}
+		Expect(k8sClient.Create(ctx, sub)).To(Succeed())
+
+		By("adding a resource to watch")
+		Expect(watches.Add(ctx, []*unstructured.Unstructured{cmRes}, ResourceCloner{})).To(Succeed())
+		Expect(watches.Resources()).To(HaveLen(1))
+
+		cm1 := &corev1.ConfigMap{}
+		cm1.Namespace = "watches-root"
+		cm1.Name = "cm1"
+		cm1.Annotations = map[string]string{constants.AnnPropagate: constants.PropagateCreate}
+		Expect(k8sClient.Create(ctx, cm1)).To(Succeed())
+
+		cm1Sub := &corev1.ConfigMap{}
+		cm1Sub.Namespace = "watches-sub"
+		cm1Sub.Name = "cm1"
+		Eventually(komega.Get(cm1Sub)).Should(Succeed())
+
+		By("removing the resource from the watch list")
+		Expect(watches.Prune(ctx, nil)).To(Succeed())
+		Expect(watches.Resources()).To(BeEmpty())
+
+		cm2 := &corev1.ConfigMap{}
+		cm2.Namespace = "watches-root"
+		cm2.Name = "cm2"
+		cm2.Annotations = map[string]string{constants.AnnPropagate: constants.PropagateCreate}
+		Expect(k8sClient.Create(ctx, cm2)).To(Succeed())
+
+		cm2Sub := &corev1.ConfigMap{}
+		cm2Sub.Namespace = "watches-sub"
+		cm2Sub.Name = "cm2"
+		Consistently(komega.Get(cm2Sub)).Should(WithTransform(apierrors.IsNotFound, BeTrue()))
+
+		By("adding the resource again")
+		Expect(watches.Add(ctx, []*unstructured.Unstructured{cmRes}, ResourceCloner{})).To(Succeed())
+		Eventually(komega.Get(cm2Sub)).Should(Succeed())
+	})
+})
@@ -0,0 +1,65 @@
+package hooks
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	accuratev2 "github.com/cybozu-go/accurate/api/accurate/v2"
+	"github.com/cybozu-go/accurate/pkg/config"
+	admissionv1 "k8s.io/api/admission/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+	"sigs.k8s.io/controller-runtime/pkg/webhook"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+)
+
+//+kubebuilder:webhook:path=/validate-accurate-cybozu-com-v2-accurateconfig,mutating=false,failurePolicy=fail,sideEffects=None,groups=accurate.cybozu.com,resources=accurateconfigs,verbs=create;update,versions=v2,matchPolicy=Equivalent,name=vaccurateconfig.kb.io,admissionReviewVersions={v1}
+
+type accurateConfigValidator struct {
+	client.Client
+	mapper meta.RESTMapper
+	dec    admission.Decoder
+}
+
+var _ admission.Handler = &accurateConfigValidator{}
+
+// Validate AccurateConfig in the same way as the configuration file.
+func (v *accurateConfigValidator) Handle(ctx context.Context, req admission.Request) admission.Response {
+	if req.Operation != admissionv1.Create && req.Operation != admissionv1.Update {
+		return admission.Allowed("")
+	}
+
+	ac := &accuratev2.AccurateConfig{}
+	if err := v.dec.Decode(req, ac); err != nil {
+		return admission.Errored(http.StatusBadRequest, err)
+	}
+
+	if ac.Name != accuratev2.AccurateConfigName {
+		return admission.Denied(fmt.Sprintf("the name of AccurateConfig must be %s", accuratev2.AccurateConfigName))
+	}
+
+	cfg := &config.Config{}
+	if err := cfg.LoadResource(ac); err != nil {
+		return admission.Denied(err.Error())
+	}
+	if err := cfg.Validate(v.mapper); err != nil {
+		return admission.Denied(fmt.Sprintf("invalid configurations: %v", err))
+	}
+	if err := cfg.ValidateRBAC(ctx, v.Client, v.mapper); err != nil {
+		return admission.Denied(fmt.Sprintf("when validating RBAC to support configuration: %v", err))
+	}
+	return admission.Allowed("")
+}
+
+// SetupAccurateConfigWebhook registers the webhook for AccurateConfig
+func SetupAccurateConfigWebhook(mgr manager.Manager, dec admission.Decoder) {
+	v := &accurateConfigValidator{
+		Client: mgr.GetClient(),
+		mapper: mgr.GetRESTMapper(),
+		dec:    dec,
+	}
+	serv := mgr.GetWebhookServer()
+	serv.Register("/validate-accurate-cybozu-com-v2-accurateconfig", &webhook.Admission{Handler: v})
+}
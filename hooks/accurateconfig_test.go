@@ -0,0 +1,35 @@
+package hooks
+
+import (
+	"context"
+
+	accuratev2 "github.com/cybozu-go/accurate/api/accurate/v2"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("AccurateConfig webhook", func() {
+	ctx := context.Background()
+
+	It("should validate the configurations", func() {
+		ac := &accuratev2.AccurateConfig{}
+		ac.Name = accuratev2.AccurateConfigName
+		ac.Spec.Watches = []accuratev2.AccurateConfigWatch{
+			{Group: "rbac.authorization.k8s.io", Version: "v1", Kind: "ClusterRole"},
+		}
+		Expect(k8sClient.Create(ctx, ac)).To(MatchError(ContainSubstring("ClusterRole is not namespace-scoped")))
+
+		ac.Spec.Watches = []accuratev2.AccurateConfigWatch{
+			{Version: "v1", Kind: "ConfigMap"},
+		}
+		Expect(k8sClient.Create(ctx, ac)).To(Succeed())
+
+		By("updating with an invalid naming policy")
+		ac.Spec.NamingPolicies = []accuratev2.AccurateConfigNamingPolicy{
+			{Root: "(", Match: "foo"},
+		}
+		Expect(k8sClient.Update(ctx, ac)).To(MatchError(ContainSubstring("invalid configurations")))
+
+		Expect(k8sClient.Delete(ctx, ac)).To(Succeed())
+	})
+})
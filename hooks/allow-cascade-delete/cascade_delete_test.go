@@ -87,5 +87,27 @@ var _ = Describe("Webhook allow cascade delete", func() {
 
 			Expect(k8sClient.Delete(ctx, sub)).To(Succeed())
 		})
+
+		It("should DENY orphaning with child namespaces", func() {
+			sub := &accuratev2.SubNamespace{}
+			sub.Namespace = root.Name
+			sub.GenerateName = "cascade-sub-"
+			sub.Spec.DeletionPolicy = accuratev2.DeletionPolicyOrphan
+			Expect(k8sClient.Create(ctx, sub)).To(Succeed())
+			// Create sub-namespace since no controllers present in this test setup
+			subNS := &corev1.Namespace{}
+			subNS.Name = sub.Name
+			subNS.Labels = map[string]string{constants.LabelParent: root.Name}
+			Expect(k8sClient.Create(ctx, subNS)).To(Succeed())
+
+			ns := &corev1.Namespace{}
+			ns.GenerateName = "cascade-sub-sub-"
+			ns.Labels = map[string]string{constants.LabelParent: subNS.Name}
+			Expect(k8sClient.Create(ctx, ns)).To(Succeed())
+
+			err := k8sClient.Delete(ctx, sub)
+			Expect(err).To(HaveOccurred())
+			Expect(errors.ReasonForError(err)).Should(Equal(metav1.StatusReasonForbidden))
+		})
 	})
 })
@@ -102,10 +102,10 @@ var _ = BeforeSuite(func() {
 	Expect(err).NotTo(HaveOccurred())
 
 	dec := admission.NewDecoder(scheme)
-	hooks.SetupNamespaceWebhook(mgr, dec, true)
+	hooks.SetupNamespaceWebhook(mgr, dec, nil, nil, true, false)
 
 	Expect(err).NotTo(HaveOccurred())
-	err = hooks.SetupSubNamespaceWebhook(mgr, dec, nil, true)
+	err = hooks.SetupSubNamespaceWebhook(mgr, dec, nil, nil, true)
 	Expect(err).NotTo(HaveOccurred())
 
 	go func() {
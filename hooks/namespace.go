@@ -2,9 +2,14 @@ package hooks
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"net/http"
+	"sync"
 
+	accuratev2 "github.com/cybozu-go/accurate/api/accurate/v2"
+	"github.com/cybozu-go/accurate/internal/util/templates"
+	"github.com/cybozu-go/accurate/pkg/config"
 	"github.com/cybozu-go/accurate/pkg/constants"
 	admissionv1 "k8s.io/api/admission/v1"
 	corev1 "k8s.io/api/core/v1"
@@ -15,12 +20,115 @@ import (
 	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
 )
 
+// AutoGraftWebhookPath is the path of the webhook that grafts new namespaces by the auto-graft rules.
+//
+// There is no kubebuilder marker for this webhook because it is installed only when
+// auto-graft is used.  The Helm chart renders the webhook configuration.
+const AutoGraftWebhookPath = "/mutate-v1-namespace"
+
+// AutoGraftRules holds the rules to graft new namespaces by their names.
+type AutoGraftRules struct {
+	mu    sync.RWMutex
+	rules []config.AutoGraftRuleRegexp
+}
+
+// NewAutoGraftRules creates AutoGraftRules from `cfg`.
+func NewAutoGraftRules(cfg *config.Config) *AutoGraftRules {
+	r := &AutoGraftRules{}
+	r.Update(cfg)
+	return r
+}
+
+// Update replaces the rules with those of `cfg`.
+func (r *AutoGraftRules) Update(cfg *config.Config) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.rules = cfg.AutoGraftRuleRegexps
+}
+
+func (r *AutoGraftRules) parent(ns string) string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return config.AutoGraftParent(r.rules, ns)
+}
+
+type namespaceMutator struct {
+	client.Client
+	dec      admission.Decoder
+	rules    *AutoGraftRules
+	policies *SubNamespacePolicies
+}
+
+var _ admission.Handler = &namespaceMutator{}
+
+// Handle makes a new namespace a sub-namespace if its name matches the auto-graft rules.
+// Namespaces that are already sub-namespaces, roots, templates, or instances of templates are left intact.
+func (m *namespaceMutator) Handle(ctx context.Context, req admission.Request) admission.Response {
+	if req.Operation != admissionv1.Create {
+		return admission.Allowed("")
+	}
+
+	ns := &corev1.Namespace{}
+	if err := m.dec.Decode(req, ns); err != nil {
+		return admission.Errored(http.StatusBadRequest, err)
+	}
+	for _, key := range []string{constants.LabelParent, constants.LabelType, constants.LabelTemplate} {
+		if _, ok := ns.Labels[key]; ok {
+			return admission.Allowed("")
+		}
+	}
+	if _, ok := ns.Annotations[constants.AnnTemplates]; ok {
+		return admission.Allowed("")
+	}
+
+	parent := m.rules.parent(ns.Name)
+	if parent == "" || parent == ns.Name {
+		return admission.Allowed("")
+	}
+
+	parentNS := &corev1.Namespace{}
+	if err := m.Get(ctx, client.ObjectKey{Name: parent}, parentNS); err != nil {
+		if !apierrors.IsNotFound(err) {
+			return admission.Errored(http.StatusInternalServerError, err)
+		}
+		return admission.Allowed("").WithWarnings(fmt.Sprintf("namespace %s is not grafted since its parent %s does not exist", ns.Name, parent))
+	}
+	if parentNS.Labels[constants.LabelType] != constants.NSTypeRoot && parentNS.Labels[constants.LabelParent] == "" {
+		return admission.Allowed("").WithWarnings(fmt.Sprintf("namespace %s is not grafted since its parent %s is neither a root nor a sub namespace", ns.Name, parent))
+	}
+
+	// The SubNamespace for the namespace would be denied in the same way.
+	sv := &subNamespaceValidator{Client: m.Client, policies: m.policies}
+	msg, err := sv.checkNewChild(ctx, parentNS, ns.Name)
+	if err != nil {
+		return admission.Errored(http.StatusInternalServerError, err)
+	}
+	if msg != "" {
+		return admission.Allowed("").WithWarnings(fmt.Sprintf("namespace %s is not grafted to %s: %s", ns.Name, parent, msg))
+	}
+
+	if ns.Labels == nil {
+		ns.Labels = make(map[string]string)
+	}
+	ns.Labels[constants.LabelParent] = parent
+	if ns.Annotations == nil {
+		ns.Annotations = make(map[string]string)
+	}
+	ns.Annotations[constants.AnnAutoGrafted] = "true"
+	data, err := json.Marshal(ns)
+	if err != nil {
+		return admission.Errored(http.StatusInternalServerError, err)
+	}
+	return admission.PatchResponseFromRaw(req.Object.Raw, data)
+}
+
 //+kubebuilder:webhook:path=/validate-v1-namespace,mutating=false,failurePolicy=fail,sideEffects=None,groups="",resources=namespaces,verbs=create;update;delete,versions=v1,name=namespace.accurate.cybozu.io,admissionReviewVersions={v1}
 
 type namespaceValidator struct {
 	client.Client
 	dec                    admission.Decoder
 	allowCascadingDeletion bool
+	requireRootNamespace   bool
 }
 
 var _ admission.Handler = &namespaceValidator{}
@@ -29,12 +137,14 @@ var _ admission.Handler = &namespaceValidator{}
 //
 // - Circular references among namespaces.
 // - Allowing a sub-namespace to set a template.
+// - Setting both `accurate.cybozu.com/template` label and `accurate.cybozu.com/templates` annotation, or duplicated templates.
 // - Marking a sub-namespace as a root namespace.
 // - Deleting `accurate.cybozu.com/type=root` label from root namespaces having one or more sub-namespaces.
 // - Deleting `accurate.cybozu.com/type=template` label from template namespaces having one or more instance namespaces.
 // - Dangling sub-namespaces (sub-namespaces whose parent namespace is missing).
-// - Dangling instance namespaces (namespaces whose template namespace is missing).
+// - Dangling instance namespaces (namespaces whose template namespace or NamespaceTemplate is missing).
 // - Changing a sub-namespace to a non-root namespace when it has child sub-namespaces.
+// - Marking a namespace as a root namespace without RootNamespace, if required.
 func (v *namespaceValidator) Handle(ctx context.Context, req admission.Request) admission.Response {
 	switch req.Operation {
 	case admissionv1.Create:
@@ -73,6 +183,18 @@ func (v *namespaceValidator) checkParent(ctx context.Context, name, typ string)
 			resp := admission.Errored(http.StatusInternalServerError, err)
 			return &resp
 		}
+		if typ == constants.NSTypeTemplate {
+			found, err := v.namespaceTemplateExists(ctx, name)
+			if err != nil {
+				resp := admission.Errored(http.StatusInternalServerError, err)
+				return &resp
+			}
+			if found {
+				return nil
+			}
+			resp := admission.Denied("neither namespace nor NamespaceTemplate exists: " + name)
+			return &resp
+		}
 		resp := admission.Denied("namespace does not exist: " + name)
 		return &resp
 	}
@@ -89,6 +211,33 @@ func (v *namespaceValidator) checkParent(ctx context.Context, name, typ string)
 }
 
 func (v *namespaceValidator) handleCreate(ctx context.Context, ns *corev1.Namespace) admission.Response {
+	if ns.Labels[constants.LabelType] == constants.NSTypeRoot {
+		if resp := v.checkRootNamespace(ctx, ns); resp != nil {
+			return *resp
+		}
+	}
+	return v.validate(ctx, ns)
+}
+
+// checkRootNamespace denies making `ns` a root namespace without RootNamespace, if required.
+func (v *namespaceValidator) checkRootNamespace(ctx context.Context, ns *corev1.Namespace) *admission.Response {
+	if !v.requireRootNamespace {
+		return nil
+	}
+	rn, err := getRootNamespacePolicy(ctx, v.Client, ns.Name)
+	if err != nil {
+		resp := admission.Errored(http.StatusInternalServerError, err)
+		return &resp
+	}
+	if rn == nil {
+		resp := admission.Denied(fmt.Sprintf("namespace %s cannot be a root namespace without RootNamespace %s", ns.Name, ns.Name))
+		return &resp
+	}
+	return nil
+}
+
+// validate checks the labels and annotations of `ns` that are common to creation and update.
+func (v *namespaceValidator) validate(ctx context.Context, ns *corev1.Namespace) admission.Response {
 	if p := ns.Labels[constants.LabelParent]; p != "" {
 		if ns.Name == p {
 			return admission.Denied("circular reference is not permitted")
@@ -96,6 +245,9 @@ func (v *namespaceValidator) handleCreate(ctx context.Context, ns *corev1.Namesp
 		if _, ok := ns.Labels[constants.LabelTemplate]; ok {
 			return admission.Denied("a sub-namespace cannot have a template")
 		}
+		if _, ok := ns.Annotations[constants.AnnTemplates]; ok {
+			return admission.Denied("a sub-namespace cannot have a template")
+		}
 		if _, ok := ns.Labels[constants.LabelType]; ok {
 			return admission.Denied("a sub-namespace cannot be a root or a template")
 		}
@@ -103,7 +255,17 @@ func (v *namespaceValidator) handleCreate(ctx context.Context, ns *corev1.Namesp
 			return *resp
 		}
 	}
-	if t := ns.Labels[constants.LabelTemplate]; t != "" {
+	if _, ok := ns.Annotations[constants.AnnTemplates]; ok {
+		if _, ok := ns.Labels[constants.LabelTemplate]; ok {
+			return admission.Denied(fmt.Sprintf("%s label and %s annotation cannot be set together", constants.LabelTemplate, constants.AnnTemplates))
+		}
+	}
+	seen := make(map[string]bool)
+	for _, t := range templates.Names(ns) {
+		if seen[t] {
+			return admission.Denied("duplicated template: " + t)
+		}
+		seen[t] = true
 		if ns.Name == t {
 			return admission.Denied("circular reference is not permitted")
 		}
@@ -114,29 +276,52 @@ func (v *namespaceValidator) handleCreate(ctx context.Context, ns *corev1.Namesp
 	return admission.Allowed("")
 }
 
-func (v *namespaceValidator) getParent(ns *corev1.Namespace) string {
+// getParents returns the parent namespace or the templates of `ns`.
+func (v *namespaceValidator) getParents(ns *corev1.Namespace) []string {
 	if p := ns.Labels[constants.LabelParent]; p != "" {
-		return p
+		return []string{p}
 	}
-	return ns.Labels[constants.LabelTemplate]
+	return templates.Names(ns)
 }
 
-func (v *namespaceValidator) handleUpdate(ctx context.Context, nsNew, nsOld *corev1.Namespace) admission.Response {
-	m := map[string]bool{nsNew.Name: true}
-	p := v.getParent(nsNew)
-	for pp := p; pp != ""; {
-		if m[pp] {
-			return admission.Denied("circular reference is not permitted")
+// checkCircular follows `parents` and their ancestors, and denies if any of them is in `path`.
+func (v *namespaceValidator) checkCircular(ctx context.Context, parents []string, path map[string]bool) *admission.Response {
+	for _, p := range parents {
+		if path[p] {
+			resp := admission.Denied("circular reference is not permitted")
+			return &resp
 		}
 		parent := &corev1.Namespace{}
-		if err := v.Get(ctx, client.ObjectKey{Name: pp}, parent); err != nil {
-			if apierrors.IsNotFound(err) {
-				return admission.Denied("parent namespace does not exist: " + pp)
+		if err := v.Get(ctx, client.ObjectKey{Name: p}, parent); err != nil {
+			if !apierrors.IsNotFound(err) {
+				resp := admission.Errored(http.StatusInternalServerError, err)
+				return &resp
 			}
-			return admission.Errored(http.StatusInternalServerError, err)
+			// NamespaceTemplate has no parent.
+			found, err := v.namespaceTemplateExists(ctx, p)
+			if err != nil {
+				resp := admission.Errored(http.StatusInternalServerError, err)
+				return &resp
+			}
+			if !found {
+				resp := admission.Denied("parent namespace does not exist: " + p)
+				return &resp
+			}
+			continue
+		}
+		path[p] = true
+		if resp := v.checkCircular(ctx, v.getParents(parent), path); resp != nil {
+			return resp
 		}
-		m[pp] = true
-		pp = v.getParent(parent)
+		delete(path, p)
+	}
+	return nil
+}
+
+func (v *namespaceValidator) handleUpdate(ctx context.Context, nsNew, nsOld *corev1.Namespace) admission.Response {
+	parents := v.getParents(nsNew)
+	if resp := v.checkCircular(ctx, parents, map[string]bool{nsNew.Name: true}); resp != nil {
+		return *resp
 	}
 
 	oldType := nsOld.Labels[constants.LabelType]
@@ -163,7 +348,7 @@ func (v *namespaceValidator) handleUpdate(ctx context.Context, nsNew, nsOld *cor
 		}
 	}
 
-	if p == "" && nsOld.Labels[constants.LabelParent] != "" && newType != constants.NSTypeRoot {
+	if len(parents) == 0 && nsOld.Labels[constants.LabelParent] != "" && newType != constants.NSTypeRoot {
 		children := &corev1.NamespaceList{}
 		if err := v.List(ctx, children, client.MatchingFields{constants.NamespaceParentKey: nsNew.Name}); err != nil {
 			return admission.Errored(http.StatusInternalServerError, err)
@@ -173,16 +358,28 @@ func (v *namespaceValidator) handleUpdate(ctx context.Context, nsNew, nsOld *cor
 		}
 	}
 
-	return v.handleCreate(ctx, nsNew)
+	// Existing root namespaces are kept as they are even if RootNamespace is required or deleted.
+	if oldType != constants.NSTypeRoot && newType == constants.NSTypeRoot {
+		if resp := v.checkRootNamespace(ctx, nsNew); resp != nil {
+			return *resp
+		}
+	}
+	return v.validate(ctx, nsNew)
 }
 
 func (v *namespaceValidator) handleDelete(ctx context.Context, ns *corev1.Namespace) admission.Response {
 	key := constants.NamespaceParentKey
 	switch {
-	case ns.Labels[constants.LabelType] == constants.NSTypeRoot && !v.allowCascadingDeletion:
 	case ns.Labels[constants.LabelType] == constants.NSTypeTemplate:
 		key = constants.NamespaceTemplateKey
-	case ns.Labels[constants.LabelParent] != "" && !v.allowCascadingDeletion:
+	case ns.Labels[constants.LabelType] == constants.NSTypeRoot || ns.Labels[constants.LabelParent] != "":
+		allowed, err := cascadingDeletionAllowed(ctx, v.Client, ns, v.allowCascadingDeletion)
+		if err != nil {
+			return admission.Errored(http.StatusInternalServerError, err)
+		}
+		if allowed {
+			return admission.Allowed("")
+		}
 	default:
 		return admission.Allowed("")
 	}
@@ -198,13 +395,40 @@ func (v *namespaceValidator) handleDelete(ctx context.Context, ns *corev1.Namesp
 	return admission.Allowed("")
 }
 
-// SetupNamespaceWebhook registers the webhook for Namespace
-func SetupNamespaceWebhook(mgr manager.Manager, dec admission.Decoder, allowCascadingDeletion bool) {
+func (v *namespaceValidator) namespaceTemplateExists(ctx context.Context, name string) (bool, error) {
+	nt := &accuratev2.NamespaceTemplate{}
+	if err := v.Get(ctx, client.ObjectKey{Name: name}, nt); err != nil {
+		if apierrors.IsNotFound(err) {
+			return false, nil
+		}
+		return false, err
+	}
+	return true, nil
+}
+
+// SetupNamespaceWebhook registers the webhooks for Namespace
+// If `requireRootNamespace` is true, only namespaces declared by RootNamespace can be root namespaces.
+// If `rules` is nil, new namespaces are never grafted automatically.
+// `policies` are checked before grafting a new namespace, in the same way as SubNamespaces.
+func SetupNamespaceWebhook(mgr manager.Manager, dec admission.Decoder, rules *AutoGraftRules, policies *SubNamespacePolicies, allowCascadingDeletion, requireRootNamespace bool) {
+	serv := mgr.GetWebhookServer()
+
+	if rules == nil {
+		rules = &AutoGraftRules{}
+	}
+	m := &namespaceMutator{
+		Client:   mgr.GetClient(),
+		dec:      dec,
+		rules:    rules,
+		policies: policies,
+	}
+	serv.Register(AutoGraftWebhookPath, &webhook.Admission{Handler: m})
+
 	v := &namespaceValidator{
 		Client:                 mgr.GetClient(),
 		dec:                    dec,
 		allowCascadingDeletion: allowCascadingDeletion,
+		requireRootNamespace:   requireRootNamespace,
 	}
-	serv := mgr.GetWebhookServer()
 	serv.Register("/validate-v1-namespace", &webhook.Admission{Handler: v})
 }
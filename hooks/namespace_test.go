@@ -7,6 +7,7 @@ import (
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
 	corev1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
 )
 
 var _ = Describe("Namespace webhook", func() {
@@ -40,6 +41,77 @@ var _ = Describe("Namespace webhook", func() {
 		Expect(k8sClient.Update(ctx, ns)).To(MatchError(ContainSubstring("there are namespaces referencing tmpl1")))
 	})
 
+	It("should graft a new namespace by the auto-graft rules", func() {
+		root := &corev1.Namespace{}
+		root.Name = "autograft-a"
+		root.Labels = map[string]string{constants.LabelType: constants.NSTypeRoot}
+		Expect(k8sClient.Create(ctx, root)).To(Succeed())
+
+		ns := &corev1.Namespace{}
+		ns.Name = "autograft-a-feature-x"
+		Expect(k8sClient.Create(ctx, ns)).To(Succeed())
+		Expect(ns.Labels).To(HaveKeyWithValue(constants.LabelParent, "autograft-a"))
+		Expect(ns.Annotations).To(HaveKeyWithValue(constants.AnnAutoGrafted, "true"))
+
+		By("creating a namespace whose parent does not exist")
+		ns = &corev1.Namespace{}
+		ns.Name = "autograft-b-feature-x"
+		Expect(k8sClient.Create(ctx, ns)).To(Succeed())
+		Expect(ns.Labels).NotTo(HaveKey(constants.LabelParent))
+		Expect(ns.Annotations).NotTo(HaveKey(constants.AnnAutoGrafted))
+
+		By("creating a namespace exceeding the limit of children")
+		root = &corev1.Namespace{}
+		root.Name = "autograft-c"
+		root.Labels = map[string]string{constants.LabelType: constants.NSTypeRoot}
+		root.Annotations = map[string]string{constants.AnnMaxChildren: "1"}
+		Expect(k8sClient.Create(ctx, root)).To(Succeed())
+		ns = &corev1.Namespace{}
+		ns.Name = "autograft-c-feature-x"
+		Expect(k8sClient.Create(ctx, ns)).To(Succeed())
+		Expect(ns.Labels).To(HaveKeyWithValue(constants.LabelParent, "autograft-c"))
+		ns = &corev1.Namespace{}
+		ns.Name = "autograft-c-feature-y"
+		Expect(k8sClient.Create(ctx, ns)).To(Succeed())
+		Expect(ns.Labels).NotTo(HaveKey(constants.LabelParent))
+		Expect(ns.Annotations).NotTo(HaveKey(constants.AnnAutoGrafted))
+
+		By("creating a namespace that is a template")
+		ns = &corev1.Namespace{}
+		ns.Name = "autograft-a-template"
+		ns.Labels = map[string]string{constants.LabelType: constants.NSTypeTemplate}
+		Expect(k8sClient.Create(ctx, ns)).To(Succeed())
+		Expect(ns.Labels).NotTo(HaveKey(constants.LabelParent))
+	})
+
+	It("should require RootNamespace only when a namespace becomes a root", func() {
+		// The webhook of this suite does not require RootNamespace.
+		root := &corev1.Namespace{}
+		root.Name = "require-root-legacy"
+		root.Labels = map[string]string{constants.LabelType: constants.NSTypeRoot}
+		Expect(k8sClient.Create(ctx, root)).To(Succeed())
+
+		v := &namespaceValidator{Client: k8sClient, requireRootNamespace: true}
+
+		By("updating an existing root namespace")
+		updated := root.DeepCopy()
+		updated.Labels["foo"] = "bar"
+		Expect(v.handleUpdate(ctx, updated, root).Allowed).To(BeTrue())
+
+		By("making a namespace a root namespace")
+		ns := &corev1.Namespace{}
+		ns.Name = "require-root-new"
+		Expect(k8sClient.Create(ctx, ns)).To(Succeed())
+		updated = ns.DeepCopy()
+		updated.Labels = map[string]string{constants.LabelType: constants.NSTypeRoot}
+		resp := v.handleUpdate(ctx, updated, ns)
+		Expect(resp.Allowed).To(BeFalse())
+		Expect(resp.Result.Message).To(ContainSubstring("without RootNamespace"))
+
+		By("creating a root namespace")
+		Expect(v.handleCreate(ctx, updated).Allowed).To(BeFalse())
+	})
+
 	It("should deny creating a self-referencing namespace", func() {
 		ns := &corev1.Namespace{}
 		ns.Name = "self-reference"
@@ -159,6 +231,56 @@ var _ = Describe("Namespace webhook", func() {
 		Expect(k8sClient.Update(ctx, sub)).To(MatchError(ContainSubstring("circular reference is not permitted")))
 	})
 
+	It("should allow referencing multiple templates", func() {
+		for _, name := range []string{"multi-tmpl1", "multi-tmpl2"} {
+			ns := &corev1.Namespace{}
+			ns.Name = name
+			ns.Labels = map[string]string{constants.LabelType: constants.NSTypeTemplate}
+			Expect(k8sClient.Create(ctx, ns)).To(Succeed())
+		}
+
+		instance := &corev1.Namespace{}
+		instance.Name = "instance-of-multi-tmpl"
+		instance.Annotations = map[string]string{constants.AnnTemplates: "multi-tmpl1,multi-tmpl1"}
+		Expect(k8sClient.Create(ctx, instance)).To(MatchError(ContainSubstring("duplicated template: multi-tmpl1")))
+
+		instance.Annotations = map[string]string{constants.AnnTemplates: "multi-tmpl1,multi-tmpl3"}
+		Expect(k8sClient.Create(ctx, instance)).To(MatchError(ContainSubstring("neither namespace nor NamespaceTemplate exists: multi-tmpl3")))
+
+		instance.Labels = map[string]string{constants.LabelTemplate: "multi-tmpl1"}
+		instance.Annotations = map[string]string{constants.AnnTemplates: "multi-tmpl2"}
+		Expect(k8sClient.Create(ctx, instance)).To(MatchError(ContainSubstring("cannot be set together")))
+
+		instance.Labels = nil
+		instance.Annotations = map[string]string{constants.AnnTemplates: "multi-tmpl1, multi-tmpl2"}
+		Expect(k8sClient.Create(ctx, instance)).To(Succeed())
+
+		By("removing accurate.cybozu.com/type label from the second template")
+		tmpl2 := &corev1.Namespace{}
+		Expect(k8sClient.Get(ctx, client.ObjectKey{Name: "multi-tmpl2"}, tmpl2)).To(Succeed())
+		tmpl2.Labels = nil
+		Expect(k8sClient.Update(ctx, tmpl2)).To(MatchError(ContainSubstring("there are namespaces referencing multi-tmpl2")))
+
+		By("making a circular reference through the second template")
+		tmpl2.Labels = map[string]string{
+			constants.LabelType:     constants.NSTypeTemplate,
+			constants.LabelTemplate: "instance-of-multi-tmpl",
+		}
+		Expect(k8sClient.Update(ctx, tmpl2)).To(MatchError(ContainSubstring("instance-of-multi-tmpl is not a valid template namespace")))
+
+		instance.Labels = map[string]string{constants.LabelType: constants.NSTypeTemplate}
+		Expect(k8sClient.Update(ctx, instance)).To(Succeed())
+		Expect(k8sClient.Update(ctx, tmpl2)).To(MatchError(ContainSubstring("circular reference is not permitted")))
+	})
+
+	It("should deny a sub-namespace having templates", func() {
+		ns := &corev1.Namespace{}
+		ns.Name = "templates-sub"
+		ns.Labels = map[string]string{constants.LabelParent: "default"}
+		ns.Annotations = map[string]string{constants.AnnTemplates: "default"}
+		Expect(k8sClient.Create(ctx, ns)).To(MatchError(ContainSubstring("a sub-namespace cannot have a template")))
+	})
+
 	It("should deny updating a sub-namespace to have a template", func() {
 		tmpl := &corev1.Namespace{}
 		tmpl.Name = "dusht-tmpl"
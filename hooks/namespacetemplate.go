@@ -0,0 +1,191 @@
+package hooks
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sync"
+
+	accuratev2 "github.com/cybozu-go/accurate/api/accurate/v2"
+	"github.com/cybozu-go/accurate/pkg/config"
+	"github.com/cybozu-go/accurate/pkg/constants"
+	admissionv1 "k8s.io/api/admission/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	v1annotationvalidation "k8s.io/apimachinery/pkg/api/validation"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	v1labelvalidation "k8s.io/apimachinery/pkg/apis/meta/v1/validation"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+	"sigs.k8s.io/controller-runtime/pkg/webhook"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+)
+
+//+kubebuilder:webhook:path=/validate-accurate-cybozu-com-v2-namespacetemplate,mutating=false,failurePolicy=fail,sideEffects=None,groups=accurate.cybozu.com,resources=namespacetemplates,verbs=create;update;delete,versions=v2,matchPolicy=Equivalent,name=vnamespacetemplate.kb.io,admissionReviewVersions={v1}
+
+// WatchedKinds holds the kinds of the resources that Accurate propagates.
+type WatchedKinds struct {
+	mu    sync.RWMutex
+	kinds map[schema.GroupVersionKind]bool
+}
+
+// NewWatchedKinds creates WatchedKinds from the resolved watches of `cfg`.
+func NewWatchedKinds(cfg *config.Config) *WatchedKinds {
+	k := &WatchedKinds{}
+	k.Update(cfg)
+	return k
+}
+
+// Update replaces the kinds with the resolved watches of `cfg`.
+func (k *WatchedKinds) Update(cfg *config.Config) {
+	kinds := make(map[schema.GroupVersionKind]bool)
+	for _, w := range cfg.Watches {
+		kinds[schema.GroupVersionKind{Group: w.Group, Version: w.Version, Kind: w.Kind}] = true
+	}
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	k.kinds = kinds
+}
+
+// watched returns true if `gvk` is propagated.  A nil WatchedKinds accepts any kind.
+func (k *WatchedKinds) watched(gvk schema.GroupVersionKind) bool {
+	if k == nil {
+		return true
+	}
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+	return k.kinds[gvk]
+}
+
+type namespaceTemplateValidator struct {
+	client.Client
+	dec   admission.Decoder
+	kinds *WatchedKinds
+}
+
+var _ admission.Handler = &namespaceTemplateValidator{}
+
+// Validate NamespaceTemplate to prevent the following problems:
+//
+// - Invalid labels, annotations, or manifests.
+// - Manifests of kinds that are not propagated.
+// - Dangling instance namespaces (namespaces whose NamespaceTemplate is missing).
+func (v *namespaceTemplateValidator) Handle(ctx context.Context, req admission.Request) admission.Response {
+	switch req.Operation {
+	case admissionv1.Create, admissionv1.Update:
+		nt := &accuratev2.NamespaceTemplate{}
+		if err := v.dec.Decode(req, nt); err != nil {
+			return admission.Errored(http.StatusBadRequest, err)
+		}
+		var old *accuratev2.NamespaceTemplate
+		if req.Operation == admissionv1.Update {
+			old = &accuratev2.NamespaceTemplate{}
+			if err := v.dec.DecodeRaw(req.OldObject, old); err != nil {
+				return admission.Errored(http.StatusBadRequest, err)
+			}
+		}
+		errs, warnings := validateNamespaceTemplateSpec(nt, old, v.kinds)
+		if len(errs) != 0 {
+			return admission.Denied(errs.ToAggregate().Error())
+		}
+		return admission.Allowed("").WithWarnings(warnings...)
+	case admissionv1.Delete:
+		nt := &accuratev2.NamespaceTemplate{}
+		if err := v.dec.DecodeRaw(req.OldObject, nt); err != nil {
+			return admission.Errored(http.StatusBadRequest, err)
+		}
+		return v.handleDelete(ctx, nt)
+	}
+	return admission.Allowed("")
+}
+
+// validateNamespaceTemplateSpec validates `nt` updated from `old`, which is nil on creation.
+// Manifests of kinds that are not in `kinds` are denied, but those already in `old` are only warned
+// so that NamespaceTemplate can be updated after the watches are changed.
+func validateNamespaceTemplateSpec(nt, old *accuratev2.NamespaceTemplate, kinds *WatchedKinds) (field.ErrorList, []string) {
+	var allErrs field.ErrorList
+	var warnings []string
+	specPath := field.NewPath("spec")
+	allErrs = append(allErrs, v1labelvalidation.ValidateLabels(nt.Spec.Labels, specPath.Child("labels"))...)
+	allErrs = append(allErrs, v1annotationvalidation.ValidateAnnotations(nt.Spec.Annotations, specPath.Child("annotations"))...)
+
+	type key struct {
+		gvk  schema.GroupVersionKind
+		name string
+	}
+	existing := make(map[key]bool)
+	if old != nil {
+		for _, res := range old.Spec.Resources {
+			obj := &unstructured.Unstructured{}
+			if err := obj.UnmarshalJSON(res.Manifest.Raw); err == nil {
+				existing[key{gvk: obj.GroupVersionKind(), name: obj.GetName()}] = true
+			}
+		}
+	}
+	seen := make(map[key]bool)
+	for i, res := range nt.Spec.Resources {
+		fldPath := specPath.Child("resources").Index(i).Child("manifest")
+		obj := &unstructured.Unstructured{}
+		if err := obj.UnmarshalJSON(res.Manifest.Raw); err != nil {
+			allErrs = append(allErrs, field.Invalid(fldPath, string(res.Manifest.Raw), err.Error()))
+			continue
+		}
+		if obj.GetName() == "" {
+			allErrs = append(allErrs, field.Required(fldPath.Child("metadata", "name"), ""))
+			continue
+		}
+		if _, ok := obj.GetAnnotations()[constants.AnnPropagate]; ok {
+			allErrs = append(allErrs, field.Forbidden(fldPath.Child("metadata", "annotations"),
+				fmt.Sprintf("%s is given by mode", constants.AnnPropagate)))
+		}
+		k := key{gvk: obj.GroupVersionKind(), name: obj.GetName()}
+		if seen[k] {
+			allErrs = append(allErrs, field.Duplicate(fldPath, fmt.Sprintf("%s %s", obj.GetKind(), obj.GetName())))
+		}
+		seen[k] = true
+		if !kinds.watched(k.gvk) {
+			msg := fmt.Sprintf("%s is not one of the watched resources", k.gvk)
+			if existing[k] {
+				warnings = append(warnings, fmt.Sprintf("%s: %s is not propagated since %s", fldPath, obj.GetName(), msg))
+			} else {
+				allErrs = append(allErrs, field.Invalid(fldPath.Child("kind"), obj.GetKind(), msg))
+			}
+		}
+	}
+	return allErrs, warnings
+}
+
+func (v *namespaceTemplateValidator) handleDelete(ctx context.Context, nt *accuratev2.NamespaceTemplate) admission.Response {
+	ns := &corev1.Namespace{}
+	err := v.Get(ctx, client.ObjectKey{Name: nt.Name}, ns)
+	if err == nil {
+		// the instances refer to the template namespace
+		return admission.Allowed("")
+	}
+	if !apierrors.IsNotFound(err) {
+		return admission.Errored(http.StatusInternalServerError, err)
+	}
+
+	instances := &corev1.NamespaceList{}
+	if err := v.List(ctx, instances, client.MatchingFields{constants.NamespaceTemplateKey: nt.Name}); err != nil {
+		return admission.Errored(http.StatusInternalServerError, err)
+	}
+	if len(instances.Items) > 0 {
+		return admission.Denied("there are namespaces referencing " + nt.Name)
+	}
+	return admission.Allowed("")
+}
+
+// SetupNamespaceTemplateWebhook registers the webhook for NamespaceTemplate
+// If `kinds` is nil, manifests of any kind are accepted.
+func SetupNamespaceTemplateWebhook(mgr manager.Manager, dec admission.Decoder, kinds *WatchedKinds) {
+	v := &namespaceTemplateValidator{
+		Client: mgr.GetClient(),
+		dec:    dec,
+		kinds:  kinds,
+	}
+	serv := mgr.GetWebhookServer()
+	serv.Register("/validate-accurate-cybozu-com-v2-namespacetemplate", &webhook.Admission{Handler: v})
+}
@@ -0,0 +1,82 @@
+package hooks
+
+import (
+	"context"
+
+	accuratev2 "github.com/cybozu-go/accurate/api/accurate/v2"
+	"github.com/cybozu-go/accurate/pkg/config"
+	"github.com/cybozu-go/accurate/pkg/constants"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+var _ = Describe("NamespaceTemplate webhook", func() {
+	ctx := context.Background()
+
+	It("should deny invalid manifests", func() {
+		nt := &accuratev2.NamespaceTemplate{}
+		nt.Name = "vtmpl-invalid"
+		nt.Spec.Resources = []accuratev2.NamespaceTemplateResource{
+			{Manifest: runtime.RawExtension{Raw: []byte(`{"apiVersion":"v1","kind":"ConfigMap","metadata":{"name":"foo"}}`)}},
+			{Manifest: runtime.RawExtension{Raw: []byte(`{"apiVersion":"v1","kind":"ConfigMap","metadata":{"name":"foo"}}`)}},
+		}
+		Expect(k8sClient.Create(ctx, nt)).To(MatchError(ContainSubstring("Duplicate value")))
+	})
+
+	It("should deny manifests of kinds that are not watched", func() {
+		nt := &accuratev2.NamespaceTemplate{}
+		nt.Name = "vtmpl-unwatched"
+		nt.Spec.Resources = []accuratev2.NamespaceTemplateResource{
+			{Manifest: runtime.RawExtension{Raw: []byte(`{"apiVersion":"v1","kind":"Secret","metadata":{"name":"foo"}}`)}},
+		}
+		Expect(k8sClient.Create(ctx, nt)).To(MatchError(ContainSubstring("not one of the watched resources")))
+
+		nt.Spec.Resources = []accuratev2.NamespaceTemplateResource{
+			{Manifest: runtime.RawExtension{Raw: []byte(`{"apiVersion":"rbac.authorization.k8s.io/v1","kind":"ClusterRole","metadata":{"name":"foo"}}`)}},
+		}
+		Expect(k8sClient.Create(ctx, nt)).To(MatchError(ContainSubstring("not one of the watched resources")))
+
+		By("warning about kinds that were accepted before")
+		kinds := NewWatchedKinds(&config.Config{})
+		nt.Spec.Resources = []accuratev2.NamespaceTemplateResource{
+			{Manifest: runtime.RawExtension{Raw: []byte(`{"apiVersion":"v1","kind":"ConfigMap","metadata":{"name":"foo"}}`)}},
+		}
+		old := nt.DeepCopy()
+		nt.Spec.Labels = map[string]string{"foo": "bar"}
+		errs, warnings := validateNamespaceTemplateSpec(nt, old, kinds)
+		Expect(errs).To(BeEmpty())
+		Expect(warnings).To(ConsistOf(ContainSubstring("foo is not propagated")))
+
+		errs, _ = validateNamespaceTemplateSpec(nt, nil, kinds)
+		Expect(errs).NotTo(BeEmpty())
+	})
+
+	It("should be referenced by namespaces", func() {
+		instance := &corev1.Namespace{}
+		instance.Name = "instance-of-vtmpl1"
+		instance.Labels = map[string]string{constants.LabelTemplate: "vtmpl1"}
+		Expect(k8sClient.Create(ctx, instance)).To(MatchError(ContainSubstring("neither namespace nor NamespaceTemplate exists: vtmpl1")))
+
+		nt := &accuratev2.NamespaceTemplate{}
+		nt.Name = "vtmpl1"
+		nt.Spec.Labels = map[string]string{"team": "neco"}
+		nt.Spec.Resources = []accuratev2.NamespaceTemplateResource{
+			{Manifest: runtime.RawExtension{Raw: []byte(`{"apiVersion":"v1","kind":"ConfigMap","metadata":{"name":"foo"}}`)}},
+		}
+		Expect(k8sClient.Create(ctx, nt)).To(Succeed())
+		Eventually(func() error {
+			return k8sClient.Create(ctx, instance)
+		}).Should(Succeed())
+
+		By("updating the instance")
+		instance.Labels["foo"] = "bar"
+		Expect(k8sClient.Update(ctx, instance)).To(Succeed())
+
+		By("deleting the NamespaceTemplate")
+		Eventually(func() error {
+			return k8sClient.Delete(ctx, nt)
+		}).Should(MatchError(ContainSubstring("there are namespaces referencing vtmpl1")))
+	})
+})
@@ -0,0 +1,91 @@
+package hooks
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	"github.com/cybozu-go/accurate/pkg/constants"
+	admissionv1 "k8s.io/api/admission/v1"
+	authenticationv1 "k8s.io/api/authentication/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+	"sigs.k8s.io/controller-runtime/pkg/webhook"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+)
+
+// PropagatedCopyWebhookPath is the path of the webhook that protects propagated copies.
+//
+// There is no kubebuilder marker for this webhook because the resources to
+// be protected are chosen by the users.  The Helm chart renders the webhook
+// configuration from `webhook.protectedResources` and enables the handler
+// with `--webhook-protect-copies`.
+const PropagatedCopyWebhookPath = "/validate-propagated-copy"
+
+type propagatedCopyValidator struct {
+	client.Client
+	dec            admission.Decoder
+	controllerUser string
+}
+
+var _ admission.Handler = &propagatedCopyValidator{}
+
+// Deny updates and deletes of copies propagated in update mode unless requested by the controller.
+func (v *propagatedCopyValidator) Handle(ctx context.Context, req admission.Request) admission.Response {
+	if req.Operation != admissionv1.Update && req.Operation != admissionv1.Delete {
+		return admission.Allowed("")
+	}
+	if req.UserInfo.Username == v.controllerUser {
+		return admission.Allowed("")
+	}
+
+	obj := &unstructured.Unstructured{}
+	if err := v.dec.DecodeRaw(req.OldObject, obj); err != nil {
+		return admission.Errored(http.StatusBadRequest, err)
+	}
+
+	ann := obj.GetAnnotations()
+	from := ann[constants.AnnFrom]
+	if from == "" || ann[constants.AnnPropagate] != constants.PropagateUpdate {
+		return admission.Allowed("")
+	}
+	if obj.GetDeletionTimestamp() != nil {
+		return admission.Allowed("")
+	}
+
+	// Namespaces must be able to be deleted with their contents.
+	ns := &corev1.Namespace{}
+	if err := v.Get(ctx, client.ObjectKey{Name: req.Namespace}, ns); err != nil {
+		if apierrors.IsNotFound(err) {
+			return admission.Allowed("")
+		}
+		return admission.Errored(http.StatusInternalServerError, err)
+	}
+	if ns.DeletionTimestamp != nil {
+		return admission.Allowed("")
+	}
+
+	return admission.Denied(fmt.Sprintf("%s %s/%s is propagated from namespace %s; edit the source in namespace %s instead",
+		obj.GetKind(), obj.GetNamespace(), obj.GetName(), from, from))
+}
+
+// SetupPropagatedCopyWebhook registers the webhook for propagated copies.
+// Requests from the user of `mgr` are always allowed so that the controller can update the copies.
+func SetupPropagatedCopyWebhook(ctx context.Context, mgr manager.Manager, dec admission.Decoder) error {
+	review := &authenticationv1.SelfSubjectReview{}
+	if err := mgr.GetClient().Create(ctx, review); err != nil {
+		return fmt.Errorf("failed to get the user of the controller: %w", err)
+	}
+
+	v := &propagatedCopyValidator{
+		Client:         mgr.GetClient(),
+		dec:            dec,
+		controllerUser: review.Status.UserInfo.Username,
+	}
+	serv := mgr.GetWebhookServer()
+	serv.Register(PropagatedCopyWebhookPath, &webhook.Admission{Handler: v})
+	return nil
+}
@@ -0,0 +1,72 @@
+package hooks
+
+import (
+	"context"
+
+	"github.com/cybozu-go/accurate/pkg/constants"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/rest"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+var _ = Describe("Propagated copy webhook", func() {
+	ctx := context.Background()
+
+	var tenantClient client.Client
+	BeforeEach(func() {
+		cfg := rest.CopyConfig(k8sCfg)
+		cfg.Impersonate = rest.ImpersonationConfig{
+			UserName: "tenant",
+			Groups:   []string{"system:masters"},
+		}
+		var err error
+		tenantClient, err = client.New(cfg, client.Options{Scheme: k8sClient.Scheme()})
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	It("should protect copies propagated in update mode", func() {
+		ns := &corev1.Namespace{}
+		ns.Name = "protect-copy"
+		Expect(k8sClient.Create(ctx, ns)).To(Succeed())
+
+		newCopy := func(name, mode string) *corev1.ConfigMap {
+			cm := &corev1.ConfigMap{}
+			cm.Namespace = ns.Name
+			cm.Name = name
+			cm.Labels = map[string]string{constants.LabelCreatedBy: constants.CreatedBy}
+			cm.Annotations = map[string]string{
+				constants.AnnFrom:      "protect-copy-parent",
+				constants.AnnPropagate: mode,
+			}
+			cm.Data = map[string]string{"foo": "bar"}
+			Expect(k8sClient.Create(ctx, cm)).To(Succeed())
+			return cm
+		}
+
+		By("denying updates and deletes by others")
+		cm := newCopy("update", constants.PropagateUpdate)
+		cm.Data["foo"] = "baz"
+		err := tenantClient.Update(ctx, cm)
+		Expect(err).To(HaveOccurred())
+		Expect(errors.ReasonForError(err)).Should(Equal(metav1.StatusReasonForbidden))
+		Expect(err.Error()).To(ContainSubstring("edit the source in namespace protect-copy-parent"))
+
+		err = tenantClient.Delete(ctx, cm)
+		Expect(err).To(HaveOccurred())
+		Expect(errors.ReasonForError(err)).Should(Equal(metav1.StatusReasonForbidden))
+
+		By("allowing the controller")
+		Expect(k8sClient.Update(ctx, cm)).To(Succeed())
+		Expect(k8sClient.Delete(ctx, cm)).To(Succeed())
+
+		By("allowing copies propagated in create mode")
+		cm = newCopy("create", constants.PropagateCreate)
+		cm.Data["foo"] = "baz"
+		Expect(tenantClient.Update(ctx, cm)).To(Succeed())
+		Expect(tenantClient.Delete(ctx, cm)).To(Succeed())
+	})
+})
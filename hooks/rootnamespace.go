@@ -0,0 +1,133 @@
+package hooks
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"path"
+	"regexp"
+	"strings"
+
+	accuratev2 "github.com/cybozu-go/accurate/api/accurate/v2"
+	"github.com/cybozu-go/accurate/internal/util/tree"
+	"github.com/cybozu-go/accurate/pkg/config"
+	"github.com/cybozu-go/accurate/pkg/constants"
+	admissionv1 "k8s.io/api/admission/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+	"sigs.k8s.io/controller-runtime/pkg/webhook"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+)
+
+//+kubebuilder:webhook:path=/validate-accurate-cybozu-com-v2-rootnamespace,mutating=false,failurePolicy=fail,sideEffects=None,groups=accurate.cybozu.com,resources=rootnamespaces,verbs=create;update,versions=v2,matchPolicy=Equivalent,name=vrootnamespace.kb.io,admissionReviewVersions={v1}
+
+type rootNamespaceValidator struct {
+	dec admission.Decoder
+}
+
+var _ admission.Handler = &rootNamespaceValidator{}
+
+// Validate the policy of RootNamespace in the same way as the configuration file.
+func (v *rootNamespaceValidator) Handle(ctx context.Context, req admission.Request) admission.Response {
+	if req.Operation != admissionv1.Create && req.Operation != admissionv1.Update {
+		return admission.Allowed("")
+	}
+
+	rn := &accuratev2.RootNamespace{}
+	if err := v.dec.Decode(req, rn); err != nil {
+		return admission.Errored(http.StatusBadRequest, err)
+	}
+
+	if errs := validateRootNamespaceSpec(rn); len(errs) != 0 {
+		return admission.Denied(errs.ToAggregate().Error())
+	}
+	return admission.Allowed("")
+}
+
+func validateRootNamespaceSpec(rn *accuratev2.RootNamespace) field.ErrorList {
+	var allErrs field.ErrorList
+	specPath := field.NewPath("spec")
+
+	if p := rn.Spec.NamingPolicy; p != nil {
+		policyPath := specPath.Child("namingPolicy")
+		if _, err := regexp.Compile(p.Match); err != nil {
+			allErrs = append(allErrs, field.Invalid(policyPath.Child("match"), p.Match, err.Error()))
+		}
+		for i, deny := range p.Deny {
+			if _, err := regexp.Compile(deny); err != nil {
+				allErrs = append(allErrs, field.Invalid(policyPath.Child("deny").Index(i), deny, err.Error()))
+			}
+		}
+	}
+
+	validateKeys := func(keys []string, fldPath *field.Path) {
+		for i, key := range keys {
+			if _, err := path.Match(key, ""); err != nil {
+				allErrs = append(allErrs, field.Invalid(fldPath.Index(i), key, err.Error()))
+			}
+			if strings.HasPrefix(key, constants.MetaPrefix) {
+				allErrs = append(allErrs, field.Forbidden(fldPath.Index(i), fmt.Sprintf("keys of %s are not allowed", constants.MetaPrefix)))
+			}
+		}
+	}
+	validateKeys(rn.Spec.LabelKeys, specPath.Child("labelKeys"))
+	validateKeys(rn.Spec.AnnotationKeys, specPath.Child("annotationKeys"))
+	return allErrs
+}
+
+// getRootNamespacePolicy returns the RootNamespace for the root namespace `root`, or nil if it does not exist.
+func getRootNamespacePolicy(ctx context.Context, c client.Reader, root string) (*accuratev2.RootNamespace, error) {
+	rn := &accuratev2.RootNamespace{}
+	if err := c.Get(ctx, client.ObjectKey{Name: root}, rn); err != nil {
+		if apierrors.IsNotFound(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get RootNamespace %s: %w", root, err)
+	}
+	return rn, nil
+}
+
+// cascadingDeletionAllowed returns whether `ns` can be deleted while it has children.
+// The setting of RootNamespace for the tree overrides `defaultValue`.
+// If the root of the tree cannot be determined, `defaultValue` is returned.
+func cascadingDeletionAllowed(ctx context.Context, c client.Reader, ns *corev1.Namespace, defaultValue bool) (bool, error) {
+	root, err := tree.FindRoot(ctx, c, ns.Name)
+	if err != nil {
+		return defaultValue, nil
+	}
+	rn, err := getRootNamespacePolicy(ctx, c, root)
+	if err != nil {
+		return false, err
+	}
+	if rn == nil || rn.Spec.AllowCascadingDeletion == nil {
+		return defaultValue, nil
+	}
+	return *rn.Spec.AllowCascadingDeletion, nil
+}
+
+// rootNamingPolicy returns the naming policy of `rn` in the same form as those of the configurations.
+func rootNamingPolicy(rn *accuratev2.RootNamespace) *config.NamingPolicyRegexp {
+	if rn == nil || rn.Spec.NamingPolicy == nil {
+		return nil
+	}
+	p := rn.Spec.NamingPolicy
+	return &config.NamingPolicyRegexp{
+		Root:      regexp.MustCompile("^" + regexp.QuoteMeta(rn.Name) + "$"),
+		Match:     p.Match,
+		Deny:      p.Deny,
+		MaxLength: p.MaxLength,
+		MaxDepth:  p.MaxDepth,
+	}
+}
+
+// SetupRootNamespaceWebhook registers the webhook for RootNamespace
+func SetupRootNamespaceWebhook(mgr manager.Manager, dec admission.Decoder) {
+	v := &rootNamespaceValidator{
+		dec: dec,
+	}
+	serv := mgr.GetWebhookServer()
+	serv.Register("/validate-accurate-cybozu-com-v2-rootnamespace", &webhook.Admission{Handler: v})
+}
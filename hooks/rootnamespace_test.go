@@ -0,0 +1,84 @@
+package hooks
+
+import (
+	"context"
+
+	accuratev2 "github.com/cybozu-go/accurate/api/accurate/v2"
+	"github.com/cybozu-go/accurate/pkg/constants"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/utils/ptr"
+)
+
+var _ = Describe("RootNamespace webhook", func() {
+	ctx := context.Background()
+
+	It("should deny invalid policies", func() {
+		rn := &accuratev2.RootNamespace{}
+		rn.Name = "rootns-invalid"
+		rn.Spec.NamingPolicy = &accuratev2.RootNamespaceNamingPolicy{Match: "("}
+		rn.Spec.LabelKeys = []string{constants.MetaPrefix + "foo"}
+		err := k8sClient.Create(ctx, rn)
+		Expect(err).To(HaveOccurred())
+		Expect(errors.ReasonForError(err)).Should(Equal(metav1.StatusReasonForbidden))
+		Expect(err.Error()).To(ContainSubstring("spec.namingPolicy.match"))
+		Expect(err.Error()).To(ContainSubstring("spec.labelKeys[0]"))
+	})
+
+	It("should apply the policies to SubNamespaces in the tree", func() {
+		root := &corev1.Namespace{}
+		root.Name = "rootns-policy"
+		root.Labels = map[string]string{constants.LabelType: constants.NSTypeRoot}
+		root.Annotations = map[string]string{constants.AnnMaxChildren: "1"}
+		Expect(k8sClient.Create(ctx, root)).To(Succeed())
+
+		rn := &accuratev2.RootNamespace{}
+		rn.Name = root.Name
+		rn.Spec.NamingPolicy = &accuratev2.RootNamespaceNamingPolicy{
+			Match: "^rootns-policy-",
+			Deny:  []string{"-system$"},
+		}
+		rn.Spec.SubNamespaceLimits = &accuratev2.AccurateConfigSubNamespaceLimits{MaxChildren: 2}
+		rn.Spec.AllowCascadingDeletion = ptr.To(true)
+		Expect(k8sClient.Create(ctx, rn)).To(Succeed())
+
+		By("denying names violating the naming policy")
+		sn := &accuratev2.SubNamespace{}
+		sn.Namespace = root.Name
+		sn.Name = "rootns-policy-system"
+		err := k8sClient.Create(ctx, sn)
+		Expect(err).To(HaveOccurred())
+		Expect(errors.ReasonForError(err)).Should(Equal(metav1.StatusReasonForbidden))
+		Expect(err.Error()).To(ContainSubstring("deny=-system$"))
+
+		By("overriding the limit annotation")
+		for _, name := range []string{"rootns-policy-1", "rootns-policy-2"} {
+			sn := &accuratev2.SubNamespace{}
+			sn.Namespace = root.Name
+			sn.Name = name
+			Expect(k8sClient.Create(ctx, sn)).To(Succeed())
+		}
+		sn = &accuratev2.SubNamespace{}
+		sn.Namespace = root.Name
+		sn.Name = "rootns-policy-3"
+		err = k8sClient.Create(ctx, sn)
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(ContainSubstring("limit of 2"))
+
+		By("allowing cascading deletion in the tree")
+		sub := &corev1.Namespace{}
+		sub.Name = "rootns-policy-1"
+		sub.Labels = map[string]string{constants.LabelParent: root.Name}
+		Expect(k8sClient.Create(ctx, sub)).To(Succeed())
+
+		child := &corev1.Namespace{}
+		child.Name = "rootns-policy-1-child"
+		child.Labels = map[string]string{constants.LabelParent: sub.Name}
+		Expect(k8sClient.Create(ctx, child)).To(Succeed())
+
+		Expect(k8sClient.Delete(ctx, sub)).To(Succeed())
+	})
+})
@@ -4,8 +4,14 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"maps"
 	"net/http"
+	"path"
 	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
 
 	accuratev1 "github.com/cybozu-go/accurate/api/accurate/v1"
 	accuratev2 "github.com/cybozu-go/accurate/api/accurate/v2"
@@ -53,12 +59,99 @@ func (m *subNamespaceMutator) Handle(ctx context.Context, req admission.Request)
 	return admission.PatchResponseFromRaw(req.Object.Raw, data)
 }
 
-//+kubebuilder:webhook:path=/validate-accurate-cybozu-com-v2-subnamespace,mutating=false,failurePolicy=fail,sideEffects=None,groups=accurate.cybozu.com,resources=subnamespaces,verbs=create;delete,versions=v2,matchPolicy=Equivalent,name=vsubnamespace.kb.io,admissionReviewVersions={v1}
+//+kubebuilder:webhook:path=/validate-accurate-cybozu-com-v2-subnamespace,mutating=false,failurePolicy=fail,sideEffects=None,groups=accurate.cybozu.com,resources=subnamespaces,verbs=create;update;delete,versions=v2,matchPolicy=Equivalent,name=vsubnamespace.kb.io,admissionReviewVersions={v1}
+
+// SubNamespaceKeys holds the label and annotation keys propagated from SubNamespaces
+// and the policy for the other keys.
+type SubNamespaceKeys struct {
+	mu             sync.RWMutex
+	labelKeys      []string
+	annotationKeys []string
+	policy         config.SubNamespaceKeyPolicy
+}
+
+// NewSubNamespaceKeys creates SubNamespaceKeys from `cfg`.
+func NewSubNamespaceKeys(cfg *config.Config) *SubNamespaceKeys {
+	k := &SubNamespaceKeys{}
+	k.Update(cfg)
+	return k
+}
+
+// Update replaces the keys and the policy with those of `cfg`.
+func (k *SubNamespaceKeys) Update(cfg *config.Config) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	k.labelKeys = cfg.SubNamespaceLabelKeys
+	k.annotationKeys = cfg.SubNamespaceAnnotationKeys
+	k.policy = cfg.SubNamespaceKeyPolicy
+}
+
+// unmatched returns messages for the keys of `sn` that are never propagated.
+// Keys whose values are the same as those of `old` are ignored so that existing SubNamespaces
+// can be updated after the configurations are changed.
+func (k *SubNamespaceKeys) unmatched(sn, old *accuratev2.SubNamespace) ([]string, config.SubNamespaceKeyPolicy) {
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+
+	var msgs []string
+	check := func(field string, entries, oldEntries map[string]string, patterns []string, option string) {
+		for _, key := range slices.Sorted(maps.Keys(entries)) {
+			if oldValue, ok := oldEntries[key]; ok && oldValue == entries[key] {
+				continue
+			}
+			if matchKey(key, patterns) {
+				continue
+			}
+			msgs = append(msgs, fmt.Sprintf("%s %s is not propagated since it does not match %s", field, key, option))
+		}
+	}
+	var oldLabels, oldAnnotations map[string]string
+	if old != nil {
+		oldLabels = old.Spec.Labels
+		oldAnnotations = old.Spec.Annotations
+	}
+	check("spec.labels", sn.Spec.Labels, oldLabels, k.labelKeys, "subNamespaceLabelKeys")
+	check("spec.annotations", sn.Spec.Annotations, oldAnnotations, k.annotationKeys, "subNamespaceAnnotationKeys")
+	return msgs, k.policy
+}
+
+// SubNamespacePolicies holds the naming policies and the limits of sub-namespaces.
+type SubNamespacePolicies struct {
+	mu             sync.RWMutex
+	namingPolicies []config.NamingPolicyRegexp
+	limits         config.SubNamespaceLimits
+}
+
+// NewSubNamespacePolicies creates SubNamespacePolicies from `cfg`.
+func NewSubNamespacePolicies(cfg *config.Config) *SubNamespacePolicies {
+	p := &SubNamespacePolicies{}
+	p.Update(cfg)
+	return p
+}
+
+// Update replaces the naming policies and the limits with those of `cfg`.
+func (p *SubNamespacePolicies) Update(cfg *config.Config) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.namingPolicies = cfg.NamingPolicyRegexps
+	p.limits = cfg.SubNamespaceLimits
+}
+
+// get returns the naming policies and the limits.  A nil SubNamespacePolicies has none of them.
+func (p *SubNamespacePolicies) get() ([]config.NamingPolicyRegexp, config.SubNamespaceLimits) {
+	if p == nil {
+		return nil, config.SubNamespaceLimits{}
+	}
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return p.namingPolicies, p.limits
+}
 
 type subNamespaceValidator struct {
 	client.Client
 	dec                    admission.Decoder
-	namingPolicies         []config.NamingPolicyRegexp
+	policies               *SubNamespacePolicies
+	keys                   *SubNamespaceKeys
 	allowCascadingDeletion bool
 }
 
@@ -72,6 +165,16 @@ func (v *subNamespaceValidator) Handle(ctx context.Context, req admission.Reques
 			return admission.Errored(http.StatusBadRequest, err)
 		}
 		return v.handleCreate(ctx, sn)
+	case admissionv1.Update:
+		sn := &accuratev2.SubNamespace{}
+		if err := v.dec.Decode(req, sn); err != nil {
+			return admission.Errored(http.StatusBadRequest, err)
+		}
+		old := &accuratev2.SubNamespace{}
+		if err := v.dec.DecodeRaw(req.OldObject, old); err != nil {
+			return admission.Errored(http.StatusBadRequest, err)
+		}
+		return v.handleUpdate(sn, old)
 	case admissionv1.Delete:
 		sn := &accuratev2.SubNamespace{}
 		if err := v.dec.DecodeRaw(req.OldObject, sn); err != nil {
@@ -93,31 +196,77 @@ func (v *subNamespaceValidator) handleCreate(ctx context.Context, sn *accuratev2
 		return admission.Denied(fmt.Sprintf("namespace %s is neither a root nor a sub namespace", ns.Name))
 	}
 
-	var allErrs field.ErrorList
-	allErrs = append(allErrs, v1labelvalidation.ValidateLabels(sn.Spec.Labels, field.NewPath("spec", "labels"))...)
-	allErrs = append(allErrs, v1annotationvalidation.ValidateAnnotations(sn.Spec.Annotations, field.NewPath("spec", "annotations"))...)
-	if len(allErrs) != 0 {
-		return admission.Denied(allErrs.ToAggregate().Error())
+	if errs := validateSpec(sn); len(errs) != 0 {
+		return admission.Denied(errs.ToAggregate().Error())
 	}
 
-	root, err := v.getRootNamespace(ctx, ns)
+	msg, err := v.checkNewChild(ctx, ns, sn.Name)
 	if err != nil {
-		return admission.Denied(err.Error())
+		return admission.Errored(http.StatusInternalServerError, err)
+	}
+	if msg != "" {
+		return admission.Denied(msg)
 	}
-	ok, msg, err := v.notMatchingNamingPolicy(ctx, sn.Name, root.Name)
+	return v.checkKeys(sn, nil)
+}
+
+// checkNewChild returns a message explaining the reason if a new sub-namespace `name` of `parent`
+// violates the naming policies or exceeds the limits on the number of sub-namespaces.
+func (v *subNamespaceValidator) checkNewChild(ctx context.Context, parent *corev1.Namespace, name string) (string, error) {
+	root, depth, err := v.getRootNamespace(ctx, parent)
 	if err != nil {
-		return admission.Errored(http.StatusInternalServerError, err)
+		return err.Error(), nil
 	}
-	if !ok {
-		return admission.Denied(fmt.Sprintf("namespace %s is not match naming policies: %s", ns.Name, msg))
+	rn, err := getRootNamespacePolicy(ctx, v.Client, root.Name)
+	if err != nil {
+		return "", err
 	}
-	return admission.Allowed("")
+	policies, _ := v.policies.get()
+	if p := rootNamingPolicy(rn); p != nil {
+		policies = append(slices.Clip(policies), *p)
+	}
+	violations, err := namingPolicyViolations(policies, name, root.Name, depth+1)
+	if err != nil {
+		return "", err
+	}
+	if len(violations) > 0 {
+		return fmt.Sprintf("namespace %s does not match naming policies: %s", name, strings.Join(violations, "; ")), nil
+	}
+
+	return v.exceededLimit(ctx, parent, root, rn, name)
 }
 
-func (v *subNamespaceValidator) handleDelete(ctx context.Context, sn *accuratev2.SubNamespace) admission.Response {
-	if v.allowCascadingDeletion {
+func (v *subNamespaceValidator) handleUpdate(sn, old *accuratev2.SubNamespace) admission.Response {
+	if errs := validateSpec(sn); len(errs) != 0 {
+		return admission.Denied(errs.ToAggregate().Error())
+	}
+	return v.checkKeys(sn, old)
+}
+
+// checkKeys warns about or denies the keys of `sn` that are never propagated, according to the policy.
+func (v *subNamespaceValidator) checkKeys(sn, old *accuratev2.SubNamespace) admission.Response {
+	if v.keys == nil {
 		return admission.Allowed("")
 	}
+	msgs, policy := v.keys.unmatched(sn, old)
+	if len(msgs) == 0 {
+		return admission.Allowed("")
+	}
+	if policy == config.SubNamespaceKeyPolicyDeny {
+		return admission.Denied(strings.Join(msgs, "; "))
+	}
+	return admission.Allowed("").WithWarnings(msgs...)
+}
+
+func validateSpec(sn *accuratev2.SubNamespace) field.ErrorList {
+	var allErrs field.ErrorList
+	allErrs = append(allErrs, v1labelvalidation.ValidateLabels(sn.Spec.Labels, field.NewPath("spec", "labels"))...)
+	allErrs = append(allErrs, v1annotationvalidation.ValidateAnnotations(sn.Spec.Annotations, field.NewPath("spec", "annotations"))...)
+	return allErrs
+}
+
+func (v *subNamespaceValidator) handleDelete(ctx context.Context, sn *accuratev2.SubNamespace) admission.Response {
+	orphan := sn.Spec.DeletionPolicy == accuratev2.DeletionPolicyOrphan
 
 	ns := &corev1.Namespace{}
 	if err := v.Get(ctx, client.ObjectKey{Name: sn.Name}, ns); err != nil {
@@ -131,6 +280,26 @@ func (v *subNamespaceValidator) handleDelete(ctx context.Context, sn *accuratev2
 		return admission.Allowed("")
 	}
 
+	if orphan {
+		// Orphaning never deletes the children, so they would be left under a namespace out of the tree.
+		children, err := v.childNames(ctx, ns.Name)
+		if err != nil {
+			return admission.Errored(http.StatusInternalServerError, err)
+		}
+		if len(children) > 0 {
+			return admission.Denied(fmt.Sprintf("namespace %s cannot be orphaned since it has child namespaces; move them to another parent first", ns.Name))
+		}
+		return admission.Allowed("")
+	}
+
+	allowed, err := cascadingDeletionAllowed(ctx, v.Client, ns, v.allowCascadingDeletion)
+	if err != nil {
+		return admission.Errored(http.StatusInternalServerError, err)
+	}
+	if allowed {
+		return admission.Allowed("")
+	}
+
 	children := &corev1.NamespaceList{}
 	if err := v.List(ctx, children, client.MatchingFields{constants.NamespaceParentKey: ns.Name}); err != nil {
 		return admission.Errored(http.StatusInternalServerError, err)
@@ -142,44 +311,184 @@ func (v *subNamespaceValidator) handleDelete(ctx context.Context, sn *accuratev2
 	return admission.Allowed("")
 }
 
-func (v *subNamespaceValidator) getRootNamespace(ctx context.Context, ns *corev1.Namespace) (*corev1.Namespace, error) {
+// getRootNamespace returns the root namespace of `ns` and the depth of `ns` in the tree.
+func (v *subNamespaceValidator) getRootNamespace(ctx context.Context, ns *corev1.Namespace) (*corev1.Namespace, int, error) {
 	if ns.Labels[constants.LabelType] == constants.NSTypeRoot {
-		return ns, nil
+		return ns, 0, nil
 	}
 
 	parent := &corev1.Namespace{}
 	if err := v.Get(ctx, client.ObjectKey{Name: ns.Labels[constants.LabelParent]}, parent); err != nil {
 		if !apierrors.IsNotFound(err) {
-			return nil, fmt.Errorf("failed to get namespace %s: %w", ns.Labels[constants.LabelParent], err)
+			return nil, 0, fmt.Errorf("failed to get namespace %s: %w", ns.Labels[constants.LabelParent], err)
 		}
-		return nil, fmt.Errorf("namespace %s is not found", ns.Labels[constants.LabelParent])
+		return nil, 0, fmt.Errorf("namespace %s is not found", ns.Labels[constants.LabelParent])
 	}
-	return v.getRootNamespace(ctx, parent)
+	root, depth, err := v.getRootNamespace(ctx, parent)
+	if err != nil {
+		return nil, 0, err
+	}
+	return root, depth + 1, nil
 }
 
-func (v *subNamespaceValidator) notMatchingNamingPolicy(ctx context.Context, ns, root string) (bool, string, error) {
-	for _, policy := range v.namingPolicies {
+// namingPolicyViolations returns every rule of `policies` for `root` that `ns` at `depth` violates.
+func namingPolicyViolations(policies []config.NamingPolicyRegexp, ns, root string, depth int) ([]string, error) {
+	var violations []string
+	for _, policy := range policies {
 		matches := policy.Root.FindAllStringSubmatchIndex(root, -1)
-		if len(matches) > 0 {
+		if len(matches) == 0 {
+			continue
+		}
+		expand := func(template string) (*regexp.Regexp, error) {
 			m := []byte{}
 			for _, match := range matches {
-				m = policy.Root.ExpandString(m, policy.Match, root, match)
+				m = policy.Root.ExpandString(m, template, root, match)
 			}
 			r, err := regexp.Compile(string(m))
 			if err != nil {
-				return false, "", fmt.Errorf("invalid naming policy: %w", err)
+				return nil, fmt.Errorf("invalid naming policy: %w", err)
 			}
+			return r, nil
+		}
+
+		r, err := expand(policy.Match)
+		if err != nil {
+			return nil, err
+		}
+		if !r.MatchString(ns) {
+			violations = append(violations, fmt.Sprintf("namespace - target=%s root=%s denied policy - root=%s match=%s", ns, root, policy.Root, policy.Match))
+		}
+		for _, deny := range policy.Deny {
+			r, err := expand(deny)
+			if err != nil {
+				return nil, err
+			}
+			if r.MatchString(ns) {
+				violations = append(violations, fmt.Sprintf("namespace - target=%s root=%s denied policy - root=%s deny=%s", ns, root, policy.Root, deny))
+			}
+		}
+		if policy.MaxLength > 0 && len(ns) > policy.MaxLength {
+			violations = append(violations, fmt.Sprintf("namespace - target=%s root=%s denied policy - root=%s maxLength=%d", ns, root, policy.Root, policy.MaxLength))
+		}
+		if policy.MaxDepth > 0 && depth > policy.MaxDepth {
+			violations = append(violations, fmt.Sprintf("namespace - target=%s root=%s depth=%d denied policy - root=%s maxDepth=%d", ns, root, depth, policy.Root, policy.MaxDepth))
+		}
+	}
+	return violations, nil
+}
+
+// exceededLimit returns a message explaining the limit if a new sub-namespace `name` of `parent`
+// would exceed the limits on the number of sub-namespaces in the tree of `root`.
+// The limits of `rn` take precedence over the annotations of `root` and the configurations.
+// The namespace `name` is not counted even if it is already grafted.
+func (v *subNamespaceValidator) exceededLimit(ctx context.Context, parent, root *corev1.Namespace, rn *accuratev2.RootNamespace, name string) (string, error) {
+	var maxChildren, maxDescendants int
+	if rn != nil && rn.Spec.SubNamespaceLimits != nil {
+		maxChildren = rn.Spec.SubNamespaceLimits.MaxChildren
+		maxDescendants = rn.Spec.SubNamespaceLimits.MaxDescendants
+	} else {
+		_, limits := v.policies.get()
+		var err error
+		maxChildren, err = limitFor(root, constants.AnnMaxChildren, limits.MaxChildren)
+		if err != nil {
+			return err.Error(), nil
+		}
+		maxDescendants, err = limitFor(root, constants.AnnMaxDescendants, limits.MaxDescendants)
+		if err != nil {
+			return err.Error(), nil
+		}
+	}
 
-			if !r.MatchString(ns) {
-				return false, fmt.Sprintf("namespace - target=%s root=%s denied policy - root=%s match=%s", ns, root, policy.Root, policy.Match), nil
+	if maxChildren > 0 {
+		children, err := v.childNames(ctx, parent.Name)
+		if err != nil {
+			return "", err
+		}
+		children = slices.DeleteFunc(children, func(child string) bool { return child == name })
+		if len(children) >= maxChildren {
+			return fmt.Sprintf("namespace %s already has %d child namespaces, which reaches the limit of %d", parent.Name, len(children), maxChildren), nil
+		}
+	}
+
+	if maxDescendants > 0 {
+		var count int
+		visited := map[string]bool{root.Name: true}
+		queue := []string{root.Name}
+		for len(queue) > 0 {
+			children, err := v.childNames(ctx, queue[0])
+			if err != nil {
+				return "", err
 			}
+			queue = queue[1:]
+			for _, child := range children {
+				if visited[child] {
+					continue
+				}
+				visited[child] = true
+				if child != name {
+					count++
+				}
+				queue = append(queue, child)
+			}
+		}
+		if count >= maxDescendants {
+			return fmt.Sprintf("the tree of root namespace %s already has %d sub-namespaces, which reaches the limit of %d", root.Name, count, maxDescendants), nil
+		}
+	}
+	return "", nil
+}
+
+// childNames returns the names of the child namespaces of `ns`, including those of SubNamespaces
+// whose namespaces are not created yet.
+func (v *subNamespaceValidator) childNames(ctx context.Context, ns string) ([]string, error) {
+	children := &corev1.NamespaceList{}
+	if err := v.List(ctx, children, client.MatchingFields{constants.NamespaceParentKey: ns}); err != nil {
+		return nil, err
+	}
+	subs := &accuratev2.SubNamespaceList{}
+	if err := v.List(ctx, subs, client.InNamespace(ns)); err != nil {
+		return nil, err
+	}
+
+	var names []string
+	for _, child := range children.Items {
+		names = append(names, child.Name)
+	}
+	for _, sn := range subs.Items {
+		if !slices.Contains(names, sn.Name) {
+			names = append(names, sn.Name)
 		}
 	}
-	return true, "", nil
+	return names, nil
+}
+
+func matchKey(key string, patterns []string) bool {
+	for _, p := range patterns {
+		// The glob pattern has been verified to be in the valid format when reading the config file.
+		if ok, _ := path.Match(p, key); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// limitFor returns the limit overridden by the annotation `key` of `root`, or `defaultLimit`.
+func limitFor(root *corev1.Namespace, key string, defaultLimit int) (int, error) {
+	value, ok := root.Annotations[key]
+	if !ok {
+		return defaultLimit, nil
+	}
+	limit, err := strconv.Atoi(value)
+	if err != nil || limit < 0 {
+		return 0, fmt.Errorf("invalid annotation %s=%s on root namespace %s: must be a non-negative integer", key, value, root.Name)
+	}
+	return limit, nil
 }
 
 // SetupSubNamespaceWebhook registers the webhooks for SubNamespace
-func SetupSubNamespaceWebhook(mgr manager.Manager, dec admission.Decoder, namingPolicyRegexps []config.NamingPolicyRegexp, allowCascadingDeletion bool) error {
+// If `policies` is nil, no naming policies or limits other than those of RootNamespaces and root namespaces apply.
+// If `keys` is nil, keys of SubNamespaces are not checked against the propagated keys.
+func SetupSubNamespaceWebhook(mgr manager.Manager, dec admission.Decoder, policies *SubNamespacePolicies, keys *SubNamespaceKeys, allowCascadingDeletion bool) error {
 	for _, s := range []runtime.Object{&accuratev1.SubNamespace{}, &accuratev2alpha1.SubNamespace{}, &accuratev2.SubNamespace{}} {
 		err := ctrl.NewWebhookManagedBy(mgr, s).
 			Complete()
@@ -198,7 +507,8 @@ func SetupSubNamespaceWebhook(mgr manager.Manager, dec admission.Decoder, naming
 	v := &subNamespaceValidator{
 		Client:                 mgr.GetClient(),
 		dec:                    dec,
-		namingPolicies:         namingPolicyRegexps,
+		policies:               policies,
+		keys:                   keys,
 		allowCascadingDeletion: allowCascadingDeletion,
 	}
 	serv.Register("/validate-accurate-cybozu-com-v2-subnamespace", &webhook.Admission{Handler: v})
@@ -2,15 +2,20 @@ package hooks
 
 import (
 	"context"
+	"regexp"
+	"slices"
+	"sync"
 
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
 
 	accuratev2 "github.com/cybozu-go/accurate/api/accurate/v2"
+	"github.com/cybozu-go/accurate/pkg/config"
 	"github.com/cybozu-go/accurate/pkg/constants"
 	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/rest"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
 )
@@ -100,6 +105,218 @@ var _ = Describe("SubNamespace webhook", func() {
 		Expect(errors.ReasonForError(err)).Should(Equal(metav1.StatusReasonForbidden))
 	})
 
+	It("should deny orphaning a SubNamespace with child namespaces", func() {
+		nsR := &corev1.Namespace{}
+		nsR.GenerateName = "ns-"
+		nsR.Labels = map[string]string{constants.LabelType: constants.NSTypeRoot}
+		Expect(k8sClient.Create(ctx, nsR)).To(Succeed())
+
+		snP := &accuratev2.SubNamespace{}
+		snP.Namespace = nsR.Name
+		snP.GenerateName = "ns-p-"
+		snP.Spec.DeletionPolicy = accuratev2.DeletionPolicyOrphan
+		Expect(k8sClient.Create(ctx, snP)).To(Succeed())
+		// Create sub-namespace since no controllers present in this test setup
+		nsP := &corev1.Namespace{}
+		nsP.Name = snP.Name
+		nsP.Labels = map[string]string{constants.LabelParent: nsR.Name}
+		Expect(k8sClient.Create(ctx, nsP)).To(Succeed())
+
+		// A SubNamespace whose namespace is not created yet is also a child
+		snC := &accuratev2.SubNamespace{}
+		snC.Namespace = nsP.Name
+		snC.GenerateName = "ns-c-"
+		Expect(k8sClient.Create(ctx, snC)).To(Succeed())
+
+		err := k8sClient.Delete(ctx, snP)
+		Expect(err).To(HaveOccurred())
+		Expect(errors.ReasonForError(err)).Should(Equal(metav1.StatusReasonForbidden))
+		Expect(err.Error()).To(ContainSubstring("cannot be orphaned"))
+
+		Expect(k8sClient.Delete(ctx, snC)).To(Succeed())
+		Expect(k8sClient.Delete(ctx, snP)).To(Succeed())
+	})
+
+	Context("Keys", func() {
+		var root *corev1.Namespace
+
+		BeforeEach(func() {
+			root = &corev1.Namespace{}
+			root.GenerateName = "keys-"
+			root.Labels = map[string]string{constants.LabelType: constants.NSTypeRoot}
+			Expect(k8sClient.Create(ctx, root)).To(Succeed())
+		})
+
+		It("should warn about keys that are never propagated", func() {
+			warnings := &warningRecorder{}
+			cfg := rest.CopyConfig(k8sCfg)
+			cfg.WarningHandler = warnings
+			c, err := client.New(cfg, client.Options{Scheme: k8sClient.Scheme()})
+			Expect(err).NotTo(HaveOccurred())
+
+			sn := &accuratev2.SubNamespace{}
+			sn.Namespace = root.Name
+			sn.GenerateName = "keys-sub-"
+			sn.Spec.Labels = map[string]string{"foo": "bar", "team.example.com/name": "a", "bar": "baz"}
+			sn.Spec.Annotations = map[string]string{"foo": "bar"}
+			Expect(c.Create(ctx, sn)).To(Succeed())
+			Expect(warnings.messages()).To(ConsistOf(ContainSubstring("spec.labels bar is not propagated")))
+
+			warnings.reset()
+			sn.Spec.Annotations["qux"] = "quux"
+			Expect(c.Update(ctx, sn)).To(Succeed())
+			// unchanged keys are not reported again
+			Expect(warnings.messages()).To(ConsistOf(ContainSubstring("spec.annotations qux is not propagated")))
+		})
+
+		It("should deny keys that are never propagated with the deny policy", func() {
+			subNamespaceKeys.Update(&config.Config{
+				SubNamespaceLabelKeys: []string{"foo", "team.example.com/*"},
+				SubNamespaceKeyPolicy: config.SubNamespaceKeyPolicyDeny,
+			})
+			DeferCleanup(func() {
+				subNamespaceKeys.Update(&config.Config{
+					SubNamespaceLabelKeys:      []string{"foo", "team.example.com/*"},
+					SubNamespaceAnnotationKeys: []string{"foo"},
+				})
+			})
+
+			sn := &accuratev2.SubNamespace{}
+			sn.Namespace = root.Name
+			sn.GenerateName = "keys-sub-"
+			sn.Spec.Labels = map[string]string{"bar": "baz"}
+			err := k8sClient.Create(ctx, sn)
+			Expect(err).To(HaveOccurred())
+			Expect(errors.ReasonForError(err)).Should(Equal(metav1.StatusReasonForbidden))
+			Expect(err.Error()).To(ContainSubstring("spec.labels bar is not propagated"))
+
+			sn.Spec.Labels = map[string]string{"team.example.com/name": "a"}
+			Expect(k8sClient.Create(ctx, sn)).To(Succeed())
+
+			sn.Spec.Labels["bar"] = "baz"
+			err = k8sClient.Update(ctx, sn)
+			Expect(err).To(HaveOccurred())
+			Expect(errors.ReasonForError(err)).Should(Equal(metav1.StatusReasonForbidden))
+		})
+
+		It("should deny updates with invalid labels", func() {
+			sn := &accuratev2.SubNamespace{}
+			sn.Namespace = root.Name
+			sn.GenerateName = "keys-sub-"
+			sn.Spec.Labels = map[string]string{"foo": "bar"}
+			Expect(k8sClient.Create(ctx, sn)).To(Succeed())
+
+			sn.Spec.Labels["foo"] = "~"
+			err := k8sClient.Update(ctx, sn)
+			Expect(err).To(HaveOccurred())
+			Expect(errors.ReasonForError(err)).Should(Equal(metav1.StatusReasonForbidden))
+		})
+	})
+
+	Context("Limits", func() {
+		It("should deny SubNamespaces exceeding the limit of children of a namespace", func() {
+			root := &corev1.Namespace{}
+			root.Name = "limit-children-root"
+			root.Labels = map[string]string{constants.LabelType: constants.NSTypeRoot}
+			root.Annotations = map[string]string{constants.AnnMaxChildren: "2"}
+			Expect(k8sClient.Create(ctx, root)).To(Succeed())
+
+			for _, name := range []string{"limit-children-1", "limit-children-2"} {
+				sn := &accuratev2.SubNamespace{}
+				sn.Namespace = root.Name
+				sn.Name = name
+				Expect(k8sClient.Create(ctx, sn)).To(Succeed())
+			}
+
+			sn := &accuratev2.SubNamespace{}
+			sn.Namespace = root.Name
+			sn.Name = "limit-children-3"
+			err := k8sClient.Create(ctx, sn)
+			Expect(err).To(HaveOccurred())
+			Expect(errors.ReasonForError(err)).Should(Equal(metav1.StatusReasonForbidden))
+			Expect(err.Error()).To(ContainSubstring("limit of 2"))
+		})
+
+		It("should deny SubNamespaces exceeding the limit of descendants of a root namespace", func() {
+			root := &corev1.Namespace{}
+			root.Name = "limit-descendants-root"
+			root.Labels = map[string]string{constants.LabelType: constants.NSTypeRoot}
+			root.Annotations = map[string]string{constants.AnnMaxDescendants: "2"}
+			Expect(k8sClient.Create(ctx, root)).To(Succeed())
+
+			sub := &corev1.Namespace{}
+			sub.Name = "limit-descendants-1"
+			sub.Labels = map[string]string{constants.LabelParent: root.Name}
+			Expect(k8sClient.Create(ctx, sub)).To(Succeed())
+
+			sn := &accuratev2.SubNamespace{}
+			sn.Namespace = sub.Name
+			sn.Name = "limit-descendants-2"
+			Expect(k8sClient.Create(ctx, sn)).To(Succeed())
+
+			sn = &accuratev2.SubNamespace{}
+			sn.Namespace = root.Name
+			sn.Name = "limit-descendants-3"
+			err := k8sClient.Create(ctx, sn)
+			Expect(err).To(HaveOccurred())
+			Expect(errors.ReasonForError(err)).Should(Equal(metav1.StatusReasonForbidden))
+			Expect(err.Error()).To(ContainSubstring("root namespace limit-descendants-root already has 2 sub-namespaces"))
+		})
+
+		It("should apply the updated naming policies and limits", func() {
+			policies, limits := subNamespacePolicies.get()
+			DeferCleanup(func() {
+				subNamespacePolicies.Update(&config.Config{NamingPolicyRegexps: policies, SubNamespaceLimits: limits})
+			})
+
+			root := &corev1.Namespace{}
+			root.Name = "limit-reload-root"
+			root.Labels = map[string]string{constants.LabelType: constants.NSTypeRoot}
+			Expect(k8sClient.Create(ctx, root)).To(Succeed())
+
+			sn := &accuratev2.SubNamespace{}
+			sn.Namespace = root.Name
+			sn.Name = "limit-reload-1"
+			Expect(k8sClient.Create(ctx, sn)).To(Succeed())
+
+			subNamespacePolicies.Update(&config.Config{
+				NamingPolicyRegexps: []config.NamingPolicyRegexp{
+					{Root: regexp.MustCompile("^limit-reload-root$"), Match: "^limit-reload-[0-9]+$"},
+				},
+				SubNamespaceLimits: config.SubNamespaceLimits{MaxChildren: 1},
+			})
+
+			sn = &accuratev2.SubNamespace{}
+			sn.Namespace = root.Name
+			sn.Name = "limit-reload-x"
+			err := k8sClient.Create(ctx, sn)
+			Expect(err).To(HaveOccurred())
+			Expect(errors.ReasonForError(err)).Should(Equal(metav1.StatusReasonForbidden))
+			Expect(err.Error()).To(ContainSubstring("match=^limit-reload-[0-9]+$"))
+
+			sn.Name = "limit-reload-2"
+			err = k8sClient.Create(ctx, sn)
+			Expect(err).To(HaveOccurred())
+			Expect(errors.ReasonForError(err)).Should(Equal(metav1.StatusReasonForbidden))
+			Expect(err.Error()).To(ContainSubstring("limit of 1"))
+		})
+
+		It("should deny SubNamespaces if the limit annotation is invalid", func() {
+			root := &corev1.Namespace{}
+			root.Name = "limit-invalid-root"
+			root.Labels = map[string]string{constants.LabelType: constants.NSTypeRoot}
+			root.Annotations = map[string]string{constants.AnnMaxChildren: "many"}
+			Expect(k8sClient.Create(ctx, root)).To(Succeed())
+
+			sn := &accuratev2.SubNamespace{}
+			sn.Namespace = root.Name
+			sn.Name = "limit-invalid-1"
+			err := k8sClient.Create(ctx, sn)
+			Expect(err).To(HaveOccurred())
+			Expect(errors.ReasonForError(err)).Should(Equal(metav1.StatusReasonForbidden))
+		})
+	})
+
 	Context("Naming Policy", func() {
 		When("the root namespace name is matched some Root Naming Policies", func() {
 			When("the SubNamespace name is matched to the Root's Match Naming Policy", func() {
@@ -304,5 +521,87 @@ var _ = Describe("SubNamespace webhook", func() {
 				})
 			})
 		})
+
+		When("the naming policy has deny patterns, a length limit, and a max depth", func() {
+			It("should deny SubNamespaces violating them and list every violation", func() {
+				root := &corev1.Namespace{}
+				root.Name = "tenant-a"
+				root.Labels = map[string]string{constants.LabelType: constants.NSTypeRoot}
+				Expect(k8sClient.Create(ctx, root)).To(Succeed())
+
+				sn := &accuratev2.SubNamespace{}
+				sn.Namespace = "tenant-a"
+				sn.Name = "tenant-a-app"
+				Expect(k8sClient.Create(ctx, sn)).To(Succeed())
+
+				sn = &accuratev2.SubNamespace{}
+				sn.Namespace = "tenant-a"
+				sn.Name = "tenant-a-very-long-name-for-a-sub-namespace-system"
+				err := k8sClient.Create(ctx, sn)
+				Expect(err).To(HaveOccurred())
+				Expect(errors.ReasonForError(err)).Should(Equal(metav1.StatusReasonForbidden))
+				Expect(err.Error()).To(ContainSubstring("namespace tenant-a-very-long-name-for-a-sub-namespace-system does not match naming policies"))
+				Expect(err.Error()).To(ContainSubstring("deny=-system$"))
+				Expect(err.Error()).To(ContainSubstring("maxLength=40"))
+			})
+
+			It("should deny SubNamespaces deeper than the max depth", func() {
+				root := &corev1.Namespace{}
+				root.Name = "tenant-b"
+				root.Labels = map[string]string{constants.LabelType: constants.NSTypeRoot}
+				Expect(k8sClient.Create(ctx, root)).To(Succeed())
+
+				parent := "tenant-b"
+				for _, name := range []string{"tenant-b-1", "tenant-b-2"} {
+					ns := &corev1.Namespace{}
+					ns.Name = name
+					ns.Labels = map[string]string{constants.LabelParent: parent}
+					Expect(k8sClient.Create(ctx, ns)).To(Succeed())
+					parent = name
+				}
+
+				sn := &accuratev2.SubNamespace{}
+				sn.Namespace = "tenant-b-2"
+				sn.Name = "tenant-b-3"
+				Expect(k8sClient.Create(ctx, sn)).To(Succeed())
+
+				ns := &corev1.Namespace{}
+				ns.Name = "tenant-b-3"
+				ns.Labels = map[string]string{constants.LabelParent: "tenant-b-2"}
+				Expect(k8sClient.Create(ctx, ns)).To(Succeed())
+
+				sn = &accuratev2.SubNamespace{}
+				sn.Namespace = "tenant-b-3"
+				sn.Name = "tenant-b-4"
+				err := k8sClient.Create(ctx, sn)
+				Expect(err).To(HaveOccurred())
+				Expect(errors.ReasonForError(err)).Should(Equal(metav1.StatusReasonForbidden))
+				Expect(err.Error()).To(ContainSubstring("maxDepth=3"))
+			})
+		})
 	})
 })
+
+// warningRecorder records the warnings returned from the API server.
+type warningRecorder struct {
+	mu       sync.Mutex
+	warnings []string
+}
+
+func (r *warningRecorder) HandleWarningHeader(_ int, _ string, text string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.warnings = append(r.warnings, text)
+}
+
+func (r *warningRecorder) messages() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return slices.Clone(r.warnings)
+}
+
+func (r *warningRecorder) reset() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.warnings = nil
+}
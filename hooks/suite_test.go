@@ -14,15 +14,19 @@ import (
 	. "github.com/onsi/gomega"
 
 	admissionv1beta1 "k8s.io/api/admission/v1beta1"
+	admissionregistrationv1 "k8s.io/api/admissionregistration/v1"
 	//+kubebuilder:scaffold:imports
 	accuratev1 "github.com/cybozu-go/accurate/api/accurate/v1"
 	accuratev2 "github.com/cybozu-go/accurate/api/accurate/v2"
 	accuratev2alpha1 "github.com/cybozu-go/accurate/api/accurate/v2alpha1"
 	"github.com/cybozu-go/accurate/pkg/config"
+	"github.com/cybozu-go/accurate/pkg/constants"
 	"github.com/cybozu-go/accurate/pkg/indexing"
 	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/rest"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/envtest"
@@ -38,8 +42,11 @@ import (
 // These tests use Ginkgo (BDD-style Go testing framework). Refer to
 // http://onsi.github.io/ginkgo/ to learn more about Ginkgo.
 
+var k8sCfg *rest.Config
 var k8sClient client.Client
 var testEnv *envtest.Environment
+var subNamespaceKeys *SubNamespaceKeys
+var subNamespacePolicies *SubNamespacePolicies
 var cancelMgr context.CancelFunc
 
 func TestAPIs(t *testing.T) {
@@ -73,13 +80,16 @@ var _ = BeforeSuite(func() {
 		Scheme: scheme,
 		CRDs:   loadCRDs(),
 		WebhookInstallOptions: envtest.WebhookInstallOptions{
-			Paths: []string{filepath.Join("..", "config", "webhook")},
+			Paths:              []string{filepath.Join("..", "config", "webhook")},
+			MutatingWebhooks:   []*admissionregistrationv1.MutatingWebhookConfiguration{autoGraftWebhookConfiguration()},
+			ValidatingWebhooks: []*admissionregistrationv1.ValidatingWebhookConfiguration{propagatedCopyWebhookConfiguration()},
 		},
 	}
 
 	cfg, err := testEnv.Start()
 	Expect(err).NotTo(HaveOccurred())
 	Expect(cfg).NotTo(BeNil())
+	k8sCfg = cfg
 
 	k8sClient, err = client.New(cfg, client.Options{Scheme: scheme})
 	Expect(err).NotTo(HaveOccurred())
@@ -103,7 +113,10 @@ var _ = BeforeSuite(func() {
 	Expect(err).NotTo(HaveOccurred())
 
 	dec := admission.NewDecoder(scheme)
-	SetupNamespaceWebhook(mgr, dec, false)
+	SetupAccurateConfigWebhook(mgr, dec)
+	SetupRootNamespaceWebhook(mgr, dec)
+	err = SetupPropagatedCopyWebhook(ctx, mgr, dec)
+	Expect(err).NotTo(HaveOccurred())
 
 	conf := config.Config{
 		NamingPolicies: []config.NamingPolicy{
@@ -135,11 +148,29 @@ var _ = BeforeSuite(func() {
 				Root:  "^unuse-naming-group-(?P<team>.*)",
 				Match: "^unuse-naming-group-child1",
 			},
+			{
+				Root:      "^tenant-.*",
+				Deny:      []string{"-system$"},
+				MaxLength: 40,
+				MaxDepth:  3,
+			},
+		},
+		SubNamespaceLabelKeys:      []string{"foo", "team.example.com/*"},
+		SubNamespaceAnnotationKeys: []string{"foo"},
+		AutoGraftRules: []config.AutoGraftRule{
+			{Match: "^autograft-(?P<team>[a-z0-9]+)-", Parent: "autograft-${team}"},
+		},
+		Watches: []config.Watch{
+			{GroupVersionKind: metav1.GroupVersionKind{Version: "v1", Kind: "ConfigMap"}},
 		},
 	}
 	err = conf.Validate(mgr.GetRESTMapper())
 	Expect(err).NotTo(HaveOccurred())
-	err = SetupSubNamespaceWebhook(mgr, dec, conf.NamingPolicyRegexps, false)
+	subNamespaceKeys = NewSubNamespaceKeys(&conf)
+	subNamespacePolicies = NewSubNamespacePolicies(&conf)
+	SetupNamespaceTemplateWebhook(mgr, dec, NewWatchedKinds(&conf))
+	SetupNamespaceWebhook(mgr, dec, NewAutoGraftRules(&conf), subNamespacePolicies, false, false)
+	err = SetupSubNamespaceWebhook(mgr, dec, subNamespacePolicies, subNamespaceKeys, false)
 	Expect(err).NotTo(HaveOccurred())
 
 	go func() {
@@ -192,3 +223,72 @@ func loadCRDs() []*apiextensionsv1.CustomResourceDefinition {
 
 	return crds
 }
+
+// autoGraftWebhookConfiguration returns the configuration that the Helm chart renders
+// when auto-graft is used.
+func autoGraftWebhookConfiguration() *admissionregistrationv1.MutatingWebhookConfiguration {
+	path := AutoGraftWebhookPath
+	failurePolicy := admissionregistrationv1.Fail
+	sideEffects := admissionregistrationv1.SideEffectClassNone
+	wc := &admissionregistrationv1.MutatingWebhookConfiguration{}
+	wc.Name = "auto-graft-webhook-configuration"
+	wc.Webhooks = []admissionregistrationv1.MutatingWebhook{{
+		Name:                    "mnamespace.accurate.cybozu.io",
+		AdmissionReviewVersions: []string{"v1"},
+		ClientConfig: admissionregistrationv1.WebhookClientConfig{
+			Service: &admissionregistrationv1.ServiceReference{
+				Name:      "webhook-service",
+				Namespace: "system",
+				Path:      &path,
+			},
+		},
+		FailurePolicy: &failurePolicy,
+		SideEffects:   &sideEffects,
+		Rules: []admissionregistrationv1.RuleWithOperations{{
+			Operations: []admissionregistrationv1.OperationType{admissionregistrationv1.Create},
+			Rule: admissionregistrationv1.Rule{
+				APIGroups:   []string{""},
+				APIVersions: []string{"v1"},
+				Resources:   []string{"namespaces"},
+			},
+		}},
+	}}
+	return wc
+}
+
+// propagatedCopyWebhookConfiguration returns the configuration that the Helm chart renders
+// for the propagated copies of ConfigMaps.
+func propagatedCopyWebhookConfiguration() *admissionregistrationv1.ValidatingWebhookConfiguration {
+	path := PropagatedCopyWebhookPath
+	failurePolicy := admissionregistrationv1.Fail
+	sideEffects := admissionregistrationv1.SideEffectClassNone
+	scope := admissionregistrationv1.NamespacedScope
+	wc := &admissionregistrationv1.ValidatingWebhookConfiguration{}
+	wc.Name = "propagated-copy-webhook-configuration"
+	wc.Webhooks = []admissionregistrationv1.ValidatingWebhook{{
+		Name:                    "propagated-copy.accurate.cybozu.io",
+		AdmissionReviewVersions: []string{"v1"},
+		ClientConfig: admissionregistrationv1.WebhookClientConfig{
+			Service: &admissionregistrationv1.ServiceReference{
+				Name:      "webhook-service",
+				Namespace: "system",
+				Path:      &path,
+			},
+		},
+		FailurePolicy: &failurePolicy,
+		SideEffects:   &sideEffects,
+		ObjectSelector: &metav1.LabelSelector{
+			MatchLabels: map[string]string{constants.LabelCreatedBy: constants.CreatedBy},
+		},
+		Rules: []admissionregistrationv1.RuleWithOperations{{
+			Operations: []admissionregistrationv1.OperationType{admissionregistrationv1.Update, admissionregistrationv1.Delete},
+			Rule: admissionregistrationv1.Rule{
+				APIGroups:   []string{""},
+				APIVersions: []string{"v1"},
+				Resources:   []string{"configmaps"},
+				Scope:       &scope,
+			},
+		}},
+	}}
+	return wc
+}
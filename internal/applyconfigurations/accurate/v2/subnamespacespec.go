@@ -2,6 +2,10 @@
 
 package v2
 
+import (
+	accuratev2 "github.com/cybozu-go/accurate/api/accurate/v2"
+)
+
 // SubNamespaceSpecApplyConfiguration represents a declarative configuration of the SubNamespaceSpec type for use
 // with apply.
 //
@@ -11,6 +15,14 @@ type SubNamespaceSpecApplyConfiguration struct {
 	Labels map[string]string `json:"labels,omitempty"`
 	// Annotations are the annotations to be propagated to the sub-namespace.
 	Annotations map[string]string `json:"annotations,omitempty"`
+	// DeletionPolicy specifies what happens to the sub-namespace when the SubNamespace is deleted.
+	// "Delete" deletes the sub-namespace. "Orphan" detaches the sub-namespace from its parent and keeps it.
+	// Defaults to "Delete".
+	DeletionPolicy *accuratev2.DeletionPolicy `json:"deletionPolicy,omitempty"`
+	// KeepPropagatedResources keeps the resources propagated to the sub-namespace as ordinary resources
+	// when the sub-namespace is orphaned. Otherwise, they are deleted.
+	// This is effective only when DeletionPolicy is "Orphan".
+	KeepPropagatedResources *bool `json:"keepPropagatedResources,omitempty"`
 }
 
 // SubNamespaceSpecApplyConfiguration constructs a declarative configuration of the SubNamespaceSpec type for use with
@@ -46,3 +58,19 @@ func (b *SubNamespaceSpecApplyConfiguration) WithAnnotations(entries map[string]
 	}
 	return b
 }
+
+// WithDeletionPolicy sets the DeletionPolicy field in the declarative configuration to the given value
+// and returns the receiver, so that objects can be built by chaining "With" function invocations.
+// If called multiple times, the DeletionPolicy field is set to the value of the last call.
+func (b *SubNamespaceSpecApplyConfiguration) WithDeletionPolicy(value accuratev2.DeletionPolicy) *SubNamespaceSpecApplyConfiguration {
+	b.DeletionPolicy = &value
+	return b
+}
+
+// WithKeepPropagatedResources sets the KeepPropagatedResources field in the declarative configuration to the given value
+// and returns the receiver, so that objects can be built by chaining "With" function invocations.
+// If called multiple times, the KeepPropagatedResources field is set to the value of the last call.
+func (b *SubNamespaceSpecApplyConfiguration) WithKeepPropagatedResources(value bool) *SubNamespaceSpecApplyConfiguration {
+	b.KeepPropagatedResources = &value
+	return b
+}
@@ -3,6 +3,8 @@
 package v2
 
 import (
+	corev1 "k8s.io/api/core/v1"
+	types "k8s.io/apimachinery/pkg/types"
 	v1 "k8s.io/client-go/applyconfigurations/meta/v1"
 )
 
@@ -17,6 +19,14 @@ type SubNamespaceStatusApplyConfiguration struct {
 	ObservedGeneration *int64 `json:"observedGeneration,omitempty"`
 	// Conditions represent the latest available observations of an object's state
 	Conditions []v1.ConditionApplyConfiguration `json:"conditions,omitempty"`
+	// NamespaceUID is the UID of the namespace created for the SubNamespace.
+	NamespaceUID *types.UID `json:"namespaceUID,omitempty"`
+	// NamespacePhase is the phase of the namespace created for the SubNamespace.
+	NamespacePhase *corev1.NamespacePhase `json:"namespacePhase,omitempty"`
+	// Path is the names of the namespaces from the root namespace to the created namespace.
+	Path []string `json:"path,omitempty"`
+	// PropagatedResources is the number of resources propagated into the created namespace.
+	PropagatedResources *int32 `json:"propagatedResources,omitempty"`
 }
 
 // SubNamespaceStatusApplyConfiguration constructs a declarative configuration of the SubNamespaceStatus type for use with
@@ -45,3 +55,37 @@ func (b *SubNamespaceStatusApplyConfiguration) WithConditions(values ...*v1.Cond
 	}
 	return b
 }
+
+// WithNamespaceUID sets the NamespaceUID field in the declarative configuration to the given value
+// and returns the receiver, so that objects can be built by chaining "With" function invocations.
+// If called multiple times, the NamespaceUID field is set to the value of the last call.
+func (b *SubNamespaceStatusApplyConfiguration) WithNamespaceUID(value types.UID) *SubNamespaceStatusApplyConfiguration {
+	b.NamespaceUID = &value
+	return b
+}
+
+// WithNamespacePhase sets the NamespacePhase field in the declarative configuration to the given value
+// and returns the receiver, so that objects can be built by chaining "With" function invocations.
+// If called multiple times, the NamespacePhase field is set to the value of the last call.
+func (b *SubNamespaceStatusApplyConfiguration) WithNamespacePhase(value corev1.NamespacePhase) *SubNamespaceStatusApplyConfiguration {
+	b.NamespacePhase = &value
+	return b
+}
+
+// WithPath adds the given value to the Path field in the declarative configuration
+// and returns the receiver, so that objects can be build by chaining "With" function invocations.
+// If called multiple times, values provided by each call will be appended to the Path field.
+func (b *SubNamespaceStatusApplyConfiguration) WithPath(values ...string) *SubNamespaceStatusApplyConfiguration {
+	for i := range values {
+		b.Path = append(b.Path, values[i])
+	}
+	return b
+}
+
+// WithPropagatedResources sets the PropagatedResources field in the declarative configuration to the given value
+// and returns the receiver, so that objects can be built by chaining "With" function invocations.
+// If called multiple times, the PropagatedResources field is set to the value of the last call.
+func (b *SubNamespaceStatusApplyConfiguration) WithPropagatedResources(value int32) *SubNamespaceStatusApplyConfiguration {
+	b.PropagatedResources = &value
+	return b
+}
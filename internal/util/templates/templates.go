@@ -0,0 +1,29 @@
+package templates
+
+import (
+	"strings"
+
+	"github.com/cybozu-go/accurate/pkg/constants"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// Names returns the templates referenced by `ns` in precedence order; later ones take precedence.
+// They are taken from `accurate.cybozu.com/templates` annotation if it is set,
+// or otherwise from `accurate.cybozu.com/template` label.
+func Names(ns metav1.Object) []string {
+	if ann := ns.GetAnnotations()[constants.AnnTemplates]; ann != "" {
+		var names []string
+		for _, name := range strings.Split(ann, ",") {
+			name = strings.TrimSpace(name)
+			if name == "" {
+				continue
+			}
+			names = append(names, name)
+		}
+		return names
+	}
+	if tmpl := ns.GetLabels()[constants.LabelTemplate]; tmpl != "" {
+		return []string{tmpl}
+	}
+	return nil
+}
@@ -0,0 +1,47 @@
+package templates
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/cybozu-go/accurate/pkg/constants"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestNames(t *testing.T) {
+	tests := []struct {
+		name        string
+		labels      map[string]string
+		annotations map[string]string
+		want        []string
+	}{
+		{
+			name: "none",
+		},
+		{
+			name:   "label",
+			labels: map[string]string{constants.LabelTemplate: "base"},
+			want:   []string{"base"},
+		},
+		{
+			name:        "annotation",
+			annotations: map[string]string{constants.AnnTemplates: "base, monitoring,,"},
+			want:        []string{"base", "monitoring"},
+		},
+		{
+			name:        "annotation takes precedence",
+			labels:      map[string]string{constants.LabelTemplate: "base"},
+			annotations: map[string]string{constants.AnnTemplates: "monitoring"},
+			want:        []string{"monitoring"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ns := &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Labels: tt.labels, Annotations: tt.annotations}}
+			if got := Names(ns); !slices.Equal(got, tt.want) {
+				t.Errorf("Names() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
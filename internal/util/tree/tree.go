@@ -0,0 +1,41 @@
+package tree
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/cybozu-go/accurate/pkg/constants"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// ErrCircularReference is returned when the parents of a namespace form a cycle.
+var ErrCircularReference = errors.New("circular reference found")
+
+// FindRoot returns the name of the root namespace of the tree that the namespace `name` belongs to
+// by following the parent labels. The root may not be a root namespace yet.
+// A namespace that does not exist is the root of its own tree.
+// If the parents form a cycle, an error wrapping ErrCircularReference is returned.
+func FindRoot(ctx context.Context, c client.Reader, name string) (string, error) {
+	visited := map[string]bool{name: true}
+	for {
+		ns := &corev1.Namespace{}
+		if err := c.Get(ctx, client.ObjectKey{Name: name}, ns); err != nil {
+			if apierrors.IsNotFound(err) {
+				return name, nil
+			}
+			return "", fmt.Errorf("failed to get namespace %s: %w", name, err)
+		}
+		parent := ns.Labels[constants.LabelParent]
+		if parent == "" {
+			return name, nil
+		}
+		if visited[parent] {
+			return "", fmt.Errorf("%w at namespace %s", ErrCircularReference, parent)
+		}
+		visited[parent] = true
+		name = parent
+	}
+}
@@ -0,0 +1,55 @@
+package tree
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/cybozu-go/accurate/pkg/constants"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func namespace(name, parent string) *corev1.Namespace {
+	ns := &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: name}}
+	if parent != "" {
+		ns.Labels = map[string]string{constants.LabelParent: parent}
+	}
+	return ns
+}
+
+func TestFindRoot(t *testing.T) {
+	c := fake.NewClientBuilder().WithObjects(
+		namespace("root", ""),
+		namespace("sub1", "root"),
+		namespace("sub1-sub", "sub1"),
+		namespace("orphan", "missing"),
+		namespace("loop1", "loop2"),
+		namespace("loop2", "loop1"),
+	).Build()
+
+	tests := []struct {
+		name    string
+		ns      string
+		want    string
+		wantErr error
+	}{
+		{name: "root", ns: "root", want: "root"},
+		{name: "sub-namespace", ns: "sub1-sub", want: "root"},
+		{name: "missing namespace", ns: "missing", want: "missing"},
+		{name: "missing parent", ns: "orphan", want: "missing"},
+		{name: "cycle", ns: "loop1", wantErr: ErrCircularReference},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := FindRoot(context.Background(), c, tt.ns)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("FindRoot() error = %v, want %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("FindRoot() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
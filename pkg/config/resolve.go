@@ -0,0 +1,106 @@
+package config
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/errors"
+	"k8s.io/client-go/restmapper"
+)
+
+// propagationVerbs are the verbs Accurate uses for propagated resources.
+var propagationVerbs = []string{"get", "list", "watch", "create", "patch", "delete"}
+
+// ResolveWatches returns the watches for the resources that exist in `groups`,
+// the API resources obtained from discovery.
+//
+// Wildcard watches are expanded into the namespace-scoped resources of the group
+// that support all the verbs for propagation.  If the version of a wildcard watch
+// is empty, the preferred version of the group is used.  Explicit watches take
+// precedence over wildcard ones for the same resource.
+//
+// Watches for resources that do not exist are omitted.  An error is returned
+// for a non-optional wildcard watch that matches no resources.
+// Validate should be called before this to check explicit watches.
+func (c *Config) ResolveWatches(groups []*restmapper.APIGroupResources) ([]Watch, error) {
+	var errList []error
+	var resolved []Watch
+	seen := make(map[schema.GroupKind]bool)
+
+	find := func(group string) *restmapper.APIGroupResources {
+		for _, g := range groups {
+			if g.Group.Name == group {
+				return g
+			}
+		}
+		return nil
+	}
+
+	for _, w := range c.Watches {
+		if w.IsWildcard() {
+			continue
+		}
+		g := find(w.Group)
+		if g == nil {
+			continue
+		}
+		idx := slices.IndexFunc(g.VersionedResources[w.Version], func(r metav1.APIResource) bool {
+			return r.Kind == w.Kind && !strings.Contains(r.Name, "/")
+		})
+		if idx < 0 {
+			continue
+		}
+		gk := schema.GroupKind{Group: w.Group, Kind: w.Kind}
+		if seen[gk] {
+			continue
+		}
+		seen[gk] = true
+		resolved = append(resolved, w)
+	}
+
+	for _, w := range c.Watches {
+		if !w.IsWildcard() {
+			continue
+		}
+
+		var found bool
+		if g := find(w.Group); g != nil {
+			version := w.Version
+			if version == "" {
+				version = g.Group.PreferredVersion.Version
+			}
+			for _, r := range g.VersionedResources[version] {
+				if strings.Contains(r.Name, "/") || !r.Namespaced || !hasVerbs(r.Verbs, propagationVerbs) {
+					continue
+				}
+				found = true
+				gk := schema.GroupKind{Group: w.Group, Kind: r.Kind}
+				if seen[gk] {
+					continue
+				}
+				seen[gk] = true
+				rw := w
+				rw.Version = version
+				rw.Kind = r.Kind
+				resolved = append(resolved, rw)
+			}
+		}
+		if !found && !w.Optional {
+			errList = append(errList, fmt.Errorf("no resources to propagate are found for %s", w.GroupVersionKind.String()))
+		}
+	}
+
+	return resolved, errors.NewAggregate(errList)
+}
+
+func hasVerbs(verbs []string, required []string) bool {
+	for _, v := range required {
+		if !slices.Contains(verbs, v) {
+			return false
+		}
+	}
+	return true
+}
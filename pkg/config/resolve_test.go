@@ -0,0 +1,113 @@
+package config
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/restmapper"
+)
+
+func TestResolveWatches(t *testing.T) {
+	allVerbs := metav1.Verbs{"get", "list", "watch", "create", "update", "patch", "delete"}
+	groups := []*restmapper.APIGroupResources{
+		{
+			Group: metav1.APIGroup{
+				Name:             "",
+				Versions:         []metav1.GroupVersionForDiscovery{{GroupVersion: "v1", Version: "v1"}},
+				PreferredVersion: metav1.GroupVersionForDiscovery{GroupVersion: "v1", Version: "v1"},
+			},
+			VersionedResources: map[string][]metav1.APIResource{
+				"v1": {{Name: "secrets", Namespaced: true, Kind: "Secret", Verbs: allVerbs}},
+			},
+		},
+		{
+			Group: metav1.APIGroup{
+				Name: "monitoring.coreos.com",
+				Versions: []metav1.GroupVersionForDiscovery{
+					{GroupVersion: "monitoring.coreos.com/v1", Version: "v1"},
+					{GroupVersion: "monitoring.coreos.com/v1alpha1", Version: "v1alpha1"},
+				},
+				PreferredVersion: metav1.GroupVersionForDiscovery{GroupVersion: "monitoring.coreos.com/v1", Version: "v1"},
+			},
+			VersionedResources: map[string][]metav1.APIResource{
+				"v1": {
+					{Name: "prometheusrules", Namespaced: true, Kind: "PrometheusRule", Verbs: allVerbs},
+					{Name: "prometheusrules/status", Namespaced: true, Kind: "PrometheusRule", Verbs: allVerbs},
+					{Name: "servicemonitors", Namespaced: true, Kind: "ServiceMonitor", Verbs: allVerbs},
+					{Name: "clustermonitors", Namespaced: false, Kind: "ClusterMonitor", Verbs: allVerbs},
+					{Name: "readonlies", Namespaced: true, Kind: "ReadOnly", Verbs: metav1.Verbs{"get", "list"}},
+				},
+				"v1alpha1": {
+					{Name: "alertmanagerconfigs", Namespaced: true, Kind: "AlertmanagerConfig", Verbs: allVerbs},
+				},
+			},
+		},
+	}
+
+	c := &Config{
+		Watches: []Watch{
+			{
+				GroupVersionKind: metav1.GroupVersionKind{Group: "monitoring.coreos.com", Kind: WildcardKind},
+				ConflictPolicy:   ConflictPolicySkip,
+			},
+			{
+				GroupVersionKind: metav1.GroupVersionKind{Group: "monitoring.coreos.com", Version: "v1", Kind: "ServiceMonitor"},
+				ConflictPolicy:   ConflictPolicyOverwrite,
+			},
+			{
+				GroupVersionKind: metav1.GroupVersionKind{Version: "v1", Kind: "Secret"},
+			},
+			{
+				GroupVersionKind: metav1.GroupVersionKind{Group: "example.com", Version: "v1", Kind: "Foo"},
+				Optional:         true,
+			},
+			{
+				GroupVersionKind: metav1.GroupVersionKind{Group: "example.com", Kind: WildcardKind},
+				Optional:         true,
+			},
+		},
+	}
+	resolved, err := c.ResolveWatches(groups)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []Watch{
+		{
+			GroupVersionKind: metav1.GroupVersionKind{Group: "monitoring.coreos.com", Version: "v1", Kind: "ServiceMonitor"},
+			ConflictPolicy:   ConflictPolicyOverwrite,
+		},
+		{
+			GroupVersionKind: metav1.GroupVersionKind{Version: "v1", Kind: "Secret"},
+		},
+		{
+			GroupVersionKind: metav1.GroupVersionKind{Group: "monitoring.coreos.com", Version: "v1", Kind: "PrometheusRule"},
+			ConflictPolicy:   ConflictPolicySkip,
+		},
+	}
+	if !cmp.Equal(resolved, expected) {
+		t.Error("wrong watches:", cmp.Diff(resolved, expected))
+	}
+
+	c = &Config{
+		Watches: []Watch{
+			{GroupVersionKind: metav1.GroupVersionKind{Group: "monitoring.coreos.com", Version: "v1alpha1", Kind: WildcardKind}},
+		},
+	}
+	resolved, err = c.ResolveWatches(groups)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(resolved) != 1 || resolved[0].Kind != "AlertmanagerConfig" {
+		t.Error("wrong watches for an explicit version:", resolved)
+	}
+
+	c = &Config{
+		Watches: []Watch{
+			{GroupVersionKind: metav1.GroupVersionKind{Group: "example.com", Kind: WildcardKind}},
+		},
+	}
+	if _, err := c.ResolveWatches(groups); err == nil {
+		t.Error("a wildcard watch for a missing group is resolved successfully")
+	}
+}
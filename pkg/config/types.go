@@ -2,9 +2,11 @@ package config
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"path"
 	"regexp"
+	"slices"
 	"strings"
 
 	authv1 "k8s.io/api/authorization/v1"
@@ -15,6 +17,7 @@ import (
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/yaml"
 
+	accuratev2 "github.com/cybozu-go/accurate/api/accurate/v2"
 	"github.com/cybozu-go/accurate/pkg/constants"
 )
 
@@ -22,110 +25,353 @@ import (
 type NamingPolicy struct {
 	Root  string `json:"root"`
 	Match string `json:"match"`
+
+	// Deny are regular expressions that SubNamespace names in the tree must not match.
+	// They can use the capture groups of Root in the same way as Match.
+	Deny []string `json:"deny,omitempty"`
+
+	// MaxLength is the maximum length of SubNamespace names in the tree.  0 means no limit.
+	MaxLength int `json:"maxLength,omitempty"`
+
+	// MaxDepth is the maximum depth of the tree, where children of the root are at depth 1.
+	// 0 means no limit.
+	MaxDepth int `json:"maxDepth,omitempty"`
 }
 
 type NamingPolicyRegexp struct {
-	Root  *regexp.Regexp
-	Match string
+	Root      *regexp.Regexp
+	Match     string
+	Deny      []string
+	MaxLength int
+	MaxDepth  int
+}
+
+// AutoGraftRule makes namespaces created without SubNamespace sub-namespaces by their names.
+type AutoGraftRule struct {
+	// Match is a regular expression to match the names of new namespaces.
+	Match string `json:"match"`
+
+	// Parent is the name of the parent namespace.
+	// It can use the capture groups of Match, e.g. `${team}`.
+	Parent string `json:"parent"`
+}
+
+type AutoGraftRuleRegexp struct {
+	Match  *regexp.Regexp
+	Parent string
+}
+
+// AutoGraftParent returns the parent of namespace `ns` by the first rule of `rules` that matches it,
+// or an empty string if none matches.
+func AutoGraftParent(rules []AutoGraftRuleRegexp, ns string) string {
+	for _, rule := range rules {
+		match := rule.Match.FindStringSubmatchIndex(ns)
+		if match == nil {
+			continue
+		}
+		return string(rule.Match.ExpandString(nil, rule.Parent, ns, match))
+	}
+	return ""
+}
+
+// ConflictPolicy is how to handle an object in a child namespace that has the same name
+// as a propagated object but was not propagated by Accurate.
+type ConflictPolicy string
+
+const (
+	// ConflictPolicySkip leaves the object intact.
+	ConflictPolicySkip ConflictPolicy = "skip"
+	// ConflictPolicyOverwrite replaces the object with the propagated copy.
+	ConflictPolicyOverwrite ConflictPolicy = "overwrite"
+	// ConflictPolicyFail leaves the object intact and stops propagating the source object.
+	ConflictPolicyFail ConflictPolicy = "fail"
+)
+
+// Watch represents a namespace-scoped resource propagated by Accurate.
+type Watch struct {
+	metav1.GroupVersionKind `json:",inline"`
+
+	// ConflictPolicy is the policy for conflicting objects in child namespaces.
+	// If empty, `create` mode skips and the other modes overwrite them.
+	ConflictPolicy ConflictPolicy `json:"conflictPolicy,omitempty"`
+
+	// Modes are the propagation modes allowed for the resource.
+	// If empty, all modes are allowed.
+	Modes []string `json:"modes,omitempty"`
+
+	// LabelKeyExcludes are the labels not to be copied to propagated objects of the resource
+	// in addition to PropagateLabelKeyExcludes.
+	LabelKeyExcludes []string `json:"labelKeyExcludes,omitempty"`
+
+	// AnnotationKeyExcludes are the annotations not to be copied to propagated objects of the resource
+	// in addition to PropagateAnnotationKeyExcludes.
+	AnnotationKeyExcludes []string `json:"annotationKeyExcludes,omitempty"`
+
+	// StripFields are the dot-separated paths of fields removed from propagated objects, e.g. `spec.clusterIP`.
+	// A path through a list removes the field from every item of the list.
+	StripFields []string `json:"stripFields,omitempty"`
+
+	// Optional defers watching the resource until it is installed in the cluster
+	// instead of rejecting the configurations.
+	Optional bool `json:"optional,omitempty"`
+}
+
+// WildcardKind is the kind of a Watch that matches all namespace-scoped resources in the group.
+const WildcardKind = "*"
+
+// IsWildcard returns true if `w` matches all namespace-scoped resources in the group.
+func (w Watch) IsWildcard() bool {
+	return w.Kind == WildcardKind
+}
+
+// StripFieldPath splits a path in StripFields into the field names.
+func StripFieldPath(p string) []string {
+	return strings.Split(p, ".")
 }
 
+// SubNamespaceLimits limits the number of sub-namespaces.
+// The limits can be overridden for each tree by annotations on the root namespace.
+type SubNamespaceLimits struct {
+	// MaxDescendants is the maximum number of sub-namespaces in a tree under a root namespace.
+	// 0 means no limit.
+	MaxDescendants int `json:"maxDescendants,omitempty"`
+
+	// MaxChildren is the maximum number of direct children of a namespace.
+	// 0 means no limit.
+	MaxChildren int `json:"maxChildren,omitempty"`
+}
+
+// SubNamespaceKeyPolicy is how to handle keys in spec.labels and spec.annotations of SubNamespaces
+// that do not match SubNamespaceLabelKeys or SubNamespaceAnnotationKeys, and are never propagated.
+// If empty, SubNamespaceKeyPolicyWarn is used.
+type SubNamespaceKeyPolicy string
+
+const (
+	// SubNamespaceKeyPolicyWarn admits the SubNamespace with warnings.
+	SubNamespaceKeyPolicyWarn SubNamespaceKeyPolicy = "warn"
+	// SubNamespaceKeyPolicyDeny denies the SubNamespace.
+	SubNamespaceKeyPolicyDeny SubNamespaceKeyPolicy = "deny"
+)
+
 // Config represents the configuration file of Accurate.
 type Config struct {
-	LabelKeys                      []string                  `json:"labelKeys,omitempty"`
-	AnnotationKeys                 []string                  `json:"annotationKeys,omitempty"`
-	SubNamespaceLabelKeys          []string                  `json:"subNamespaceLabelKeys,omitempty"`
-	SubNamespaceAnnotationKeys     []string                  `json:"subNamespaceAnnotationKeys,omitempty"`
-	Watches                        []metav1.GroupVersionKind `json:"watches,omitempty"`
-	PropagateLabelKeyExcludes      []string                  `json:"propagateLabelKeyExcludes,omitempty"`
-	PropagateAnnotationKeyExcludes []string                  `json:"propagateAnnotationKeyExcludes,omitempty"`
-	NamingPolicies                 []NamingPolicy            `json:"namingPolicies,omitempty"`
+	LabelKeys                      []string              `json:"labelKeys,omitempty"`
+	AnnotationKeys                 []string              `json:"annotationKeys,omitempty"`
+	SubNamespaceLabelKeys          []string              `json:"subNamespaceLabelKeys,omitempty"`
+	SubNamespaceAnnotationKeys     []string              `json:"subNamespaceAnnotationKeys,omitempty"`
+	Watches                        []Watch               `json:"watches,omitempty"`
+	PropagateLabelKeyExcludes      []string              `json:"propagateLabelKeyExcludes,omitempty"`
+	PropagateAnnotationKeyExcludes []string              `json:"propagateAnnotationKeyExcludes,omitempty"`
+	NamingPolicies                 []NamingPolicy        `json:"namingPolicies,omitempty"`
+	SubNamespaceLimits             SubNamespaceLimits    `json:"subNamespaceLimits,omitempty"`
+	SubNamespaceKeyPolicy          SubNamespaceKeyPolicy `json:"subNamespaceKeyPolicy,omitempty"`
+	AutoGraftRules                 []AutoGraftRule       `json:"autoGraftRules,omitempty"`
 	NamingPolicyRegexps            []NamingPolicyRegexp
+	AutoGraftRuleRegexps           []AutoGraftRuleRegexp
 }
 
 // Validate validates the configurations.
+// It runs all the checks and returns every violation found as an aggregated error.
 func (c *Config) Validate(mapper meta.RESTMapper) error {
+	var errList []error
+
 	for _, key := range c.LabelKeys {
 		// Verify that pattern is a valid format.
 		if _, err := path.Match(key, ""); err != nil {
-			return fmt.Errorf("malformed pattern for labelKeys %s: %w", key, err)
+			errList = append(errList, fmt.Errorf("malformed pattern for labelKeys %s: %w", key, err))
 		}
 		if strings.HasPrefix(key, constants.MetaPrefix) {
-			return fmt.Errorf("misconfigured labelKey: %s is not allowed", key)
+			errList = append(errList, fmt.Errorf("misconfigured labelKey: %s is not allowed", key))
 		}
 	}
 
 	for _, key := range c.AnnotationKeys {
 		// Verify that pattern is a valid format.
 		if _, err := path.Match(key, ""); err != nil {
-			return fmt.Errorf("malformed pattern for annotationKeys %s: %w", key, err)
+			errList = append(errList, fmt.Errorf("malformed pattern for annotationKeys %s: %w", key, err))
 		}
 		if strings.HasPrefix(key, constants.MetaPrefix) {
-			return fmt.Errorf("misconfigured annotationKey: %s is not allowed", key)
+			errList = append(errList, fmt.Errorf("misconfigured annotationKey: %s is not allowed", key))
 		}
 	}
 
 	for _, key := range c.SubNamespaceLabelKeys {
 		// Verify that pattern is a valid format.
 		if _, err := path.Match(key, ""); err != nil {
-			return fmt.Errorf("malformed pattern for subNamespaceLabelKeys %s: %w", key, err)
+			errList = append(errList, fmt.Errorf("malformed pattern for subNamespaceLabelKeys %s: %w", key, err))
 		}
 		if strings.HasPrefix(key, constants.MetaPrefix) {
-			return fmt.Errorf("misconfigured subNamespaceLabelKey: %s is not allowed", key)
+			errList = append(errList, fmt.Errorf("misconfigured subNamespaceLabelKey: %s is not allowed", key))
 		}
 	}
 
 	for _, key := range c.SubNamespaceAnnotationKeys {
 		// Verify that pattern is a valid format.
 		if _, err := path.Match(key, ""); err != nil {
-			return fmt.Errorf("malformed pattern for subNamespaceAnnotationKeys %s: %w", key, err)
+			errList = append(errList, fmt.Errorf("malformed pattern for subNamespaceAnnotationKeys %s: %w", key, err))
 		}
 		if strings.HasPrefix(key, constants.MetaPrefix) {
-			return fmt.Errorf("misconfigured subNamespaceAnnotationKey: %s is not allowed", key)
+			errList = append(errList, fmt.Errorf("misconfigured subNamespaceAnnotationKey: %s is not allowed", key))
 		}
 	}
 
-	for _, gvk := range c.Watches {
-		mapping, err := mapper.RESTMapping(schema.GroupKind{Group: gvk.Group, Kind: gvk.Kind}, gvk.Version)
-		if err != nil {
-			return fmt.Errorf("invalid gvk %s: %w", gvk.String(), err)
+	for _, w := range c.Watches {
+		gvk := w.GroupVersionKind
+		switch {
+		case w.IsWildcard():
+			// Wildcards are expanded by ResolveWatches.
+			if gvk.Group == "" {
+				errList = append(errList, fmt.Errorf("kind %s requires a non-core group", WildcardKind))
+			}
+		case gvk.Version == "":
+			errList = append(errList, fmt.Errorf("version is required for %s", gvk.String()))
+		default:
+			mapping, err := mapper.RESTMapping(schema.GroupKind{Group: gvk.Group, Kind: gvk.Kind}, gvk.Version)
+			if err != nil {
+				if !(w.Optional && meta.IsNoMatchError(err)) {
+					errList = append(errList, fmt.Errorf("invalid gvk %s: %w", gvk.String(), err))
+				}
+			} else if mapping.Scope.Name() != meta.RESTScopeNameNamespace {
+				errList = append(errList, fmt.Errorf("%s is not namespace-scoped", gvk.String()))
+			}
+		}
+
+		switch w.ConflictPolicy {
+		case "", ConflictPolicySkip, ConflictPolicyOverwrite, ConflictPolicyFail:
+		default:
+			errList = append(errList, fmt.Errorf("invalid conflictPolicy for %s: %s", gvk.String(), w.ConflictPolicy))
 		}
 
-		if mapping.Scope.Name() != meta.RESTScopeNameNamespace {
-			return fmt.Errorf("%s is not namespace-scoped", gvk.String())
+		for _, mode := range w.Modes {
+			switch mode {
+			case constants.PropagateCreate, constants.PropagateUpdate, constants.PropagateMerge:
+			default:
+				errList = append(errList, fmt.Errorf("invalid mode for %s: %s", gvk.String(), mode))
+			}
+		}
+
+		for _, key := range w.LabelKeyExcludes {
+			if _, err := path.Match(key, ""); err != nil {
+				errList = append(errList, fmt.Errorf("malformed pattern for labelKeyExcludes of %s %s: %w", gvk.String(), key, err))
+			}
+		}
+
+		for _, key := range w.AnnotationKeyExcludes {
+			if _, err := path.Match(key, ""); err != nil {
+				errList = append(errList, fmt.Errorf("malformed pattern for annotationKeyExcludes of %s %s: %w", gvk.String(), key, err))
+			}
+		}
+
+		for _, p := range w.StripFields {
+			fields := StripFieldPath(p)
+			if slices.Contains(fields, "") {
+				errList = append(errList, fmt.Errorf("malformed path for stripFields of %s: %s", gvk.String(), p))
+				continue
+			}
+			switch fields[0] {
+			case "apiVersion", "kind", "metadata":
+				errList = append(errList, fmt.Errorf("%s cannot be stripped from %s", p, gvk.String()))
+			}
 		}
 	}
 
 	for _, key := range c.PropagateLabelKeyExcludes {
 		// Verify that pattern is a valid format.
 		if _, err := path.Match(key, ""); err != nil {
-			return fmt.Errorf("malformed pattern for propagateLabelKeyExcludes %s: %w", key, err)
+			errList = append(errList, fmt.Errorf("malformed pattern for propagateLabelKeyExcludes %s: %w", key, err))
 		}
 	}
 
 	for _, key := range c.PropagateAnnotationKeyExcludes {
 		// Verify that pattern is a valid format.
 		if _, err := path.Match(key, ""); err != nil {
-			return fmt.Errorf("malformed pattern for propagateAnnotationKeyExcludes %s: %w", key, err)
+			errList = append(errList, fmt.Errorf("malformed pattern for propagateAnnotationKeyExcludes %s: %w", key, err))
 		}
 	}
 
+	if c.SubNamespaceLimits.MaxDescendants < 0 {
+		errList = append(errList, fmt.Errorf("invalid subNamespaceLimits.maxDescendants: %d", c.SubNamespaceLimits.MaxDescendants))
+	}
+	if c.SubNamespaceLimits.MaxChildren < 0 {
+		errList = append(errList, fmt.Errorf("invalid subNamespaceLimits.maxChildren: %d", c.SubNamespaceLimits.MaxChildren))
+	}
+
+	switch c.SubNamespaceKeyPolicy {
+	case "", SubNamespaceKeyPolicyWarn, SubNamespaceKeyPolicyDeny:
+	default:
+		errList = append(errList, fmt.Errorf("invalid subNamespaceKeyPolicy: %s", c.SubNamespaceKeyPolicy))
+	}
+
+	// Validate may be called more than once for the same configurations.
+	c.NamingPolicyRegexps = nil
 	for _, policy := range c.NamingPolicies {
 		root, err := regexp.Compile(policy.Root)
 		if err != nil {
-			return fmt.Errorf("invalid naming policy: %w", err)
+			errList = append(errList, fmt.Errorf("invalid naming policy: %w", err))
+			continue
+		}
+		valid := true
+		for _, deny := range policy.Deny {
+			if _, err := regexp.Compile(deny); err != nil {
+				errList = append(errList, fmt.Errorf("invalid deny pattern in naming policy for %s: %w", policy.Root, err))
+				valid = false
+			}
+		}
+		if policy.MaxLength < 0 {
+			errList = append(errList, fmt.Errorf("invalid maxLength in naming policy for %s: %d", policy.Root, policy.MaxLength))
+			valid = false
+		}
+		if policy.MaxDepth < 0 {
+			errList = append(errList, fmt.Errorf("invalid maxDepth in naming policy for %s: %d", policy.Root, policy.MaxDepth))
+			valid = false
+		}
+		if !valid {
+			continue
+		}
+		c.NamingPolicyRegexps = append(c.NamingPolicyRegexps, NamingPolicyRegexp{
+			Root:      root,
+			Match:     policy.Match,
+			Deny:      policy.Deny,
+			MaxLength: policy.MaxLength,
+			MaxDepth:  policy.MaxDepth,
+		})
+	}
+
+	c.AutoGraftRuleRegexps = nil
+	for i, rule := range c.AutoGraftRules {
+		match, err := regexp.Compile(rule.Match)
+		if err != nil {
+			errList = append(errList, fmt.Errorf("invalid match in autoGraftRules[%d]: %w", i, err))
+			continue
+		}
+		if rule.Parent == "" {
+			errList = append(errList, fmt.Errorf("parent is required in autoGraftRules[%d]", i))
+			continue
 		}
-		c.NamingPolicyRegexps = append(c.NamingPolicyRegexps, NamingPolicyRegexp{Root: root, Match: policy.Match})
+		c.AutoGraftRuleRegexps = append(c.AutoGraftRuleRegexps, AutoGraftRuleRegexp{
+			Match:  match,
+			Parent: rule.Parent,
+		})
 	}
-	return nil
+	return errors.NewAggregate(errList)
 }
 
-// ValidateRBAC validates that the manager has RBAC permissions to support configuration
+// ValidateRBAC validates that the manager has RBAC permissions to support configuration.
+// Wildcard watches and optional watches for resources not installed yet are skipped;
+// call it again with the result of ResolveWatches to check them.
 func (c *Config) ValidateRBAC(ctx context.Context, client client.Client, mapper meta.RESTMapper) error {
 	var errList []error
 
-	for _, gvk := range c.Watches {
+	for _, w := range c.Watches {
+		if w.IsWildcard() {
+			continue
+		}
+		gvk := w.GroupVersionKind
 		mapping, err := mapper.RESTMapping(schema.GroupKind{Group: gvk.Group, Kind: gvk.Kind}, gvk.Version)
 		if err != nil {
+			if w.Optional && meta.IsNoMatchError(err) {
+				continue
+			}
 			return fmt.Errorf("error mapping GVK %s: %w", gvk.String(), err)
 		}
 
@@ -135,7 +381,7 @@ func (c *Config) ValidateRBAC(ctx context.Context, client client.Client, mapper
 			Version:  mapping.Resource.Version,
 			Resource: mapping.Resource.Resource,
 		}
-		for _, verb := range []string{"get", "list", "watch", "create", "patch", "delete"} {
+		for _, verb := range propagationVerbs {
 			selfCheck.Spec.ResourceAttributes.Verb = verb
 			if err := client.Create(ctx, selfCheck); err != nil {
 				return fmt.Errorf("error creating SelfSubjectAccessReview: %w", err)
@@ -153,3 +399,12 @@ func (c *Config) ValidateRBAC(ctx context.Context, client client.Client, mapper
 func (c *Config) Load(data []byte) error {
 	return yaml.Unmarshal(data, c, yaml.DisallowUnknownFields)
 }
+
+// LoadResource loads configurations from the spec of an AccurateConfig resource.
+func (c *Config) LoadResource(ac *accuratev2.AccurateConfig) error {
+	data, err := json.Marshal(ac.Spec)
+	if err != nil {
+		return err
+	}
+	return c.Load(data)
+}
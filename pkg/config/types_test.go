@@ -3,17 +3,21 @@ package config
 import (
 	"context"
 	_ "embed"
+	"errors"
 	"testing"
 
+	accuratev2 "github.com/cybozu-go/accurate/api/accurate/v2"
 	"github.com/google/go-cmp/cmp"
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
 	"k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
 	"k8s.io/client-go/discovery/cached/memory"
 	fakediscovery "k8s.io/client-go/discovery/fake"
 	fakeclientset "k8s.io/client-go/kubernetes/fake"
 	"k8s.io/client-go/restmapper"
+	"sigs.k8s.io/yaml"
 )
 
 var _ = Describe("Validate", func() {
@@ -68,10 +72,12 @@ var _ = Describe("Validate", func() {
 
 	It("should pass watches for namespace-scoped resources", func() {
 		c := &Config{
-			Watches: []metav1.GroupVersionKind{{
-				Group:   "rbac.authorization.k8s.io",
-				Version: "v1",
-				Kind:    "Role",
+			Watches: []Watch{{
+				GroupVersionKind: metav1.GroupVersionKind{
+					Group:   "rbac.authorization.k8s.io",
+					Version: "v1",
+					Kind:    "Role",
+				},
 			}},
 		}
 		Expect(c.Validate(mapper)).To(Succeed())
@@ -79,14 +85,60 @@ var _ = Describe("Validate", func() {
 
 	It("should deny cluster-scoped resources in watches", func() {
 		c := &Config{
-			Watches: []metav1.GroupVersionKind{{
-				Group:   "rbac.authorization.k8s.io",
-				Version: "v1",
-				Kind:    "ClusterRole",
+			Watches: []Watch{{
+				GroupVersionKind: metav1.GroupVersionKind{
+					Group:   "rbac.authorization.k8s.io",
+					Version: "v1",
+					Kind:    "ClusterRole",
+				},
 			}},
 		}
 		Expect(c.Validate(mapper)).NotTo(Succeed())
 	})
+
+	It("should deny an unknown conflict policy in watches", func() {
+		c := &Config{
+			Watches: []Watch{{
+				GroupVersionKind: metav1.GroupVersionKind{
+					Group:   "rbac.authorization.k8s.io",
+					Version: "v1",
+					Kind:    "Role",
+				},
+				ConflictPolicy: "ignore",
+			}},
+		}
+		Expect(c.Validate(mapper)).NotTo(Succeed())
+	})
+
+	It("should pass per-resource options in watches", func() {
+		c := &Config{
+			Watches: []Watch{{
+				GroupVersionKind: metav1.GroupVersionKind{
+					Version: "v1",
+					Kind:    "Secret",
+				},
+				Modes:                 []string{"create", "update"},
+				LabelKeyExcludes:      []string{"*.example.com/*"},
+				AnnotationKeyExcludes: []string{"*.example.com/*"},
+				StripFields:           []string{"spec.clusterIP", "spec.ports.nodePort"},
+			}},
+		}
+		Expect(c.Validate(mapper)).To(Succeed())
+	})
+
+	DescribeTable("should deny invalid per-resource options in watches",
+		func(w Watch) {
+			w.GroupVersionKind = metav1.GroupVersionKind{Version: "v1", Kind: "Secret"}
+			c := &Config{Watches: []Watch{w}}
+			Expect(c.Validate(mapper)).NotTo(Succeed())
+		},
+		Entry("unknown mode", Watch{Modes: []string{"copy"}}),
+		Entry("malformed label pattern", Watch{LabelKeyExcludes: []string{"[abc"}}),
+		Entry("malformed annotation pattern", Watch{AnnotationKeyExcludes: []string{"[abc"}}),
+		Entry("empty field name", Watch{StripFields: []string{"spec..clusterIP"}}),
+		Entry("metadata", Watch{StripFields: []string{"metadata.labels"}}),
+		Entry("kind", Watch{StripFields: []string{"kind"}}),
+	)
 })
 
 var _ = Describe("ValidateRBAC", func() {
@@ -95,10 +147,12 @@ var _ = Describe("ValidateRBAC", func() {
 
 	BeforeEach(func() {
 		c = &Config{
-			Watches: []metav1.GroupVersionKind{{
-				Group:   "rbac.authorization.k8s.io",
-				Version: "v1",
-				Kind:    "Role",
+			Watches: []Watch{{
+				GroupVersionKind: metav1.GroupVersionKind{
+					Group:   "rbac.authorization.k8s.io",
+					Version: "v1",
+					Kind:    "Role",
+				},
 			}},
 		}
 		ctx = context.Background()
@@ -140,7 +194,7 @@ func TestLoad(t *testing.T) {
 		t.Error("wrong SubNamespace annotation keys:", cmp.Diff(c.SubNamespaceAnnotationKeys, []string{"foo", "bar"}))
 	}
 
-	if len(c.Watches) != 2 {
+	if len(c.Watches) != 3 {
 		t.Error("wrong number of watches:", len(c.Watches))
 	}
 	gvk := c.Watches[0]
@@ -153,6 +207,22 @@ func TestLoad(t *testing.T) {
 	if gvk.Kind != "Deployment" {
 		t.Error("wrong kind:", gvk.Kind)
 	}
+	if gvk.ConflictPolicy != "" {
+		t.Error("wrong conflict policy:", gvk.ConflictPolicy)
+	}
+	if c.Watches[1].ConflictPolicy != ConflictPolicySkip {
+		t.Error("wrong conflict policy:", c.Watches[1].ConflictPolicy)
+	}
+	expectedSvc := Watch{
+		GroupVersionKind:      metav1.GroupVersionKind{Version: "v1", Kind: "Service"},
+		Modes:                 []string{"update"},
+		LabelKeyExcludes:      []string{"foo.example.com/*"},
+		AnnotationKeyExcludes: []string{"bar.example.com/*"},
+		StripFields:           []string{"spec.clusterIP"},
+	}
+	if !cmp.Equal(c.Watches[2], expectedSvc) {
+		t.Error("wrong watch:", cmp.Diff(c.Watches[2], expectedSvc))
+	}
 
 	if len(c.NamingPolicies) != 2 {
 		t.Error("wrong number of namingPolicies:", len(c.NamingPolicies))
@@ -166,6 +236,26 @@ func TestLoad(t *testing.T) {
 	t.Log(err)
 }
 
+func TestLoadResource(t *testing.T) {
+	expected := &Config{}
+	if err := expected.Load(validData); err != nil {
+		t.Fatal(err)
+	}
+
+	ac := &accuratev2.AccurateConfig{}
+	if err := yaml.Unmarshal(validData, &ac.Spec, yaml.DisallowUnknownFields); err != nil {
+		t.Fatal(err)
+	}
+	c := &Config{}
+	if err := c.LoadResource(ac); err != nil {
+		t.Fatal(err)
+	}
+
+	if !cmp.Equal(c, expected) {
+		t.Error("wrong config:", cmp.Diff(c, expected))
+	}
+}
+
 func TestValidate(t *testing.T) {
 	m := newFakeRESTMapper()
 	testcases := []struct {
@@ -176,16 +266,21 @@ func TestValidate(t *testing.T) {
 			config: &Config{
 				LabelKeys:      []string{"a", "b"},
 				AnnotationKeys: []string{"foo", "bar"},
-				Watches: []metav1.GroupVersionKind{
+				Watches: []Watch{
 					{
-						Group:   "",
-						Version: "v1",
-						Kind:    "Secret",
+						GroupVersionKind: metav1.GroupVersionKind{
+							Group:   "",
+							Version: "v1",
+							Kind:    "Secret",
+						},
+						ConflictPolicy: ConflictPolicyFail,
 					},
 					{
-						Group:   "apps",
-						Version: "v1",
-						Kind:    "Deployment",
+						GroupVersionKind: metav1.GroupVersionKind{
+							Group:   "apps",
+							Version: "v1",
+							Kind:    "Deployment",
+						},
 					},
 				},
 				NamingPolicies: []NamingPolicy{
@@ -212,6 +307,114 @@ func TestValidate(t *testing.T) {
 			},
 			isValid: false,
 		},
+		{
+			config: &Config{
+				Watches: []Watch{
+					{GroupVersionKind: metav1.GroupVersionKind{Group: "monitoring.coreos.com", Kind: WildcardKind}},
+					{
+						GroupVersionKind: metav1.GroupVersionKind{Group: "example.com", Version: "v1", Kind: "Foo"},
+						Optional:         true,
+					},
+				},
+			},
+			isValid: true,
+		},
+		{
+			config: &Config{
+				NamingPolicies: []NamingPolicy{
+					{Root: "^tenant-", Deny: []string{"-system$"}, MaxLength: 40, MaxDepth: 3},
+				},
+			},
+			isValid: true,
+		},
+		{
+			config: &Config{
+				NamingPolicies: []NamingPolicy{
+					{Root: "^tenant-", Deny: []string{"("}},
+				},
+			},
+			isValid: false,
+		},
+		{
+			config: &Config{
+				NamingPolicies: []NamingPolicy{
+					{Root: "^tenant-", MaxLength: -1},
+				},
+			},
+			isValid: false,
+		},
+		{
+			config: &Config{
+				SubNamespaceLimits: SubNamespaceLimits{MaxDescendants: 100, MaxChildren: 10},
+			},
+			isValid: true,
+		},
+		{
+			config: &Config{
+				SubNamespaceLimits: SubNamespaceLimits{MaxChildren: -1},
+			},
+			isValid: false,
+		},
+		{
+			config: &Config{
+				SubNamespaceKeyPolicy: SubNamespaceKeyPolicyDeny,
+			},
+			isValid: true,
+		},
+		{
+			config: &Config{
+				SubNamespaceKeyPolicy: "ignore",
+			},
+			isValid: false,
+		},
+		{
+			config: &Config{
+				AutoGraftRules: []AutoGraftRule{
+					{Match: "^team-(?P<team>[a-z]+)-", Parent: "team-${team}"},
+				},
+			},
+			isValid: true,
+		},
+		{
+			config: &Config{
+				AutoGraftRules: []AutoGraftRule{
+					{Match: "(", Parent: "foo"},
+				},
+			},
+			isValid: false,
+		},
+		{
+			config: &Config{
+				AutoGraftRules: []AutoGraftRule{
+					{Match: "^team-"},
+				},
+			},
+			isValid: false,
+		},
+		{
+			config: &Config{
+				Watches: []Watch{
+					{GroupVersionKind: metav1.GroupVersionKind{Kind: WildcardKind}},
+				},
+			},
+			isValid: false,
+		},
+		{
+			config: &Config{
+				Watches: []Watch{
+					{GroupVersionKind: metav1.GroupVersionKind{Group: "apps", Kind: "Deployment"}},
+				},
+			},
+			isValid: false,
+		},
+		{
+			config: &Config{
+				Watches: []Watch{
+					{GroupVersionKind: metav1.GroupVersionKind{Group: "example.com", Version: "v1", Kind: "Foo"}},
+				},
+			},
+			isValid: false,
+		},
 	}
 
 	for _, testcase := range testcases {
@@ -225,6 +428,52 @@ func TestValidate(t *testing.T) {
 	}
 }
 
+func TestAutoGraftParent(t *testing.T) {
+	c := &Config{
+		AutoGraftRules: []AutoGraftRule{
+			{Match: "^team-(?P<team>[a-z]+)-", Parent: "team-${team}"},
+			{Match: "^sandbox-", Parent: "sandbox"},
+		},
+	}
+	if err := c.Validate(newFakeRESTMapper()); err != nil {
+		t.Fatal(err)
+	}
+
+	testcases := map[string]string{
+		"team-a-feature-x": "team-a",
+		"sandbox-foo":      "sandbox",
+		"default":          "",
+	}
+	for ns, expected := range testcases {
+		if parent := AutoGraftParent(c.AutoGraftRuleRegexps, ns); parent != expected {
+			t.Errorf("wrong parent for %s: expected=%q actual=%q", ns, expected, parent)
+		}
+	}
+}
+
+func TestValidateReportsEveryViolation(t *testing.T) {
+	c := &Config{
+		LabelKeys:      []string{"[abc", "accurate.cybozu.com/type"},
+		AnnotationKeys: []string{"foo.bar/baz"},
+		Watches: []Watch{{
+			GroupVersionKind: metav1.GroupVersionKind{
+				Group:   "rbac.authorization.k8s.io",
+				Version: "v1",
+				Kind:    "Role",
+			},
+		}},
+		NamingPolicies: []NamingPolicy{{Root: "(", Match: "foo"}},
+	}
+	err := c.Validate(newFakeRESTMapper())
+	var agg utilerrors.Aggregate
+	if !errors.As(err, &agg) {
+		t.Fatal("not an aggregated error:", err)
+	}
+	if len(agg.Errors()) != 4 {
+		t.Error("wrong number of errors:", agg.Errors())
+	}
+}
+
 func newFakeRESTMapper() meta.RESTMapper {
 	cs := &fakeclientset.Clientset{}
 	cs.Resources = append(cs.Resources, &metav1.APIResourceList{
@@ -18,6 +18,39 @@ const (
 const (
 	AnnFrom      = MetaPrefix + "from"
 	AnnPropagate = MetaPrefix + "propagate"
+	// AnnPropagateTemplate makes Accurate render string fields of propagated
+	// copies as Go templates against the receiving namespace.
+	AnnPropagateTemplate = MetaPrefix + "propagate-template"
+	// AnnPropagateDepth limits the number of levels a resource is propagated.
+	// Each copy carries the remaining depth decremented by one.
+	AnnPropagateDepth = MetaPrefix + "propagate-depth"
+	// AnnPropagateNamespaceSelector is a label selector of namespaces
+	// that receive copies of the resource.
+	AnnPropagateNamespaceSelector = MetaPrefix + "propagate-namespace-selector"
+	// AnnPropagateNamespaceExcludes is a comma-separated list of namespace names
+	// that do not receive copies of the resource.
+	AnnPropagateNamespaceExcludes = MetaPrefix + "propagate-namespace-excludes"
+	// AnnPropagateExclude is a comma-separated list of resources that a namespace
+	// does not inherit. Each item is `<resource>.<group>/<name>`.
+	AnnPropagateExclude = MetaPrefix + "propagate-exclude"
+	// AnnMaxDescendants overrides the maximum number of sub-namespaces
+	// in the tree when set on a root namespace.
+	AnnMaxDescendants = MetaPrefix + "max-descendants"
+	// AnnMaxChildren overrides the maximum number of direct children of each
+	// namespace in the tree when set on a root namespace.
+	AnnMaxChildren = MetaPrefix + "max-children"
+	// AnnAdoptableBy allows a SubNamespace in the namespace of the value to
+	// adopt an existing namespace of the same name.
+	AnnAdoptableBy = MetaPrefix + "adoptable-by"
+	// AnnTemplates is a comma-separated list of templates of a namespace in precedence order.
+	// Later templates take precedence. It is used instead of `accurate.cybozu.com/template` label.
+	AnnTemplates = MetaPrefix + "templates"
+	// AnnAutoGrafted marks a namespace grafted by the auto-graft rules,
+	// for which Accurate creates the SubNamespace in the parent namespace.
+	AnnAutoGrafted = MetaPrefix + "auto-grafted"
+	// AnnConflicts is a comma-separated list of namespaces where an object of the
+	// same name as the propagated resource exists and was left intact.
+	AnnConflicts = MetaPrefix + "conflicts"
 	// Deprecated: Part of the deprecated propagate-generated feature subject for
 	// removal soon.
 	AnnPropagateGenerated = MetaPrefix + "propagate-generated"
@@ -33,6 +66,7 @@ const (
 	NSTypeRoot      = "root"
 	PropagateCreate = "create"
 	PropagateUpdate = "update"
+	PropagateMerge  = "merge"
 	PropagateAny    = "any" // defined as an in-memory index value
 )
 
@@ -42,4 +76,8 @@ const InternalMetaPrefix = "internal.accurate.cybozu.com/"
 const (
 	AnnObservedGeneration = InternalMetaPrefix + "observed-generation"
 	AnnConditions         = InternalMetaPrefix + "conditions"
+	// AnnDeletionPolicy and AnnKeepPropagatedResources keep the fields of
+	// SubNamespace v2 spec in the older API versions.
+	AnnDeletionPolicy          = InternalMetaPrefix + "deletion-policy"
+	AnnKeepPropagatedResources = InternalMetaPrefix + "keep-propagated-resources"
 )
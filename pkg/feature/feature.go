@@ -11,10 +11,16 @@ import (
 // in namespaces that should be out-of-scope for Accurate.
 const DisablePropagateGenerated featuregate.Feature = "DisablePropagateGenerated"
 
+// PropagateMetaDeletion will remove namespace labels and annotations that Accurate set
+// with an update operation, e.g. by an older release, when they are no longer propagated.
+// Keys set with server-side apply are removed regardless of this feature gate.
+const PropagateMetaDeletion featuregate.Feature = "PropagateMetaDeletion"
+
 func init() {
 	runtime.Must(config.DefaultMutableFeatureGate.Add(defaultFeatureGates))
 }
 
 var defaultFeatureGates = map[featuregate.Feature]featuregate.FeatureSpec{
 	DisablePropagateGenerated: {Default: true, PreRelease: featuregate.Beta},
+	PropagateMetaDeletion:     {Default: false, PreRelease: featuregate.Alpha},
 }
@@ -4,6 +4,7 @@ import (
 	"context"
 
 	accuratev2 "github.com/cybozu-go/accurate/api/accurate/v2"
+	"github.com/cybozu-go/accurate/internal/util/templates"
 	"github.com/cybozu-go/accurate/pkg/constants"
 	corev1 "k8s.io/api/core/v1"
 	"sigs.k8s.io/controller-runtime/pkg/client"
@@ -14,10 +15,11 @@ import (
 func SetupIndexForResource(ctx context.Context, mgr manager.Manager, res client.Object) error {
 	return mgr.GetFieldIndexer().IndexField(ctx, res, constants.PropagateKey, func(rawObj client.Object) []string {
 		val := rawObj.GetAnnotations()[constants.AnnPropagate]
-		if val == "" {
-			return nil
+		switch val {
+		case constants.PropagateCreate, constants.PropagateUpdate, constants.PropagateMerge:
+			return []string{val, constants.PropagateAny}
 		}
-		return []string{val, constants.PropagateAny}
+		return nil
 	})
 }
 
@@ -36,11 +38,7 @@ func SetupIndexForNamespace(ctx context.Context, mgr manager.Manager) error {
 	}
 
 	return mgr.GetFieldIndexer().IndexField(ctx, ns, constants.NamespaceTemplateKey, func(rawObj client.Object) []string {
-		tmpl := rawObj.GetLabels()[constants.LabelTemplate]
-		if tmpl == "" {
-			return nil
-		}
-		return []string{tmpl}
+		return templates.Names(rawObj)
 	})
 }
 